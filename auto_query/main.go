@@ -7,20 +7,24 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/security"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 func mustDial(addr string, timeout time.Duration) *grpc.ClientConn {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
+	opts, err := security.DialOptions()
+	if err != nil {
+		log.Fatalf("TLS configuration: %v", err)
+	}
 	conn, err := grpc.DialContext(
 		ctx, addr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithBlock(),
+		append(opts, grpc.WithBlock())...,
 	)
 	if err != nil {
 		log.Fatalf("dial %s failed: %v", addr, err)
@@ -61,9 +65,9 @@ func writeCSV(filename string, header []string, rows [][]string, appendMode bool
 
 func runTopK(c pb.SketcherClient, typ, field string, k uint32, csvPath string, appendMode bool) error {
 	req := &pb.TopKRequest{
-		Type:  typ,
-		K:     k,
-		Field: field,
+		Type: typ,
+		K:    k,
+		Name: field,
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
 	defer cancel()
@@ -102,27 +106,53 @@ func runTopK(c pb.SketcherClient, typ, field string, k uint32, csvPath string, a
 	return nil
 }
 
+// resolveFields returns the fields to query. An empty flag means every field
+// the server currently keeps an ASketch of the given type for.
+func resolveFields(c pb.SketcherClient, typ, fieldFlag string) ([]string, error) {
+	if fieldFlag != "" {
+		return strings.Split(fieldFlag, ","), nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
+	defer cancel()
+	resp, err := c.ListFields(ctx, &pb.ListFieldsRequest{Type: typ})
+	if err != nil {
+		return nil, fmt.Errorf("ListFields: %w", err)
+	}
+	fields := make([]string, len(resp.GetFields()))
+	for i, f := range resp.GetFields() {
+		fields[i] = f.GetField()
+	}
+	return fields, nil
+}
+
 func main() {
 	addr := flag.String("addr", "127.0.0.1:8080", "gRPC server address (host:port)")
 	typ := flag.String("type", "int", "value type: int|float64")
-	field := flag.String("field", "", "event/signal name for which to compute Top-K")
+	field := flag.String("field", "", "comma separated event/signal names for which to compute Top-K; empty queries every field on the server")
 	k := flag.Uint("topk", 10, "K for Top-K")
 	csvPath := flag.String("out", "test.csv", "path to output CSV file")
 	watch := flag.Duration("watch", 50*time.Millisecond, "repeat every duration (e.g. 2s, 1m); 0 disables")
 	timeout := flag.Duration("timeout", 2*time.Minute, "maximum runtime before stopping")
+	security.RegisterFlags(flag.CommandLine)
 	flag.Parse()
 
-	if *field == "" {
-		log.Fatal("--field must be specified")
-	}
-
 	conn := mustDial(*addr, 5*time.Second)
 	defer conn.Close()
 	client := pb.NewSketcherClient(conn)
 
+	fields, err := resolveFields(client, *typ, *field)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(fields) == 0 {
+		log.Fatalf("server has no %s ASketch fields yet, pass --field", *typ)
+	}
+
 	do := func(first bool) {
-		if err := runTopK(client, *typ, *field, uint32(*k), *csvPath, !first); err != nil {
-			log.Printf("error: %v", err)
+		for i, fld := range fields {
+			if err := runTopK(client, *typ, fld, uint32(*k), *csvPath, !(first && i == 0)); err != nil {
+				log.Printf("error: %v", err)
+			}
 		}
 	}
 
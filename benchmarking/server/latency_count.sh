@@ -1,4 +0,0 @@
-#!/usr/bin/env bash
-fileName="count-Latency-$(date +"%H-%M-%S@%F")"
-
-cd ../../server && go test -timeout 8h -run TestServerLatencyCount | tee "../benchmarking/server/${fileName}"
\ No newline at end of file
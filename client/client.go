@@ -3,43 +3,73 @@ package client
 import (
 	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/security"
 	"github.com/bruhng/distributed-sketching/shared"
 	"github.com/bruhng/distributed-sketching/stream"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/connectivity"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
 )
 
 var MAX_RECONN_ATTEMPTS int = 20
 
-func Init[T shared.Number](port string, adr string, sketchType string, dataSetPath string, headerName string, numStreamRuns int, streamDelayms int, mergeAfter int) {
+func Init[T shared.Number](port string, adr string, sketchType string, dataSetPath string, headerName string, numStreamRuns int, streamDelayms int, policy MergePolicy, opts ...stream.Option) {
 	//fmt.Printf("[debug] T = %T\n", *new(T))
-	dataStream := *stream.NewStreamFromCsv[T](dataSetPath, headerName, streamDelayms, numStreamRuns)
+	if err := policy.Validate(sketchType); err != nil {
+		fmt.Println(err)
+		panic("Invalid merge policy")
+	}
+	source, err := stream.Open[T](dataSetPath, headerName, streamDelayms, numStreamRuns, opts...)
+	if err != nil {
+		fmt.Println(err)
+		panic("Could not open data set")
+	}
+	dataStream := *source
+	go logStreamErrors(dataStream.Errors)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	policy.Done = ctx.Done()
 
 	switch sketchType {
 	case "kll":
-		KllClient(100, mergeAfter, dataStream, adr+":"+port, startRealConnection)
+		KllClient(100, policy, dataStream, "", adr+":"+port, startRealConnection)
 	case "count":
-		CountClient(mergeAfter, dataStream, adr+":"+port, startRealConnection)
+		CountClient(policy, dataStream, "", adr+":"+port, startRealConnection)
 	case "asketch":
-		ASketchClient(mergeAfter, dataStream, headerName, adr+":"+port, startRealConnection)
+		ASketchClient(policy, dataStream, headerName, adr+":"+port, startRealConnection)
+	case "hll":
+		HllClient(policy, dataStream, "", adr+":"+port, startRealConnection)
 	case "badCount":
-		BadCountClient(mergeAfter, dataStream, adr+":"+port, startRealConnection)
+		BadCountClient(policy.Every, dataStream, adr+":"+port, startRealConnection)
 	case "badKll":
-		BadKllClient(mergeAfter, dataStream, adr+":"+port, startRealConnection)
+		BadKllClient(policy.Every, dataStream, adr+":"+port, startRealConnection)
 	case "streamClient":
-		StreamClient(mergeAfter, dataStream, adr+":"+port, startRealConnection)
+		StreamClient(policy, dataStream, headerName, adr+":"+port, startRealConnection)
 	default:
 		panic("No sketch provided or invalid sketch")
 	}
 }
 
+// logStreamErrors prints malformed rows of a data set until the stream ends
+func logStreamErrors(errs chan error) {
+	for err := range errs {
+		fmt.Println("skipping row:", err)
+	}
+}
+
 func startRealConnection(adr string) (pb.SketcherClient, *grpc.ClientConn, error) {
 
-	conn, err := grpc.NewClient(adr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	opts, err := security.DialOptions()
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := grpc.NewClient(adr, opts...)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -83,11 +113,17 @@ type mergeFunction[T any] func(context.Context, *T, ...grpc.CallOption) (*pb.Mer
 func MakeRequest[T any](protoSketch *T, addr string, merge mergeFunction[T], conn *grpc.ClientConn, c *pb.SketcherClient, startConnection connectionStarter, attempt *int) {
 	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 
-	// Log protobuf size before sending
-
+	message := messageName(fmt.Sprintf("%T", protoSketch))
+	if msg, ok := any(protoSketch).(proto.Message); ok {
+		sentBytes.Add(float64(proto.Size(msg)), message)
+	}
+	start := time.Now()
 	_, err := merge(ctx, protoSketch)
 	cancel()
+	mergeDuration.Observe(time.Since(start).Seconds(), message)
+	mergesSent.Inc(message)
 	if err != nil {
+		mergeErrors.Inc(message)
 		fmt.Println(err)
 		conn.Close()
 		if *attempt > MAX_RECONN_ATTEMPTS {
@@ -95,6 +131,7 @@ func MakeRequest[T any](protoSketch *T, addr string, merge mergeFunction[T], con
 			panic("Could not reestablish connection")
 			// TODO: Maybe remove panic
 		}
+		reconnects.Inc()
 		tc, tconn, err := startConnection(addr)
 		if err != nil {
 			fmt.Printf("%d faild reconnection attempt, will try again later\n", *attempt)
@@ -21,9 +21,9 @@ func BenchmarkKllThroughput(b *testing.B) {
 		b.Run(fmt.Sprintf("StreamRate: %d", rate), func(b *testing.B) {
 			for b.Loop() {
 				b.StopTimer()
-				dataStream := *stream.NewStreamFromCsv[float64](DATA_SET_PATH, HEADER_NAME, rate, NUM_STREAM_RUNS, 1000)
+				dataStream := *stream.NewStreamFromCsv[float64](DATA_SET_PATH, HEADER_NAME, rate, NUM_STREAM_RUNS, stream.WithCutoff(1000))
 				b.StartTimer()
-				client.KllClient(200, 100000, dataStream, SERVER_ADR+":"+PORT, startFakeConnection)
+				client.KllClient(200, client.EveryN(100000), dataStream, "", SERVER_ADR+":"+PORT, startFakeConnection)
 				b.StopTimer()
 			}
 		})
@@ -35,9 +35,9 @@ func BenchmarkCountThroughput(b *testing.B) {
 		b.Run(fmt.Sprintf("StreamRate: %d", rate), func(b *testing.B) {
 			for b.Loop() {
 				b.StopTimer()
-				dataStream := *stream.NewStreamFromCsv[float64](DATA_SET_PATH, HEADER_NAME, rate, NUM_STREAM_RUNS, 1000)
+				dataStream := *stream.NewStreamFromCsv[float64](DATA_SET_PATH, HEADER_NAME, rate, NUM_STREAM_RUNS, stream.WithCutoff(1000))
 				b.StartTimer()
-				client.CountClient(100000, dataStream, SERVER_ADR+":"+PORT, startFakeConnection)
+				client.CountClient(client.EveryN(100000), dataStream, "", SERVER_ADR+":"+PORT, startFakeConnection)
 				b.StopTimer()
 			}
 			// client.RestartServer(SERVER_ADR, PORT, 1)
@@ -51,7 +51,7 @@ func BenchmarkCenterThroughput(b *testing.B) {
 		b.Run(fmt.Sprintf("StreamRate: %d", rate), func(b *testing.B) {
 			for b.Loop() {
 				b.StopTimer()
-				dataStream := *stream.NewStreamFromCsv[float64](DATA_SET_PATH, HEADER_NAME, rate, NUM_STREAM_RUNS, 1000)
+				dataStream := *stream.NewStreamFromCsv[float64](DATA_SET_PATH, HEADER_NAME, rate, NUM_STREAM_RUNS, stream.WithCutoff(1000))
 				b.StartTimer()
 				client.BadCountClient(100000, dataStream, SERVER_ADR+":"+PORT, startFakeConnection)
 				b.StopTimer()
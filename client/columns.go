@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/bruhng/distributed-sketching/shared"
+	"github.com/bruhng/distributed-sketching/stream"
+)
+
+// ColumnMapping feeds one csv column into one sketch, merged on the server
+// under Name.
+type ColumnMapping struct {
+	Column string
+	Sketch string // kll, count, hll, asketch or streamClient
+	Type   string // int or float
+	Name   string
+}
+
+// ParseColumnMappings parses a comma separated list of
+// column=sketch:type[@name] entries, e.g.
+// "speed_meters_per_second=kll:float,vehicle_id=hll:int@vehicles".
+// The name defaults to the column.
+func ParseColumnMappings(spec string) ([]ColumnMapping, error) {
+	var mappings []ColumnMapping
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		column, rest, ok := strings.Cut(entry, "=")
+		if !ok || column == "" {
+			return nil, fmt.Errorf("%q is not of the form column=sketch:type[@name]", entry)
+		}
+		rest, name, _ := strings.Cut(rest, "@")
+		sketch, typ, ok := strings.Cut(rest, ":")
+		if !ok {
+			typ = "float"
+		}
+		switch sketch {
+		case "kll", "count", "hll", "asketch", "streamClient":
+		default:
+			return nil, fmt.Errorf("%s is not a sketch that can be used per column", sketch)
+		}
+		if typ != "int" && typ != "float" {
+			return nil, fmt.Errorf("%s is not a valid type, use int or float", typ)
+		}
+		if name == "" {
+			name = column
+		}
+		mappings = append(mappings, ColumnMapping{Column: column, Sketch: sketch, Type: typ, Name: name})
+	}
+	if len(mappings) == 0 {
+		return nil, fmt.Errorf("no column mappings given")
+	}
+	return mappings, nil
+}
+
+// InitColumns reads the data set once and runs one sketch client per mapping
+// concurrently, each on its own connection.
+func InitColumns(port string, adr string, dataSetPath string, mappings []ColumnMapping, numStreamRuns int, streamDelayms int, policy MergePolicy, opts ...stream.Option) {
+	fields := make([]string, len(mappings))
+	for i, m := range mappings {
+		fields[i] = m.Column
+		if err := policy.Validate(m.Sketch); err != nil {
+			fmt.Println(err)
+			panic("Invalid merge policy")
+		}
+	}
+	columns, err := stream.NewColumnsFromCsv(dataSetPath, fields, streamDelayms, numStreamRuns, opts...)
+	if err != nil {
+		fmt.Println(err)
+		panic("Could not open data set")
+	}
+	go logStreamErrors(columns.Errors)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	policy.Done = ctx.Done()
+
+	var wg sync.WaitGroup
+	for i, m := range mappings {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			switch m.Type {
+			case "float":
+				runColumn[float64](m, columns, i, adr+":"+port, policy)
+			case "int":
+				runColumn[int](m, columns, i, adr+":"+port, policy)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func runColumn[T shared.Number](m ColumnMapping, columns *stream.Columns, c int, addr string, policy MergePolicy) {
+	dataStream := *stream.ParseColumn[T](columns, c, m.Column)
+	go logStreamErrors(dataStream.Errors)
+	switch m.Sketch {
+	case "kll":
+		KllClient(100, policy, dataStream, m.Name, addr, startRealConnection)
+	case "count":
+		CountClient(policy, dataStream, m.Name, addr, startRealConnection)
+	case "hll":
+		HllClient(policy, dataStream, m.Name, addr, startRealConnection)
+	case "asketch":
+		ASketchClient(policy, dataStream, m.Name, addr, startRealConnection)
+	case "streamClient":
+		StreamClient(policy, dataStream, m.Name, addr, startRealConnection)
+	}
+}
@@ -0,0 +1,33 @@
+package client_test
+
+import (
+	"testing"
+
+	"github.com/bruhng/distributed-sketching/client"
+)
+
+func TestParseColumnMappings(t *testing.T) {
+	mappings, err := client.ParseColumnMappings("speed_meters_per_second=kll:float, vehicle_id=hll:int@vehicles,event_id=asketch:int")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []client.ColumnMapping{
+		{Column: "speed_meters_per_second", Sketch: "kll", Type: "float", Name: "speed_meters_per_second"},
+		{Column: "vehicle_id", Sketch: "hll", Type: "int", Name: "vehicles"},
+		{Column: "event_id", Sketch: "asketch", Type: "int", Name: "event_id"},
+	}
+	if len(mappings) != len(want) {
+		t.Fatalf("got %d mappings, want %d", len(mappings), len(want))
+	}
+	for i := range want {
+		if mappings[i] != want[i] {
+			t.Fatalf("mapping %d = %+v, want %+v", i, mappings[i], want[i])
+		}
+	}
+
+	for _, bad := range []string{"", "speed", "speed=badKll:float", "speed=kll:string"} {
+		if _, err := client.ParseColumnMappings(bad); err == nil {
+			t.Fatalf("ParseColumnMappings(%q) did not fail", bad)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package client_test
+
+import (
+	"context"
+	"math"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/bruhng/distributed-sketching/client"
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/server/servertest"
+	"github.com/bruhng/distributed-sketching/shared"
+	"github.com/bruhng/distributed-sketching/stream"
+	"google.golang.org/grpc"
+)
+
+// callCounter counts the successful calls of every RPC of a server
+type callCounter struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (c *callCounter) intercept(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	if err == nil {
+		c.mu.Lock()
+		c.calls[path.Base(info.FullMethod)]++
+		c.mu.Unlock()
+	}
+	return resp, err
+}
+
+func (c *callCounter) count(method string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls[method]
+}
+
+func newServer(t *testing.T) (*servertest.Server, *callCounter, pb.SketcherClient) {
+	calls := &callCounter{calls: map[string]int{}}
+	srv := servertest.NewServer(t, grpc.ChainUnaryInterceptor(calls.intercept))
+	return srv, calls, srv.Client(t)
+}
+
+// values returns n items where item i is f(i)
+func values[T shared.Number](n int, f func(i int) T) stream.Stream[T] {
+	data := make([]T, n)
+	for i := range data {
+		data[i] = f(i)
+	}
+	return *stream.NewStream(data, 0)
+}
+
+// hot is 7 for half of the items and distinct otherwise
+func hot(i int) int {
+	if i%2 == 0 {
+		return 7
+	}
+	return 1000 + i
+}
+
+func TestKllClientEndToEnd(t *testing.T) {
+	t.Parallel()
+	srv, calls, c := newServer(t)
+	client.KllClient(200, client.EveryN(100), values(1000, func(i int) int { return i }), "speed", "bufnet", srv.Dial)
+
+	rank, err := c.QueryKll(context.Background(), &pb.NumericValue{Value: &pb.NumericValue_IntVal{IntVal: 500}, Type: "int", Name: "speed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rank.N != 1000 || rank.Phi < 450 || rank.Phi > 550 {
+		t.Errorf("rank of 500 is %d of %d, want about 500 of 1000", rank.Phi, rank.N)
+	}
+	if n := calls.count("MergeKll"); n != 10 {
+		t.Errorf("%d merges, want 10", n)
+	}
+}
+
+func TestCountClientEndToEnd(t *testing.T) {
+	t.Parallel()
+	srv, calls, c := newServer(t)
+	client.CountClient(client.EveryN(300), values(1000, func(i int) float64 { return float64(i % 10) }), "events", "bufnet", srv.Dial)
+
+	res, err := c.QueryCount(context.Background(), &pb.NumericValue{Value: &pb.NumericValue_FloatVal{FloatVal: 3}, Type: "float64", Name: "events"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Res < 90 || res.Res > 110 {
+		t.Errorf("count of 3 is %d, want about 100", res.Res)
+	}
+	// the last 100 items are sent when the stream ends
+	if n := calls.count("MergeCount"); n != 4 {
+		t.Errorf("%d merges, want 4", n)
+	}
+}
+
+func TestHllClientEndToEnd(t *testing.T) {
+	t.Parallel()
+	srv, calls, c := newServer(t)
+	client.HllClient(client.EveryN(100), values(1000, func(i int) int { return i % 300 }), "vehicles", "bufnet", srv.Dial)
+
+	card, err := c.QueryHll(context.Background(), &pb.CardinalityRequest{Name: "vehicles", Type: "int"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(card.Estimate-300) > 30 {
+		t.Errorf("cardinality is %v, want about 300", card.Estimate)
+	}
+	if n := calls.count("MergeHll"); n != 10 {
+		t.Errorf("%d merges, want 10", n)
+	}
+}
+
+func TestASketchClientEndToEnd(t *testing.T) {
+	t.Parallel()
+	srv, calls, c := newServer(t)
+	client.ASketchClient(client.EveryN(200), values(1000, hot), "events", "bufnet", srv.Dial)
+	checkHot(t, c, "events")
+	if n := calls.count("MergeASketch"); n != 5 {
+		t.Errorf("%d merges, want 5", n)
+	}
+}
+
+func TestStreamClientEndToEnd(t *testing.T) {
+	t.Parallel()
+	srv, calls, c := newServer(t)
+	client.StreamClient(client.EveryN(200), values(1000, hot), "events", "bufnet", srv.Dial)
+	checkHot(t, c, "events")
+	if n := calls.count("MergeBufIntoASketch"); n != 5 {
+		t.Errorf("%d merges, want 5", n)
+	}
+}
+
+// checkHot checks that the ASketch of field found the hot item
+func checkHot(t *testing.T, c pb.SketcherClient, field string) {
+	t.Helper()
+	ctx := context.Background()
+	top, err := c.TopKASketch(ctx, &pb.TopKRequest{K: 1, Type: "int", Name: field})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top.Entries) != 1 || top.Entries[0].Key.GetIntVal() != 7 {
+		t.Fatalf("top item is %v, want 7", top.Entries)
+	}
+	res, err := c.QueryASketch(ctx, &pb.NumericValue{Value: &pb.NumericValue_IntVal{IntVal: 7}, Name: field})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Res < 500 {
+		t.Errorf("count of 7 is %d, want at least 500", res.Res)
+	}
+}
+
+func TestBadClientsEndToEnd(t *testing.T) {
+	t.Parallel()
+	srv, calls, _ := newServer(t)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		client.BadKllClient(100, values(1000, func(i int) float64 { return float64(i) }), "bufnet", srv.Dial)
+	}()
+	go func() {
+		defer wg.Done()
+		client.BadCountClient(100, values(1000, func(i int) int { return i }), "bufnet", srv.Dial)
+	}()
+	wg.Wait()
+	for _, method := range []string{"BadKll", "BadCount"} {
+		if n := calls.count(method); n != 10 {
+			t.Errorf("%d calls of %s, want 10", n, method)
+		}
+	}
+}
+
+func TestLatencyClientsEndToEnd(t *testing.T) {
+	t.Parallel()
+	srv, calls, c := newServer(t)
+	if latencies := client.LatencyKllClient(200, 100, values(1000, func(i int) int { return i }), "bufnet", srv.Dial); len(latencies) != 10 {
+		t.Errorf("%d kll latencies, want 10", len(latencies))
+	}
+	if latencies := client.LatencyCountClient(100, values(1000, func(i int) int { return i % 10 }), "bufnet", srv.Dial); len(latencies) != 10 {
+		t.Errorf("%d count latencies, want 10", len(latencies))
+	}
+
+	rank, err := c.QueryKll(context.Background(), &pb.NumericValue{Value: &pb.NumericValue_IntVal{IntVal: 999}, Type: "int"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rank.N != 1000 {
+		t.Errorf("kll has %d items, want 1000", rank.N)
+	}
+	if calls.count("MergeKll") != 10 || calls.count("MergeCount") != 10 {
+		t.Errorf("calls are %v, want 10 merges of each", calls.calls)
+	}
+}
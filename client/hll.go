@@ -0,0 +1,35 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/bruhng/distributed-sketching/shared"
+	"github.com/bruhng/distributed-sketching/sketches/convert"
+	"github.com/bruhng/distributed-sketching/sketches/hll"
+	"github.com/bruhng/distributed-sketching/stream"
+	"google.golang.org/protobuf/proto"
+)
+
+func HllClient[T shared.Number](policy MergePolicy, dataStream stream.Stream[T], name string, addr string, startConnection connectionStarter) {
+	var reconAttempt *int = new(int)
+	*reconAttempt = 0
+	c, conn, err := startConnection(addr)
+	if err != nil {
+		fmt.Println(err)
+		panic("could not start connection")
+	}
+	sketch := hll.NewHLLSketch[T](shared.HLLRegisters, shared.HLLSeed)
+	runMerges(dataStream, policy, sketchLabel("hll", name), func(data T) {
+		sketch.Add(data)
+	}, fixedSize(func() proto.Message {
+		return convert.ToProtoHll(sketch, name)
+	}), func() {
+		protoSketch := convert.ToProtoHll(sketch, name)
+
+		MakeRequest(protoSketch, addr, c.MergeHll, conn, &c, startConnection, reconAttempt)
+		sketch = hll.NewHLLSketch[T](shared.HLLRegisters, shared.HLLSeed)
+	})
+	if conn != nil {
+		conn.Close()
+	}
+}
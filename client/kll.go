@@ -2,10 +2,10 @@ package client
 
 import (
 	"fmt"
-	"reflect"
 
 	pb "github.com/bruhng/distributed-sketching/proto"
 	"github.com/bruhng/distributed-sketching/shared"
+	"github.com/bruhng/distributed-sketching/sketches/convert"
 	"github.com/bruhng/distributed-sketching/sketches/kll"
 	"github.com/bruhng/distributed-sketching/stream"
 	"google.golang.org/grpc"
@@ -13,7 +13,7 @@ import (
 
 type connectionStarter func(string) (pb.SketcherClient, *grpc.ClientConn, error)
 
-func KllClient[T shared.Number](k int, mergeAfter int, dataStream stream.Stream[T], addr string, startConnection connectionStarter) {
+func KllClient[T shared.Number](k int, policy MergePolicy, dataStream stream.Stream[T], name string, addr string, startConnection connectionStarter) {
 	var reconAttempt *int = new(int)
 	*reconAttempt = 0
 	c, conn, err := startConnection(addr)
@@ -22,25 +22,30 @@ func KllClient[T shared.Number](k int, mergeAfter int, dataStream stream.Stream[
 		panic("could not start connection")
 	}
 	sketch := kll.NewKLLSketch[T](k)
-	i := 0
-	for data := range dataStream.Data {
-
+	runMerges(dataStream, policy, sketchLabel("kll", name), func(data T) {
 		sketch.Add(data)
-		i++
-
-		if i%mergeAfter == 0 {
-			protoSketch := ConvertToProtoKLL(sketch)
-			
-			MakeRequest[pb.KLLSketch](protoSketch, addr, c.MergeKll, conn, &c, startConnection, reconAttempt)
-			sketch = kll.NewKLLSketch[T](k)
-			i = 0
-		}
-	}
+	}, func() int {
+		return kllSize(sketch)
+	}, func() {
+		protoSketch := convert.ToProtoKLL(sketch)
+		protoSketch.Name = name
+
+		MakeRequest[pb.KLLSketch](protoSketch, addr, c.MergeKll, conn, &c, startConnection, reconAttempt)
+		sketch = kll.NewKLLSketch[T](k)
+	})
 	if conn != nil {
 		conn.Close()
 
 	}
-	blackhole = sketch
+}
+
+// kllSize estimates the encoded size of a KLL sketch from its stored items
+func kllSize[T shared.Number](sketch *kll.KLLSketch[T]) int {
+	size := 0
+	for _, row := range sketch.Sketch {
+		size += 4 + 10*len(row)
+	}
+	return size
 }
 
 func GetKll[T shared.Number](k int, mergeAfter int, dataStream stream.Stream[T]) *pb.KLLSketch {
@@ -52,35 +57,8 @@ func GetKll[T shared.Number](k int, mergeAfter int, dataStream stream.Stream[T])
 		i++
 
 		if i%mergeAfter == 0 {
-			return ConvertToProtoKLL(sketch)
+			return convert.ToProtoKLL(sketch)
 		}
 	}
 	return nil
 }
-
-func ConvertToProtoKLL[T shared.Number](sketch *kll.KLLSketch[T]) *pb.KLLSketch {
-	t := fmt.Sprintf("%T", sketch.Sketch)[4:]
-	orderedArray := &pb.KLLSketch{N: int64(sketch.N), Type: t}
-	data := sketch.Sketch
-
-	for _, row := range data {
-		protoRow := &pb.NumericRow{} // Create a new row
-
-		for _, val := range row {
-			if reflect.ValueOf(val).Kind() == reflect.Int {
-				protoRow.Values = append(protoRow.Values, &pb.NumericValue{
-					Value: &pb.NumericValue_IntVal{IntVal: int64(val)}, // Wrap value properly
-				})
-
-			} else {
-				protoRow.Values = append(protoRow.Values, &pb.NumericValue{
-					Value: &pb.NumericValue_FloatVal{FloatVal: float64(val)}, // Wrap value properly
-				})
-
-			}
-		}
-		orderedArray.Rows = append(orderedArray.Rows, protoRow)
-	}
-
-	return orderedArray
-}
@@ -6,6 +6,7 @@ import (
 
 	pb "github.com/bruhng/distributed-sketching/proto"
 	"github.com/bruhng/distributed-sketching/shared"
+	"github.com/bruhng/distributed-sketching/sketches/convert"
 	"github.com/bruhng/distributed-sketching/sketches/count"
 	"github.com/bruhng/distributed-sketching/sketches/kll"
 	"github.com/bruhng/distributed-sketching/stream"
@@ -27,7 +28,7 @@ func LatencyCountClient[T shared.Number](mergeAfter int, dataStream stream.Strea
 		i++
 
 		if i%mergeAfter == 0 {
-			protoSketch := ConvertToProtoCount(sketch)
+			protoSketch := convert.ToProtoCount(sketch)
 			prev := time.Now()
 			MakeRequest(protoSketch, addr, c.MergeCount, conn, &c, startConnection, reconAttempt)
 			diff := time.Since(prev)
@@ -60,7 +61,7 @@ func LatencyKllClient[T shared.Number](k int, mergeAfter int, dataStream stream.
 		i++
 
 		if i%mergeAfter == 0 {
-			protoSketch := ConvertToProtoKLL(sketch)
+			protoSketch := convert.ToProtoKLL(sketch)
 			prev := time.Now()
 			MakeRequest[pb.KLLSketch](protoSketch, addr, c.MergeKll, conn, &c, startConnection, reconAttempt)
 			diff := time.Since(prev)
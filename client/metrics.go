@@ -0,0 +1,22 @@
+package client
+
+import (
+	"strings"
+
+	"github.com/bruhng/distributed-sketching/metrics"
+)
+
+var (
+	mergesSent    = metrics.Default.NewCounter("sketch_client_merges_total", "Merge requests sent, by message.", "message")
+	mergeErrors   = metrics.Default.NewCounter("sketch_client_merge_errors_total", "Merge requests that failed, by message.", "message")
+	mergeDuration = metrics.Default.NewHistogram("sketch_client_merge_duration_seconds", "Round trip time of merge requests.", metrics.DefaultBuckets, "message")
+	sentBytes     = metrics.Default.NewCounter("sketch_client_sent_bytes_total", "Encoded size of the sent sketches.", "message")
+	reconnects    = metrics.Default.NewCounter("sketch_client_reconnect_attempts_total", "Attempts to reconnect to the server after a failed merge, successful or not.")
+	streamLag     = metrics.Default.NewGauge("sketch_client_stream_lag_seconds", "How far the stream is behind its rate because the client does not keep up, sampled on every merge.", "sketch")
+	itemsSketched = metrics.Default.NewCounter("sketch_client_items_total", "Items added to sketches.", "sketch")
+)
+
+// messageName turns "*proto.KLLSketch" into "KLLSketch"
+func messageName(typeName string) string {
+	return typeName[strings.LastIndex(typeName, ".")+1:]
+}
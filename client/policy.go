@@ -0,0 +1,133 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bruhng/distributed-sketching/shared"
+	"github.com/bruhng/distributed-sketching/stream"
+	"google.golang.org/protobuf/proto"
+)
+
+// MergePolicy decides when a client sends its sketch to the server. Every set
+// trigger is active and whichever fires first flushes the sketch, zero values
+// disable a trigger. Whatever is left when the stream ends or the client is
+// stopped is always sent.
+type MergePolicy struct {
+	// Every flushes after this many items.
+	Every int
+	// Interval flushes on a fixed ticker of this period, at every tick that
+	// items arrived since the previous flush.
+	Interval time.Duration
+	// MaxBytes flushes once the sketch is estimated to be at least this large.
+	// Only KLL sketches and the stream client grow with their input, see
+	// Validate.
+	MaxBytes int
+	// Done flushes and stops the client when closed, e.g. on shutdown.
+	Done <-chan struct{}
+}
+
+// EveryN is the classic policy of flushing after n items.
+func EveryN(n int) MergePolicy {
+	return MergePolicy{Every: n}
+}
+
+// Validate reports policies the client of sketchType cannot follow. The bad
+// clients only merge every n items, and count, hll and asketch sketches have a
+// fixed size, so a byte limit would flush after every item or never.
+func (p MergePolicy) Validate(sketchType string) error {
+	switch sketchType {
+	case "badKll", "badCount":
+		if p.Every <= 0 || p.Interval > 0 || p.MaxBytes > 0 {
+			return fmt.Errorf("%s only merges every n items, please submit a positive item count and no interval or byte limit", sketchType)
+		}
+	case "count", "hll", "asketch":
+		if p.MaxBytes > 0 {
+			return fmt.Errorf("%s sketches have a fixed size, a byte limit is not supported", sketchType)
+		}
+	}
+	return nil
+}
+
+func (p MergePolicy) String() string {
+	var parts []string
+	if p.Every > 0 {
+		parts = append(parts, fmt.Sprintf("items=%d", p.Every))
+	}
+	if p.Interval > 0 {
+		parts = append(parts, fmt.Sprintf("every=%s", p.Interval))
+	}
+	if p.MaxBytes > 0 {
+		parts = append(parts, fmt.Sprintf("bytes=%d", p.MaxBytes))
+	}
+	if len(parts) == 0 {
+		return "end of stream"
+	}
+	return strings.Join(parts, ",")
+}
+
+// fixedSize returns the encoded size of a sketch that does not grow with its
+// input, measured once on first use. Validate rejects byte limits for them.
+func fixedSize(convert func() proto.Message) func() int {
+	size := -1
+	return func() int {
+		if size < 0 {
+			size = proto.Size(convert())
+		}
+		return size
+	}
+}
+
+// runMerges feeds the stream into add and calls flush whenever the policy
+// says so and once more when the stream ends or the policy is done, if
+// anything is pending. size is only called when a byte limit is set, label
+// names the sketch in the client metrics.
+func runMerges[T shared.Number](dataStream stream.Stream[T], policy MergePolicy, label string, add func(T), size func() int, flush func()) {
+	var tick <-chan time.Time
+	if policy.Interval > 0 {
+		ticker := time.NewTicker(policy.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	pending := 0
+	send := func() {
+		flush()
+		itemsSketched.Add(float64(pending), label)
+		streamLag.Set(dataStream.Lag().Seconds(), label)
+		pending = 0
+	}
+	for {
+		select {
+		case data, ok := <-dataStream.Data:
+			if !ok {
+				if pending > 0 {
+					send()
+				}
+				return
+			}
+			add(data)
+			pending++
+			if (policy.Every > 0 && pending >= policy.Every) || (policy.MaxBytes > 0 && size() >= policy.MaxBytes) {
+				send()
+			}
+		case <-tick:
+			if pending > 0 {
+				send()
+			}
+		case <-policy.Done:
+			if pending > 0 {
+				send()
+			}
+			return
+		}
+	}
+}
+
+// sketchLabel names a client sketch in metrics, e.g. "kll" or "kll/speed"
+func sketchLabel(kind string, name string) string {
+	if name == "" {
+		return kind
+	}
+	return kind + "/" + name
+}
@@ -0,0 +1,159 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bruhng/distributed-sketching/client"
+	"github.com/bruhng/distributed-sketching/metrics"
+	"github.com/bruhng/distributed-sketching/mock_proto"
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/stream"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func mockStarter(c pb.SketcherClient) func(string) (pb.SketcherClient, *grpc.ClientConn, error) {
+	return func(string) (pb.SketcherClient, *grpc.ClientConn, error) {
+		return c, nil, nil
+	}
+}
+
+func recordKllMerges(t *testing.T) (*mock_proto.MockSketcherClient, *[]int64) {
+	c := mock_proto.NewMockSketcherClient(gomock.NewController(t))
+	var sizes []int64
+	c.EXPECT().MergeKll(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, in *pb.KLLSketch, _ ...grpc.CallOption) (*pb.MergeReply, error) {
+		sizes = append(sizes, in.N)
+		return &pb.MergeReply{}, nil
+	}).AnyTimes()
+	return c, &sizes
+}
+
+func TestMergeEveryNFlushesTail(t *testing.T) {
+	c, sizes := recordKllMerges(t)
+	s := stream.Stream[float64]{Data: make(chan float64, 250)}
+	for i := range 250 {
+		s.Data <- float64(i)
+	}
+	close(s.Data)
+	client.KllClient(100, client.EveryN(100), s, "", "", mockStarter(c))
+	if len(*sizes) != 3 || (*sizes)[0] != 100 || (*sizes)[1] != 100 || (*sizes)[2] != 50 {
+		t.Fatalf("merged sketches of sizes %v, want [100 100 50]", *sizes)
+	}
+}
+
+func TestMergeInterval(t *testing.T) {
+	c, sizes := recordKllMerges(t)
+	s := stream.Stream[float64]{Data: make(chan float64)}
+	go func() {
+		for i := range 10 {
+			s.Data <- float64(i)
+			if i == 4 {
+				time.Sleep(150 * time.Millisecond)
+			}
+		}
+		close(s.Data)
+	}()
+	client.KllClient(100, client.MergePolicy{Interval: 50 * time.Millisecond}, s, "", "", mockStarter(c))
+	total := int64(0)
+	for _, n := range *sizes {
+		total += n
+	}
+	if len(*sizes) < 2 || (*sizes)[0] != 5 || total != 10 {
+		t.Fatalf("merged sketches of sizes %v, want the first 5 items flushed by the timer", *sizes)
+	}
+}
+
+func TestMergeBytes(t *testing.T) {
+	c := mock_proto.NewMockSketcherClient(gomock.NewController(t))
+	var batches []int
+	c.EXPECT().MergeBufIntoASketch(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, in *pb.BufBatch, _ ...grpc.CallOption) (*pb.MergeReply, error) {
+		batches = append(batches, len(in.Items))
+		return &pb.MergeReply{}, nil
+	}).AnyTimes()
+
+	s := stream.Stream[int]{Data: make(chan int, 25)}
+	for i := range 25 {
+		s.Data <- i
+	}
+	close(s.Data)
+	client.StreamClient(client.MergePolicy{MaxBytes: 100}, s, "f", "", mockStarter(c))
+	if len(batches) != 3 || batches[0] != 10 || batches[2] != 5 {
+		t.Fatalf("sent batches of %v items, want [10 10 5]", batches)
+	}
+}
+
+func TestMergeOnDone(t *testing.T) {
+	c, sizes := recordKllMerges(t)
+	s := stream.Stream[float64]{Data: make(chan float64)}
+	done := make(chan struct{})
+	go func() {
+		for i := range 7 {
+			s.Data <- float64(i)
+		}
+		close(done)
+	}()
+	client.KllClient(100, client.MergePolicy{Every: 1000, Done: done}, s, "", "", mockStarter(c))
+	if len(*sizes) != 1 || (*sizes)[0] != 7 {
+		t.Fatalf("merged sketches of sizes %v, want the 7 pending items", *sizes)
+	}
+}
+
+func TestMergePolicyValidate(t *testing.T) {
+	for _, tc := range []struct {
+		sketch string
+		policy client.MergePolicy
+		ok     bool
+	}{
+		{"kll", client.MergePolicy{MaxBytes: 1000}, true},
+		{"streamClient", client.MergePolicy{MaxBytes: 1000}, true},
+		{"count", client.MergePolicy{Every: 10, Interval: time.Second}, true},
+		{"count", client.MergePolicy{MaxBytes: 1000}, false},
+		{"hll", client.MergePolicy{MaxBytes: 1000}, false},
+		{"asketch", client.MergePolicy{MaxBytes: 1000}, false},
+		{"badKll", client.EveryN(100), true},
+		{"badKll", client.MergePolicy{Interval: time.Second}, false},
+		{"badCount", client.MergePolicy{MaxBytes: 1000}, false},
+		{"badCount", client.MergePolicy{Every: 100, Interval: time.Second}, false},
+	} {
+		if err := tc.policy.Validate(tc.sketch); (err == nil) != tc.ok {
+			t.Errorf("%s with %v: got error %v", tc.sketch, tc.policy, err)
+		}
+	}
+}
+
+// reconnectAttempts reads the client reconnect counter from metrics.Default
+func reconnectAttempts(t *testing.T) float64 {
+	rec := httptest.NewRecorder()
+	metrics.Default.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	_, value, _ := strings.Cut(rec.Body.String(), "\nsketch_client_reconnect_attempts_total ")
+	value, _, _ = strings.Cut(value, "\n")
+	n, _ := strconv.ParseFloat(value, 64)
+	return n
+}
+
+func TestSuccessfulReconnectsCounted(t *testing.T) {
+	c := mock_proto.NewMockSketcherClient(gomock.NewController(t))
+	c.EXPECT().MergeKll(gomock.Any(), gomock.Any()).Return(nil, errors.New("unavailable")).Times(2)
+	s := stream.Stream[float64]{Data: make(chan float64, 200)}
+	for i := range 200 {
+		s.Data <- float64(i)
+	}
+	close(s.Data)
+	before := reconnectAttempts(t)
+	// failed merges close the connection, so hand out real ones that never dial
+	starter := func(string) (pb.SketcherClient, *grpc.ClientConn, error) {
+		conn, err := grpc.NewClient("passthrough:///unused", grpc.WithTransportCredentials(insecure.NewCredentials()))
+		return c, conn, err
+	}
+	client.KllClient(100, client.EveryN(100), s, "", "", starter)
+	if got := reconnectAttempts(t) - before; got != 2 {
+		t.Fatalf("%v reconnect attempts counted, want 2", got)
+	}
+}
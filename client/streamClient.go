@@ -5,10 +5,11 @@ import (
 
 	pb "github.com/bruhng/distributed-sketching/proto"
 	"github.com/bruhng/distributed-sketching/shared"
+	"github.com/bruhng/distributed-sketching/sketches/convert"
 	"github.com/bruhng/distributed-sketching/stream"
 )
 
-func StreamClient[T shared.Number](batchsize int, dataStream stream.Stream[T], addr string, startConnection connectionStarter) {
+func StreamClient[T shared.Number](policy MergePolicy, dataStream stream.Stream[T], fieldName string, addr string, startConnection connectionStarter) {
 	var reconAttempt *int = new(int)
 	*reconAttempt = 0
 	c, conn, err := startConnection(addr)
@@ -16,27 +17,24 @@ func StreamClient[T shared.Number](batchsize int, dataStream stream.Stream[T], a
 		fmt.Printf("Connection failed with error: %v\n", err)
 		panic("could not start connection")
 	}
-	fmt.Printf("Starting stream client with batch size %d\n", batchsize)
+	fmt.Printf("Starting stream client sending on %s\n", policy)
 	//Instantiate application-buffer
-	buf := make([]T, batchsize)
-	i := 0
-	for data := range dataStream.Data {
-		buf[i] = data
-		i++
-
-		if i%batchsize == 0 {
-			//fmt.Print("Buffer full, initiating send\n")
-			protoBuf := ConvertToProtoBuf(buf)
-
-			MakeRequest(protoBuf, addr, c.MergeBufIntoASketch, conn, &c, startConnection, reconAttempt)
-			buf = make([]T, batchsize)
-			i = 0
-		}
-	}
+	buf := make([]T, 0, policy.Every)
+	runMerges(dataStream, policy, sketchLabel("streamClient", fieldName), func(data T) {
+		buf = append(buf, data)
+	}, func() int {
+		return 10 * len(buf)
+	}, func() {
+		//fmt.Print("Buffer full, initiating send\n")
+		protoBuf := convert.ToProtoBuf(buf)
+		protoBuf.Name = fieldName
+
+		MakeRequest(protoBuf, addr, c.MergeBufIntoASketch, conn, &c, startConnection, reconAttempt)
+		buf = make([]T, 0, policy.Every)
+	})
 	if conn != nil {
 		conn.Close()
 	}
-	blackhole = buf
 }
 
 // rethink this, could not work. Only works with "always-addable" functionality of data sketches
@@ -49,26 +47,8 @@ func GetProtoBuf[T shared.Number](batchsize int, dataStream stream.Stream[T]) *p
 
 		if i%batchsize == 0 {
 			//fmt.Print("Buffer full, initiating send\n")
-			return ConvertToProtoBuf(buf)
+			return convert.ToProtoBuf(buf)
 		}
 	}
 	return nil
 }
-
-func ConvertToProtoBuf[T shared.Number](buf []T) *pb.BufBatch {
-	t := fmt.Sprintf("%T", *new(T))
-	protoBuf := &pb.BufBatch{Type: t}
-	switch t {
-	case "int":
-		for _, item := range buf {
-			protoBuf.Items = append(protoBuf.Items, &pb.NumericValue{Value: &pb.NumericValue_IntVal{IntVal: int64(item)}})
-		}
-	case "float64":
-		for _, item := range buf {
-			protoBuf.Items = append(protoBuf.Items, &pb.NumericValue{Value: &pb.NumericValue_FloatVal{FloatVal: float64(item)}})
-		}
-	default:
-		panic("Type not supported")
-	}
-	return protoBuf
-}
@@ -8,40 +8,16 @@ import (
 
 	"github.com/bruhng/distributed-sketching/client"
 	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/server/servertest"
 	"github.com/bruhng/distributed-sketching/shared"
 	"github.com/bruhng/distributed-sketching/stream"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/connectivity"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 var SYSTEM_NUM_STREAM_RUNS = 1
 var NUM_MERGES = 5
 
-func startRealConnection(adr string) (pb.SketcherClient, *grpc.ClientConn, error) {
-
-	conn, err := grpc.NewClient(adr, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		return nil, nil, err
-	}
-	c := pb.NewSketcherClient(conn)
-	conn.Connect()
-	// wait for the connection to be established
-	for {
-		state := conn.GetState()
-		if state == connectivity.Idle || state == connectivity.Connecting {
-			continue
-		} else if state == connectivity.Ready {
-			break
-		} else {
-			return c, conn, fmt.Errorf("Could not establish connection to server")
-		}
-	}
-	//
-	return c, conn, nil
-}
-
 func startFakeConnection(_ string) (pb.SketcherClient, *grpc.ClientConn, error) {
 	return nil, nil, nil
 }
@@ -50,8 +26,8 @@ var clientAmounts []int = []int{100}
 
 var mergeRates []int = []int{10000}
 
-func startKll[T shared.Number](wg *sync.WaitGroup, fg *sync.WaitGroup, cond *sync.Cond, streamRate int, mergeRate int, sketch *pb.KLLSketch, merges int) {
-	c, conn, err := startRealConnection(SERVER_ADR + ":" + PORT)
+func startKll[T shared.Number](wg *sync.WaitGroup, fg *sync.WaitGroup, cond *sync.Cond, srv *servertest.Server, streamRate int, mergeRate int, sketch *pb.KLLSketch, merges int) {
+	c, conn, err := srv.Dial("")
 
 	var mergesMadeGroup sync.WaitGroup
 
@@ -71,7 +47,7 @@ func startKll[T shared.Number](wg *sync.WaitGroup, fg *sync.WaitGroup, cond *syn
 		time.Sleep(time.Duration(streamRate*mergeRate) * time.Nanosecond)
 		mergesMadeGroup.Add(1)
 		go func() {
-			client.MakeRequest[pb.KLLSketch](sketch, SERVER_ADR, c.MergeKll, conn, &c, startRealConnection, reconAttempt)
+			client.MakeRequest[pb.KLLSketch](sketch, "bufnet", c.MergeKll, conn, &c, srv.Dial, reconAttempt)
 			mergesMadeGroup.Done()
 		}()
 	}
@@ -97,10 +73,11 @@ func BenchmarkSystemKll(b *testing.B) {
 					b.ReportAllocs()
 
 					pb.StopTimer()
+					srv := servertest.NewServer(pb)
 					for range clientAmmount {
 						wg.Add(1)
 
-						go startKll[float64](&wg, &fg, cond, streamRate, mergeRate, sketch, NUM_MERGES)
+						go startKll[float64](&wg, &fg, cond, srv, streamRate, mergeRate, sketch, NUM_MERGES)
 					}
 					wg.Wait()
 					pb.StartTimer()
@@ -110,14 +87,12 @@ func BenchmarkSystemKll(b *testing.B) {
 					fg.Wait()
 
 				})
-				client.RestartServer("10.42.0.1", "8080", 3)
-				time.Sleep(10 * time.Second)
 			}
 		}
 	}
 }
-func startBadKll[T shared.Number](wg *sync.WaitGroup, fg *sync.WaitGroup, cond *sync.Cond, streamRate int, mergeRate int, sketch *pb.BadArray, merges int) {
-	c, conn, err := startRealConnection(SERVER_ADR + ":" + PORT)
+func startBadKll[T shared.Number](wg *sync.WaitGroup, fg *sync.WaitGroup, cond *sync.Cond, srv *servertest.Server, streamRate int, mergeRate int, sketch *pb.BadArray, merges int) {
+	c, conn, err := srv.Dial("")
 	var reconAttempt *int = new(int)
 	var mergesMadeGroup sync.WaitGroup
 	if err != nil {
@@ -134,7 +109,7 @@ func startBadKll[T shared.Number](wg *sync.WaitGroup, fg *sync.WaitGroup, cond *
 		time.Sleep(time.Duration(streamRate*mergeRate) * time.Nanosecond)
 		mergesMadeGroup.Add(1)
 		go func() {
-			client.MakeRequest[pb.BadArray](sketch, SERVER_ADR, c.BadKll, conn, &c, startRealConnection, reconAttempt)
+			client.MakeRequest[pb.BadArray](sketch, "bufnet", c.BadKll, conn, &c, srv.Dial, reconAttempt)
 			mergesMadeGroup.Done()
 		}()
 	}
@@ -159,9 +134,10 @@ func BenchmarkSystemBadKll(b *testing.B) {
 					b.ReportAllocs()
 
 					pb.StopTimer()
+					srv := servertest.NewServer(pb)
 					for range clientAmmount {
 						wg.Add(1)
-						go startBadKll[float64](&wg, &fg, cond, streamRate, mergeRate, sketch, NUM_MERGES)
+						go startBadKll[float64](&wg, &fg, cond, srv, streamRate, mergeRate, sketch, NUM_MERGES)
 					}
 					wg.Wait()
 					pb.StartTimer()
@@ -171,15 +147,13 @@ func BenchmarkSystemBadKll(b *testing.B) {
 					fg.Wait()
 
 				})
-				client.RestartServer("10.42.0.1", "8080", 3)
-				time.Sleep(10 * time.Second)
 			}
 		}
 	}
 }
 
-func startCount[T shared.Number](wg *sync.WaitGroup, fg *sync.WaitGroup, cond *sync.Cond, streamRate int, mergeRate int, sketch *pb.CountSketch, merges int) {
-	c, conn, err := startRealConnection(SERVER_ADR + ":" + PORT)
+func startCount[T shared.Number](wg *sync.WaitGroup, fg *sync.WaitGroup, cond *sync.Cond, srv *servertest.Server, streamRate int, mergeRate int, sketch *pb.CountSketch, merges int) {
+	c, conn, err := srv.Dial("")
 	var reconAttempt *int = new(int)
 	var mergesMadeGroup sync.WaitGroup
 	if err != nil {
@@ -196,7 +170,7 @@ func startCount[T shared.Number](wg *sync.WaitGroup, fg *sync.WaitGroup, cond *s
 		time.Sleep(time.Duration(streamRate*mergeRate) * time.Nanosecond)
 		mergesMadeGroup.Add(1)
 		go func() {
-			client.MakeRequest[pb.CountSketch](sketch, SERVER_ADR, c.MergeCount, conn, &c, startRealConnection, reconAttempt)
+			client.MakeRequest[pb.CountSketch](sketch, "bufnet", c.MergeCount, conn, &c, srv.Dial, reconAttempt)
 			mergesMadeGroup.Done()
 		}()
 	}
@@ -220,9 +194,10 @@ func BenchmarkSystemCount(b *testing.B) {
 					b.ReportAllocs()
 
 					pb.StopTimer()
+					srv := servertest.NewServer(pb)
 					for range clientAmmount {
 						wg.Add(1)
-						go startCount[float64](&wg, &fg, cond, streamRate, mergeRate, sketch, NUM_MERGES)
+						go startCount[float64](&wg, &fg, cond, srv, streamRate, mergeRate, sketch, NUM_MERGES)
 					}
 					wg.Wait()
 					pb.StartTimer()
@@ -232,15 +207,12 @@ func BenchmarkSystemCount(b *testing.B) {
 					fg.Wait()
 
 				})
-				// add reset
-				client.RestartServer("10.42.0.1", "8080", 3)
-				time.Sleep(10 * time.Second)
 			}
 		}
 	}
 }
-func startBadCount[T shared.Number](wg *sync.WaitGroup, fg *sync.WaitGroup, cond *sync.Cond, streamRate int, mergeRate int, sketch *pb.BadArray, merges int) {
-	c, conn, err := startRealConnection(SERVER_ADR + ":" + PORT)
+func startBadCount[T shared.Number](wg *sync.WaitGroup, fg *sync.WaitGroup, cond *sync.Cond, srv *servertest.Server, streamRate int, mergeRate int, sketch *pb.BadArray, merges int) {
+	c, conn, err := srv.Dial("")
 	var reconAttempt *int = new(int)
 	var mergesMadeGroup sync.WaitGroup
 	if err != nil {
@@ -257,7 +229,7 @@ func startBadCount[T shared.Number](wg *sync.WaitGroup, fg *sync.WaitGroup, cond
 		time.Sleep(time.Duration(streamRate*mergeRate) * time.Nanosecond)
 		mergesMadeGroup.Add(1)
 		go func() {
-			client.MakeRequest[pb.BadArray](sketch, SERVER_ADR, c.BadCount, conn, &c, startRealConnection, reconAttempt)
+			client.MakeRequest[pb.BadArray](sketch, "bufnet", c.BadCount, conn, &c, srv.Dial, reconAttempt)
 			mergesMadeGroup.Done()
 		}()
 	}
@@ -282,9 +254,10 @@ func BenchmarkSystemBadCount(b *testing.B) {
 					b.ReportAllocs()
 
 					pb.StopTimer()
+					srv := servertest.NewServer(pb)
 					for range clientAmmount {
 						wg.Add(1)
-						go startBadCount[float64](&wg, &fg, cond, streamRate, mergeRate, sketch, NUM_MERGES)
+						go startBadCount[float64](&wg, &fg, cond, srv, streamRate, mergeRate, sketch, NUM_MERGES)
 					}
 					wg.Wait()
 					pb.StartTimer()
@@ -294,8 +267,6 @@ func BenchmarkSystemBadCount(b *testing.B) {
 					fg.Wait()
 
 				})
-				client.RestartServer("10.42.0.1", "8080", 3)
-				time.Sleep(10 * time.Second)
 			}
 		}
 	}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadScenarios(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenarios.json")
+	os.WriteFile(path, []byte(`{
+		"defaults": {"duration": "2s", "clients": 4},
+		"scenarios": [
+			{"name": "kll", "sweep": {"stream_rate": [0, 100], "clients": [1, 8]}},
+			{"sketch": "count", "type": "float", "topology": "loopback"}
+		]
+	}`), 0o644)
+	scenarios, err := loadScenarios(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"kll/clients=1,stream_rate=0", "kll/clients=1,stream_rate=100",
+		"kll/clients=8,stream_rate=0", "kll/clients=8,stream_rate=100", "count",
+	}
+	if len(scenarios) != len(want) {
+		t.Fatalf("got %d scenarios, want %d", len(scenarios), len(want))
+	}
+	for i, s := range scenarios {
+		if s.Name != want[i] {
+			t.Errorf("scenario %d is %s, want %s", i, s.Name, want[i])
+		}
+		if s.Duration != duration(2*time.Second) || s.MergeEvery != 1000 {
+			t.Errorf("%s did not keep the defaults: %+v", s.Name, s)
+		}
+	}
+	if scenarios[3].Clients != 8 || scenarios[3].StreamRate != 100 {
+		t.Errorf("sweep not applied: %+v", scenarios[3])
+	}
+	if s := scenarios[4]; s.Clients != 4 || s.Type != "float64" || s.Topology != "loopback" {
+		t.Errorf("count scenario is %+v", s)
+	}
+
+	for _, bad := range []string{
+		`{"scenarios": [{"sketch": "bloom"}]}`,
+		`{"scenarios": [{"sketch": "count", "merge_bytes": 1000}]}`,
+		`{"scenarios": [{"sketch": "badKll", "merge_every": 0, "merge_interval": "1s"}]}`,
+		`{"scenarios": [{"clients": 0}]}`,
+		`{"scenarios": [{"duration": 10}]}`,
+		`{"scenarios": [{"topology": "remote"}]}`,
+		`{"scenarios": [{"colour": "red"}]}`,
+		`{"scenarios": [{"sweep": {"clients": []}}]}`,
+		`{"scenarios": []}`,
+	} {
+		os.WriteFile(path, []byte(bad), 0o644)
+		if _, err := loadScenarios(path); err == nil {
+			t.Errorf("%s was accepted", bad)
+		}
+	}
+}
+
+func TestRunScenario(t *testing.T) {
+	for _, set := range []func(s *scenario){
+		func(s *scenario) { s.Name, s.Clients = "kll", 2 },
+		func(s *scenario) { s.Name, s.Sketch, s.Type, s.Topology = "count", "count", "float64", "loopback" },
+		func(s *scenario) {
+			s.Name, s.Sketch, s.MergeEvery, s.MergeInterval = "hll", "hll", 0, duration(20*time.Millisecond)
+		},
+		func(s *scenario) { s.Name, s.Sketch, s.Clients, s.Data = "asketch", "asketch", 2, "gen:zipf?s=1.3" },
+		func(s *scenario) { s.Name, s.MergeEvery, s.MergeBytes, s.Arrival = "kll-bytes", 0, 20_000, "bursty" },
+		func(s *scenario) { s.Name, s.Sketch, s.Clients = "badKll", "badKll", 2 },
+		func(s *scenario) { s.Name, s.Sketch, s.Type = "badCount", "badCount", "float64" },
+	} {
+		base := defaultScenario()
+		base.Duration = duration(300 * time.Millisecond)
+		base.Items = 10_000
+		set(&base)
+		if err := base.validate(); err != nil {
+			t.Fatal(err)
+		}
+		r, err := run(base, 1)
+		if err != nil {
+			t.Fatalf("%s: %v", base.Name, err)
+		}
+		if r.merges == 0 || r.errors != 0 || len(r.latencies) != int(r.merges) {
+			t.Errorf("%s: %d merges, %d errors, %d latencies", base.Name, r.merges, r.errors, len(r.latencies))
+		}
+		if strings.HasPrefix(base.Sketch, "bad") {
+			if r.sent <= r.merges*int64(base.MergeEvery) {
+				t.Errorf("%s: sent %d bytes in %d merges of %d items", base.Name, r.sent, r.merges, base.MergeEvery)
+			}
+			continue
+		}
+		// the clients merge what they have left when the scenario ends
+		if every := int64(base.MergeEvery); every > 0 && (r.items <= (r.merges-int64(base.Clients))*every || r.items > r.merges*every) {
+			t.Errorf("%s: %d items in %d merges of at most %d", base.Name, r.items, r.merges, every)
+		}
+		if r.sent <= r.merges || r.server <= 0 {
+			t.Errorf("%s: sent %d bytes, server sketch of %d bytes", base.Name, r.sent, r.server)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var ds []time.Duration
+	for i := 1; i <= 100; i++ {
+		ds = append(ds, time.Duration(i))
+	}
+	for p, want := range map[float64]time.Duration{0.5: 50, 0.9: 90, 0.99: 99, 1: 100, 0: 1} {
+		if got := percentile(ds, p); got != want {
+			t.Errorf("p%v is %d, want %d", p, got, want)
+		}
+	}
+	if percentile(nil, 0.5) != 0 {
+		t.Error("percentile of no latencies is not 0")
+	}
+}
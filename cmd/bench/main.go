@@ -0,0 +1,115 @@
+// Command bench runs the throughput and latency experiments described by
+// scenario files:
+//
+//	bench [-format table|json|csv] [-out file] [-only name] scenarios.json...
+//
+// Every scenario starts a server in this process, unless its topology is
+// remote, and a number of clients that build sketches from a data set and
+// merge them for a fixed time. One row is reported per run with the items and
+// merges per second, the merge latency percentiles, the bytes sent and
+// received and the memory used. See benchmarking/scenarios for examples.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bruhng/distributed-sketching/output"
+	"github.com/bruhng/distributed-sketching/security"
+)
+
+var columns = []string{
+	"scenario", "run", "sketch", "type", "topology", "clients", "merge_every", "stream_rate",
+	"seconds", "items", "items_per_s", "merges", "merges_per_s", "errors",
+	"p50_ms", "p90_ms", "p99_ms", "max_ms", "mean_ms",
+	"sent_bytes", "received_bytes", "bytes_per_merge",
+	"heap_bytes", "alloc_bytes_per_item", "server_sketch_bytes",
+}
+
+func row(r *result) []any {
+	s := r.scenario
+	return []any{
+		s.Name, int64(r.run), s.Sketch, s.Type, s.Topology, int64(s.Clients), int64(s.MergeEvery), s.StreamRate,
+		r.elapsed.Seconds(), r.items, perSecond(r.items, r.elapsed), r.merges, perSecond(r.merges, r.elapsed), r.errors,
+		ms(percentile(r.latencies, 0.5)), ms(percentile(r.latencies, 0.9)), ms(percentile(r.latencies, 0.99)),
+		ms(percentile(r.latencies, 1)), ms(mean(r.latencies)),
+		r.sent, r.received, perUnit(float64(r.sent), r.merges+r.errors),
+		r.heap, perUnit(float64(r.allocated), r.items), r.server,
+	}
+}
+
+func run(s scenario, n int) (*result, error) {
+	if s.Type == "float64" {
+		return runScenario[float64](s, n)
+	}
+	return runScenario[int](s, n)
+}
+
+func main() {
+	format := flag.String("format", "table", "output format: table, json or csv")
+	out := flag.String("out", "", "file to write the results to instead of standard output")
+	only := flag.String("only", "", "run only the scenarios whose name contains this")
+	list := flag.Bool("list", false, "list the scenarios instead of running them")
+	security.RegisterFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: bench [flags] scenarios.json...\n\nflags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := (&output.Table{}).Write(io.Discard, *format); err != nil {
+		log.Fatal(err)
+	}
+
+	var scenarios []scenario
+	for _, path := range flag.Args() {
+		loaded, err := loadScenarios(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, s := range loaded {
+			if strings.Contains(s.Name, *only) {
+				scenarios = append(scenarios, s)
+			}
+		}
+	}
+	if *list {
+		for _, s := range scenarios {
+			fmt.Printf("%s: %s %s, %d clients, %s for %s\n", s.Name, s.Sketch, s.Type, s.Clients, s.Topology, time.Duration(s.Duration))
+		}
+		return
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	table := &output.Table{Columns: columns}
+	for _, s := range scenarios {
+		for n := 1; n <= s.Repeat; n++ {
+			log.Printf("running %s (%d/%d)", s.Name, n, s.Repeat)
+			r, err := run(s, n)
+			if err != nil {
+				log.Fatalf("%s: %v", s.Name, err)
+			}
+			table.Rows = append(table.Rows, row(r))
+		}
+	}
+	if err := table.Write(w, *format); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bruhng/distributed-sketching/client"
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/security"
+	"github.com/bruhng/distributed-sketching/server"
+	"github.com/bruhng/distributed-sketching/shared"
+	"github.com/bruhng/distributed-sketching/stream"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const bufSize = 1024 * 1024 * 100
+
+// result is what one run of a scenario measured.
+type result struct {
+	scenario  scenario
+	run       int
+	elapsed   time.Duration
+	items     int64 // items the clients sketched, see errors for lost merges
+	merges    int64
+	errors    int64
+	latencies []time.Duration
+	sent      int64 // bytes on the wire, with gRPC framing
+	received  int64
+	heap      int64  // growth of the live heap of this process
+	allocated uint64 // bytes allocated by this process
+	server    int64  // memory of the merged sketch on the server
+}
+
+// wireCounter counts the payload bytes of every RPC of a connection.
+type wireCounter struct {
+	sent, received atomic.Int64
+}
+
+func (w *wireCounter) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (w *wireCounter) HandleRPC(_ context.Context, s stats.RPCStats) {
+	switch s := s.(type) {
+	case *stats.OutPayload:
+		w.sent.Add(int64(s.WireLength))
+	case *stats.InPayload:
+		w.received.Add(int64(s.WireLength))
+	}
+}
+
+func (w *wireCounter) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (w *wireCounter) HandleConn(context.Context, stats.ConnStats) {}
+
+// target starts the server of a scenario, unless it is remote, and returns the
+// address and options clients dial it with and a function stopping it.
+func target(s scenario) (string, []grpc.DialOption, func(), error) {
+	plain := grpc.WithTransportCredentials(insecure.NewCredentials())
+	switch s.Topology {
+	case "bufconn":
+		lis := bufconn.Listen(bufSize)
+		srv := server.NewServer().NewGRPCServer()
+		go srv.Serve(lis)
+		dialer := grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		})
+		return "passthrough:///bufnet", []grpc.DialOption{plain, dialer}, srv.Stop, nil
+	case "loopback":
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return "", nil, nil, err
+		}
+		srv := server.NewServer().NewGRPCServer()
+		go srv.Serve(lis)
+		return lis.Addr().String(), []grpc.DialOption{plain}, srv.Stop, nil
+	}
+	opts, err := security.DialOptions()
+	return s.Addr, opts, func() {}, err
+}
+
+// load reads the values the clients of a scenario replay. Generated data is
+// drawn directly so it does not need a stream running forever.
+func load[T shared.Number](s scenario) ([]T, error) {
+	if spec, ok := strings.CutPrefix(s.Data, "gen:"); ok {
+		g, err := stream.ParseGeneratorSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		n := s.Items
+		if g.N > 0 && g.N < n {
+			n = g.N
+		}
+		return stream.Generate[T](g, n), nil
+	}
+	src, err := stream.Open[T](s.Data, s.Field, 0, 1)
+	if err != nil {
+		return nil, err
+	}
+	var data []T
+	for v := range src.Data {
+		if len(data) < s.Items {
+			data = append(data, v)
+		}
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%s has no %s values in %s", s.Data, s.Type, s.Field)
+	}
+	return data, nil
+}
+
+// clientStats is what one client measured.
+type clientStats struct {
+	items, merges, errors int64
+	latencies             []time.Duration
+}
+
+// record times the merge requests of a client, the baselines send theirs to
+// BadKll and BadCount
+func (st *clientStats) record(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	if m := path.Base(method); strings.HasPrefix(m, "Merge") || strings.HasPrefix(m, "Bad") {
+		st.latencies = append(st.latencies, time.Since(start))
+		if err != nil {
+			st.errors++
+		} else {
+			st.merges++
+		}
+	}
+	return err
+}
+
+// runClient replays data from offset on through the client of the scenario's
+// sketch until ctx is done, when the client merges what it has left. The
+// baseline clients drop the items they have not sent.
+func runClient[T shared.Number](ctx context.Context, s scenario, addr string, opts []grpc.DialOption, data []T, offset int, name string) clientStats {
+	var st clientStats
+	opts = append(slices.Clone(opts), grpc.WithUnaryInterceptor(st.record))
+	dial := func(addr string) (pb.SketcherClient, *grpc.ClientConn, error) {
+		conn, err := grpc.NewClient(addr, opts...)
+		if err != nil {
+			return nil, nil, err
+		}
+		return pb.NewSketcherClient(conn), conn, nil
+	}
+
+	// the client takes every item from the unbuffered channel, so the items
+	// sent are the items it sketched
+	arrival, _ := stream.ParseArrival(s.Arrival)
+	limiter := stream.NewLimiter(s.StreamRate, arrival)
+	items := stream.Stream[T]{Data: make(chan T), Errors: make(chan error)}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer close(items.Errors)
+		defer close(items.Data)
+		for i := offset; ; i++ {
+			limiter.Wait()
+			select {
+			case items.Data <- data[i%len(data)]:
+				st.items++
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	policy := s.policy()
+	policy.Done = ctx.Done()
+	switch s.Sketch {
+	case "kll":
+		client.KllClient(s.K, policy, items, name, addr, dial)
+	case "count":
+		client.CountClient(policy, items, name, addr, dial)
+	case "hll":
+		client.HllClient(policy, items, name, addr, dial)
+	case "asketch":
+		client.ASketchClient(policy, items, name, addr, dial)
+	case "badKll":
+		client.BadKllClient(s.MergeEvery, items, addr, dial)
+	case "badCount":
+		client.BadCountClient(s.MergeEvery, items, addr, dial)
+	}
+	<-done
+	return st
+}
+
+// measure collects the garbage and returns the live heap and the bytes
+// allocated so far.
+func measure() (int64, uint64) {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return int64(m.HeapAlloc), m.TotalAlloc
+}
+
+// runScenario runs s once. The clients merge into a sketch of their own,
+// named after the scenario and run, so runs do not add up on a remote server.
+func runScenario[T shared.Number](s scenario, run int) (*result, error) {
+	data, err := load[T](s)
+	if err != nil {
+		return nil, err
+	}
+	addr, opts, stop, err := target(s)
+	if err != nil {
+		return nil, err
+	}
+	defer stop()
+	// a connection of its own checks the server is up and describes the
+	// merged sketch, outside of the measured traffic
+	conn, err := grpc.NewClient(addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	control := pb.NewSketcherClient(conn)
+	pctx, pcancel := context.WithTimeout(context.Background(), 10*time.Second)
+	_, err = control.TestLatency(pctx, &pb.EmptyMessage{})
+	pcancel()
+	if err != nil {
+		return nil, fmt.Errorf("could not reach the server: %w", err)
+	}
+	wire := &wireCounter{}
+	opts = append(opts, grpc.WithStatsHandler(wire))
+
+	name := fmt.Sprintf("bench:%s:%d", s.Name, run)
+	heapBefore, allocBefore := measure()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.Duration))
+	defer cancel()
+	results := make([]clientStats, s.Clients)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// every client dials a connection of its own, like separate
+			// devices, and starts at a different item so the sketches differ
+			results[i] = runClient(ctx, s, addr, opts, data, i*len(data)/s.Clients, name)
+		}()
+	}
+	wg.Wait()
+	r := &result{scenario: s, run: run, elapsed: time.Since(start), sent: wire.sent.Load(), received: wire.received.Load()}
+	heapAfter, allocAfter := measure()
+	runtime.KeepAlive(data)
+	r.heap = heapAfter - heapBefore
+	r.allocated = allocAfter - allocBefore
+
+	for _, st := range results {
+		r.items += st.items
+		r.merges += st.merges
+		r.errors += st.errors
+		r.latencies = append(r.latencies, st.latencies...)
+	}
+	sort.Slice(r.latencies, func(i, j int) bool { return r.latencies[i] < r.latencies[j] })
+
+	// the baselines are one unnamed sketch per type the server does not
+	// describe
+	if strings.HasPrefix(s.Sketch, "bad") {
+		return r, nil
+	}
+	dctx, dcancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer dcancel()
+	info, err := control.DescribeSketch(dctx, &pb.DescribeRequest{Name: name, Kind: s.Sketch, Type: s.Type})
+	if err != nil {
+		return nil, fmt.Errorf("could not describe the merged sketch: %w", err)
+	}
+	for _, sk := range info.GetSketches() {
+		r.server += sk.GetMemoryBytes()
+	}
+	return r, nil
+}
+
+// percentile returns the nearest rank percentile p of sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(p*float64(len(sorted))+0.5) - 1
+	return sorted[min(max(i, 0), len(sorted)-1)]
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func mean(ds []time.Duration) time.Duration {
+	if len(ds) == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, d := range ds {
+		sum += d
+	}
+	return sum / time.Duration(len(ds))
+}
+
+// perSecond and perUnit divide without turning an empty run into NaN
+func perSecond(n int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(n) / d.Seconds()
+}
+
+func perUnit(n float64, units int64) float64 {
+	if units <= 0 {
+		return 0
+	}
+	return n / float64(units)
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bruhng/distributed-sketching/client"
+	"github.com/bruhng/distributed-sketching/stream"
+)
+
+// scenario is one benchmark run. Scenario files hold a list of them:
+//
+//	{
+//	  "defaults": {"duration": "10s", "data": "gen:zipf?s=1.2"},
+//	  "scenarios": [
+//	    {"name": "kll", "sketch": "kll", "sweep": {"clients": [1, 8, 64]}}
+//	  ]
+//	}
+//
+// Every scenario starts from the defaults. A sweep runs the scenario once for
+// every combination of the listed values.
+type scenario struct {
+	Name string `json:"name"`
+	// Sketch is kll, count, hll or asketch, or badKll or badCount for the
+	// centralized baselines, whose clients send their raw items every
+	// merge_every items.
+	Sketch string `json:"sketch"`
+	Type   string `json:"type"` // int or float (float64)
+	// Clients each build their own sketch from the data and merge it.
+	Clients int `json:"clients"`
+	// MergeEvery merges after this many items, MergeInterval on a ticker of
+	// this period and MergeBytes once a KLL sketch is this large, whichever
+	// comes first, like the merge policy of the clients. Zero disables a
+	// trigger.
+	MergeEvery    int      `json:"merge_every"`
+	MergeInterval duration `json:"merge_interval"`
+	MergeBytes    int      `json:"merge_bytes"`
+	// StreamRate is the items per second of every client, 0 is unlimited.
+	StreamRate float64  `json:"stream_rate"`
+	Arrival    string   `json:"arrival"` // uniform, bursty or poisson
+	Duration   duration `json:"duration"`
+	// Topology is bufconn (an in memory connection), loopback (TCP on
+	// 127.0.0.1) or remote (the server at Addr).
+	Topology string `json:"topology"`
+	Addr     string `json:"addr"`
+	// Data is a data set spec as taken by the clients, Field its column. At
+	// most Items values are read once and replayed by every client.
+	Data  string `json:"data"`
+	Field string `json:"field"`
+	Items int    `json:"items"`
+	K     int    `json:"k"` // of KLL sketches
+	// Repeat runs the scenario this many times, one result row each.
+	Repeat int `json:"repeat"`
+}
+
+func defaultScenario() scenario {
+	return scenario{
+		Sketch:     "kll",
+		Type:       "int",
+		Clients:    1,
+		MergeEvery: 1000,
+		Duration:   duration(10 * time.Second),
+		Topology:   "bufconn",
+		Data:       "gen:uniform?max=1000000",
+		Field:      "value",
+		Items:      1_000_000,
+		K:          200,
+		Repeat:     1,
+	}
+}
+
+// duration reads as a string like "500ms" or "1m".
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("durations are strings like \"10s\": %s", b)
+	}
+	v, err := time.ParseDuration(s)
+	*d = duration(v)
+	return err
+}
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// policy is the merge policy of the clients of s
+func (s scenario) policy() client.MergePolicy {
+	return client.MergePolicy{Every: s.MergeEvery, Interval: time.Duration(s.MergeInterval), MaxBytes: s.MergeBytes}
+}
+
+func (s scenario) validate() error {
+	switch s.Sketch {
+	case "kll", "count", "hll", "asketch", "badKll", "badCount":
+	default:
+		return fmt.Errorf("%s is not supported, please submit a valid sketch", s.Sketch)
+	}
+	switch s.Type {
+	case "int", "float64":
+	default:
+		return fmt.Errorf("%s is not supported, please submit a valid type", s.Type)
+	}
+	switch s.Topology {
+	case "bufconn", "loopback":
+	case "remote":
+		if s.Addr == "" {
+			return fmt.Errorf("the remote topology needs an addr")
+		}
+	default:
+		return fmt.Errorf("%s is not supported, please submit a valid topology", s.Topology)
+	}
+	if _, err := stream.ParseArrival(s.Arrival); err != nil {
+		return err
+	}
+	if err := s.policy().Validate(s.Sketch); err != nil {
+		return err
+	}
+	switch {
+	case s.Clients < 1:
+		return fmt.Errorf("clients must be at least 1")
+	case s.MergeEvery <= 0 && s.MergeInterval <= 0 && s.MergeBytes <= 0:
+		return fmt.Errorf("merge_every, merge_interval or merge_bytes must be set")
+	case s.Duration <= 0:
+		return fmt.Errorf("duration must be positive")
+	case s.Items < 1 || s.K < 1 || s.Repeat < 1:
+		return fmt.Errorf("items, k and repeat must be at least 1")
+	}
+	return nil
+}
+
+// loadScenarios reads a scenario file and expands its sweeps.
+func loadScenarios(path string) ([]scenario, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Defaults  map[string]json.RawMessage   `json:"defaults"`
+		Scenarios []map[string]json.RawMessage `json:"scenarios"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(file.Scenarios) == 0 {
+		return nil, fmt.Errorf("%s has no scenarios", path)
+	}
+	var out []scenario
+	for i, entry := range file.Scenarios {
+		expanded, err := expand(file.Defaults, entry)
+		if err != nil {
+			return nil, fmt.Errorf("%s: scenario %d: %w", path, i+1, err)
+		}
+		out = append(out, expanded...)
+	}
+	return out, nil
+}
+
+// expand returns a scenario for every combination of the sweep of entry, named
+// after the swept values, e.g. "kll/clients=8,stream_rate=100".
+func expand(defaults map[string]json.RawMessage, entry map[string]json.RawMessage) ([]scenario, error) {
+	fields := maps.Clone(defaults)
+	if fields == nil {
+		fields = map[string]json.RawMessage{}
+	}
+	maps.Copy(fields, entry)
+	var sweep map[string][]json.RawMessage
+	if raw, ok := fields["sweep"]; ok {
+		if err := json.Unmarshal(raw, &sweep); err != nil {
+			return nil, fmt.Errorf("sweep must map fields to lists of values: %w", err)
+		}
+		delete(fields, "sweep")
+	}
+	keys := slices.Sorted(maps.Keys(sweep))
+
+	combos := [][]json.RawMessage{{}}
+	for _, k := range keys {
+		if len(sweep[k]) == 0 {
+			return nil, fmt.Errorf("sweep of %s has no values", k)
+		}
+		var next [][]json.RawMessage
+		for _, combo := range combos {
+			for _, v := range sweep[k] {
+				next = append(next, append(slices.Clone(combo), v))
+			}
+		}
+		combos = next
+	}
+
+	var out []scenario
+	for _, combo := range combos {
+		var labels []string
+		for i, k := range keys {
+			fields[k] = combo[i]
+			labels = append(labels, k+"="+strings.Trim(string(combo[i]), `"`))
+		}
+		b, err := json.Marshal(fields)
+		if err != nil {
+			return nil, err
+		}
+		s := defaultScenario()
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&s); err != nil {
+			return nil, err
+		}
+		if s.Type == "float" {
+			s.Type = "float64"
+		}
+		if s.Name == "" {
+			s.Name = s.Sketch
+		}
+		if len(labels) > 0 {
+			s.Name += "/" + strings.Join(labels, ",")
+		}
+		if err := s.validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", s.Name, err)
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
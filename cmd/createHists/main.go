@@ -1,6 +1,6 @@
 package main
 
-import (	
+import (
 	"encoding/csv"
 	"fmt"
 	"image/color"
@@ -13,7 +13,7 @@ import (
 	"gonum.org/v1/plot/vg"
 )
 
-func main () {
+func main() {
 	plotHist("../../data/PVS 1/dataset_gps.csv", "speed_meters_per_second", 20)
 }
 
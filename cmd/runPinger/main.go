@@ -2,14 +2,15 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"time"
 
 	"github.com/bruhng/distributed-sketching/client"
 	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/security"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 )
 
 var DATA_SET_PATH = "../data/PVS 1/dataset_gps.csv"
@@ -21,8 +22,15 @@ var NUM_STREAM_RUNS = 10
 var STREAM_DELAY = 0
 
 func main() {
+	security.RegisterFlags(flag.CommandLine)
+	flag.Parse()
 	samples := 10000
-	conn, err := grpc.NewClient(SERVER_ADR+":"+PORT, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	opts, err := security.DialOptions()
+	if err != nil {
+		fmt.Println(err)
+		panic("Could not load TLS configuration")
+	}
+	conn, err := grpc.NewClient(SERVER_ADR+":"+PORT, opts...)
 	if err != nil {
 		fmt.Println(err)
 		panic("Could not connect to server")
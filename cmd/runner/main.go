@@ -25,9 +25,9 @@ func main() {
 		go func() {
 			defer wg.Done()
 			if *typ == "float" {
-				client.Init[float64](*port, *addr, *sketch, *data, *field, -1, *stream, *merge)
+				client.Init[float64](*port, *addr, *sketch, *data, *field, -1, *stream, client.EveryN(*merge))
 			} else {
-				client.Init[int](*port, *addr, *sketch, *data, *field, -1, *stream, *merge)
+				client.Init[int](*port, *addr, *sketch, *data, *field, -1, *stream, client.EveryN(*merge))
 			}
 		}()
 	}
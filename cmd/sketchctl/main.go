@@ -0,0 +1,70 @@
+// Command sketchctl queries a sketch server from scripts:
+//
+//	sketchctl [flags] <command> [command flags]
+//
+// It prints the result as a table, JSON or CSV and exits with 0 on success, 1
+// when the server returns an error and 2 on invalid arguments. The command
+// repl starts the interactive consumer instead.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bruhng/distributed-sketching/consumer"
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/security"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	address := flag.String("a", "127.0.0.1", "address of the server")
+	port := flag.String("port", "8080", "port of the server")
+	format := flag.String("o", "table", "output format: table, json or csv")
+	timeout := flag.Duration("timeout", 5*time.Second, "time allowed for the whole command")
+	security.RegisterFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: sketchctl [flags] <command> [command flags]\n\nflags:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\ncommands:\n  repl  interactive consumer\n")
+		consumer.Usage(os.Stderr)
+	}
+	flag.Parse()
+
+	if flag.Arg(0) == "repl" {
+		consumer.Init(*port, *address)
+		return
+	}
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	opts, err := security.DialOptions()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	conn, err := grpc.NewClient(*address+":"+*port, opts...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	ctl := consumer.Ctl{Client: pb.NewSketcherClient(conn), Out: os.Stdout, Err: os.Stderr, Format: *format}
+	if err := ctl.Run(ctx, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "sketchctl:", err)
+		conn.Close()
+		if errors.Is(err, consumer.ErrUsage) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,407 @@
+// Command sketchtool works on sketch files without a server:
+//
+//	sketchtool [-format table|json|csv] <command> [command flags] files...
+//
+// Sketch files are written by sketchctl save, by the server's -state flag and
+// by sketchtool merge and convert. It exits with 0 on success, 1 when a file
+// can not be used and 2 on invalid arguments.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bruhng/distributed-sketching/consumer"
+	"github.com/bruhng/distributed-sketching/output"
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/sketchfile"
+)
+
+type command struct {
+	usage string
+	run   func(fs *flag.FlagSet, args []string) (*output.Table, error)
+}
+
+var commands = map[string]command{
+	"merge":   {"-o file [-to pb|json] [-as name] files...  merge the sketches of the same kind, name and type", mergeCmd},
+	"query":   {"quantile|rank|freq|cardinality [-phi 0.5,0.9] [-x value] [-sketch asketch|count] [-name name] [-type float|int] file  query a sketch", queryCmd},
+	"topk":    {"[-k 10] [-name name] [-type float|int] file  most frequent values of an ASketch", topkCmd},
+	"info":    {"files...  kind, name, type, items and parameters of every sketch", infoCmd},
+	"convert": {"[-to pb|json] in out  rewrite a file in another format", convertCmd},
+	"diff":    {"[-kind kind] [-type float|int] [-name name] [-name2 name] a b  distance between two sketches", diffCmd},
+}
+
+func main() {
+	format := flag.String("format", "table", "output format: table, json or csv")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: sketchtool [flags] <command> [command flags] files...\n\nflags:\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\ncommands:\n")
+		usage(os.Stderr)
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Args(), *format, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "sketchtool:", err)
+		if errors.Is(err, consumer.ErrUsage) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+func usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s %s\n", name, commands[name].usage)
+	}
+}
+
+// run executes args, a command followed by its flags and files, and writes
+// its result in format.
+func run(args []string, format string, out io.Writer, errOut io.Writer) error {
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return usageError("%s is not a command", args[0])
+	}
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	fs.Usage = func() {
+		fmt.Fprintf(errOut, "usage: sketchtool %s %s\n", args[0], cmd.usage)
+		fs.PrintDefaults()
+	}
+	table, err := cmd.run(fs, args[1:])
+	if err != nil {
+		return err
+	}
+	return table.Write(out, format)
+}
+
+func usageError(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", consumer.ErrUsage, fmt.Sprintf(format, args...))
+}
+
+// parse parses the flags of fs wherever they are in args, so they may follow
+// the files, and returns the other arguments.
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var rest []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, fmt.Errorf("%w: %w", consumer.ErrUsage, err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return rest, nil
+		}
+		rest = append(rest, args[0])
+		args = args[1:]
+	}
+}
+
+// protoType maps the float/int of the command line to the type names of the
+// sketches, empty matches both.
+func protoType(typ string) (string, error) {
+	switch typ {
+	case "":
+		return "", nil
+	case "float", "float64":
+		return "float64", nil
+	case "int":
+		return "int", nil
+	}
+	return "", usageError("%s is not a valid type, use float or int", typ)
+}
+
+func format(to string, path string) (string, error) {
+	if to == "" {
+		return sketchfile.FormatOf(path), nil
+	}
+	for _, f := range sketchfile.Formats {
+		if f == to {
+			return to, nil
+		}
+	}
+	return "", usageError("%s is not a file format, use %s", to, strings.Join(sketchfile.Formats, " or "))
+}
+
+func readAll(paths []string) ([]*pb.ServerState, error) {
+	states := make([]*pb.ServerState, len(paths))
+	for i, path := range paths {
+		state, err := sketchfile.Read(path)
+		if err != nil {
+			return nil, err
+		}
+		states[i] = state
+	}
+	return states, nil
+}
+
+func infoTable(state *pb.ServerState) *output.Table {
+	t := &output.Table{Columns: []string{"kind", "name", "type", "n", "params"}}
+	for _, info := range sketchfile.Describe(state) {
+		keys := make([]string, 0, len(info.Params))
+		for k := range info.Params {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		params := make([]string, len(keys))
+		for i, k := range keys {
+			params[i] = fmt.Sprintf("%s=%d", k, info.Params[k])
+		}
+		t.Rows = append(t.Rows, []any{info.Kind, info.Name, info.Type, info.N, strings.Join(params, " ")})
+	}
+	return t
+}
+
+func mergeCmd(fs *flag.FlagSet, args []string) (*output.Table, error) {
+	out := fs.String("o", "", "file to write")
+	to := fs.String("to", "", "format of the file, pb or json, by default json for .json files")
+	as := fs.String("as", "", "merge every sketch under this name instead of its own")
+	files, err := parse(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if *out == "" || len(files) == 0 {
+		return nil, usageError("merge needs -o and at least one file")
+	}
+	f, err := format(*to, *out)
+	if err != nil {
+		return nil, err
+	}
+	states, err := readAll(files)
+	if err != nil {
+		return nil, err
+	}
+	if *as != "" {
+		for _, state := range states {
+			sketchfile.Rename(state, *as)
+		}
+	}
+	merged, err := sketchfile.Merge(states...)
+	if err != nil {
+		return nil, err
+	}
+	if err := sketchfile.Write(*out, merged, f); err != nil {
+		return nil, err
+	}
+	return infoTable(merged), nil
+}
+
+func queryCmd(fs *flag.FlagSet, args []string) (*output.Table, error) {
+	phis := fs.String("phi", "0.5", "comma separated quantiles between 0 and 1, for quantile")
+	x := fs.Float64("x", 0, "value to rank or count, for rank and freq")
+	counter := fs.String("sketch", "asketch", "sketch that counts, asketch or count, for freq")
+	name := fs.String("name", "", "name of the sketch, empty for the unnamed one")
+	typ := fs.String("type", "", "element type of the sketch, float or int, empty for either")
+	rest, err := parse(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 2 {
+		return nil, usageError("query needs a query and one file")
+	}
+	t, err := protoType(*typ)
+	if err != nil {
+		return nil, err
+	}
+	var kind string
+	switch rest[0] {
+	case "quantile", "rank":
+		kind = "kll"
+	case "cardinality":
+		kind = "hll"
+	case "freq":
+		if *counter != "asketch" && *counter != "count" {
+			return nil, usageError("%s can not count values, use asketch or count", *counter)
+		}
+		kind = *counter
+	default:
+		return nil, usageError("%s is not a query, use quantile, rank, freq or cardinality", rest[0])
+	}
+	state, err := sketchfile.Read(rest[1])
+	if err != nil {
+		return nil, err
+	}
+	sketch, err := sketchfile.Select(state, kind, *name, t)
+	if err != nil {
+		return nil, err
+	}
+
+	switch rest[0] {
+	case "quantile":
+		var values []float64
+		for _, s := range strings.Split(*phis, ",") {
+			phi, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil || phi < 0 || phi > 1 {
+				return nil, usageError("%s is not a quantile between 0 and 1", s)
+			}
+			values = append(values, phi)
+		}
+		quantiles, err := sketchfile.Quantiles(sketch.Kll, values)
+		if err != nil {
+			return nil, err
+		}
+		table := &output.Table{Columns: []string{"phi", "value"}}
+		for i, phi := range values {
+			table.Rows = append(table.Rows, []any{phi, quantiles[i]})
+		}
+		return table, nil
+	case "rank":
+		rank, err := sketchfile.Rank(sketch.Kll, *x)
+		if err != nil {
+			return nil, err
+		}
+		quantile := 0.0
+		if sketch.Kll.N > 0 {
+			quantile = float64(rank) / float64(sketch.Kll.N)
+		}
+		return &output.Table{Columns: []string{"value", "rank", "n", "quantile"}, Rows: [][]any{{*x, int64(rank), sketch.Kll.N, quantile}}}, nil
+	case "freq":
+		n, err := sketchfile.Frequency(sketch, *x)
+		if err != nil {
+			return nil, err
+		}
+		return &output.Table{Columns: []string{"sketch", "value", "count"}, Rows: [][]any{{sketch.Kind(), *x, int64(n)}}}, nil
+	}
+	estimate, err := sketchfile.Cardinality(sketch.Hll)
+	if err != nil {
+		return nil, err
+	}
+	return &output.Table{Columns: []string{"name", "estimate"}, Rows: [][]any{{*name, estimate}}}, nil
+}
+
+func topkCmd(fs *flag.FlagSet, args []string) (*output.Table, error) {
+	k := fs.Int("k", 10, "number of values")
+	name := fs.String("name", "", "field of the ASketch, empty for the unnamed one")
+	typ := fs.String("type", "", "element type of the sketch, float or int, empty for either")
+	files, err := parse(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) != 1 {
+		return nil, usageError("topk needs one file")
+	}
+	t, err := protoType(*typ)
+	if err != nil {
+		return nil, err
+	}
+	state, err := sketchfile.Read(files[0])
+	if err != nil {
+		return nil, err
+	}
+	sketch, err := sketchfile.Select(state, "asketch", *name, t)
+	if err != nil {
+		return nil, err
+	}
+	table := &output.Table{Columns: []string{"rank", "value", "count"}}
+	for i, e := range sketchfile.TopK(sketch.Asketch, *k) {
+		var value any = e.Item.GetFloatVal()
+		if v, ok := e.Item.GetValue().(*pb.NumericValue_IntVal); ok {
+			value = v.IntVal
+		}
+		table.Rows = append(table.Rows, []any{int64(i + 1), value, e.New})
+	}
+	return table, nil
+}
+
+func infoCmd(fs *flag.FlagSet, args []string) (*output.Table, error) {
+	files, err := parse(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, usageError("info needs at least one file")
+	}
+	states, err := readAll(files)
+	if err != nil {
+		return nil, err
+	}
+	table := &output.Table{Columns: []string{"file", "kind", "name", "type", "n", "params"}}
+	for i, state := range states {
+		for _, row := range infoTable(state).Rows {
+			table.Rows = append(table.Rows, append([]any{files[i]}, row...))
+		}
+	}
+	return table, nil
+}
+
+func convertCmd(fs *flag.FlagSet, args []string) (*output.Table, error) {
+	to := fs.String("to", "", "format to write, pb or json, by default json for .json files")
+	files, err := parse(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) != 2 {
+		return nil, usageError("convert needs an input and an output file")
+	}
+	f, err := format(*to, files[1])
+	if err != nil {
+		return nil, err
+	}
+	state, err := sketchfile.Read(files[0])
+	if err != nil {
+		return nil, err
+	}
+	if err := sketchfile.Write(files[1], state, f); err != nil {
+		return nil, err
+	}
+	return infoTable(state), nil
+}
+
+func diffCmd(fs *flag.FlagSet, args []string) (*output.Table, error) {
+	kind := fs.String("kind", "", "kind of the sketches, kll, count, hll or asketch, empty for any")
+	typ := fs.String("type", "", "element type of the sketches, float or int, empty for either")
+	name := fs.String("name", "", "name of the sketch in the first file, and in the second unless -name2 is given")
+	name2 := fs.String("name2", "", "name of the sketch in the second file")
+	files, err := parse(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) != 2 {
+		return nil, usageError("diff needs two files, which may be the same one")
+	}
+	t, err := protoType(*typ)
+	if err != nil {
+		return nil, err
+	}
+	otherName := *name
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "name2" {
+			otherName = *name2
+		}
+	})
+	states, err := readAll(files)
+	if err != nil {
+		return nil, err
+	}
+	a, err := sketchfile.Select(states[0], *kind, *name, t)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", files[0], err)
+	}
+	b, err := sketchfile.Select(states[1], a.Kind(), otherName, a.Type())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", files[1], err)
+	}
+	metrics, err := sketchfile.Diff(a, b)
+	if err != nil {
+		return nil, err
+	}
+	table := &output.Table{Columns: []string{"kind", "metric", "value"}}
+	for _, m := range metrics {
+		table.Rows = append(table.Rows, []any{a.Kind(), m.Name, m.Value})
+	}
+	return table, nil
+}
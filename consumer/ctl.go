@@ -0,0 +1,531 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bruhng/distributed-sketching/output"
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrUsage marks errors in the arguments of a command, as opposed to errors
+// returned by the server.
+var ErrUsage = errors.New("usage")
+
+func usageError(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrUsage, fmt.Sprintf(format, args...))
+}
+
+// Ctl runs the non interactive commands of sketchctl against a server.
+type Ctl struct {
+	Client pb.SketcherClient
+	Out    io.Writer
+	Err    io.Writer
+	Format string
+}
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, c pb.SketcherClient, fs *flag.FlagSet, args []string) (*output.Table, error)
+}
+
+var commands = map[string]command{
+	"quantile":    {"-phi 0.5,0.99 [-type float|int] [-name name]  values at quantiles of a KLL sketch", quantileCmd},
+	"rank":        {"-x value [-type float|int] [-name name]  rank and quantile of a value in a KLL sketch", rankCmd},
+	"topk":        {"[-k 10] [-type float|int] [-name field]  most frequent values of an ASketch", topkCmd},
+	"freq":        {"-x value [-sketch asketch|count] [-type float|int] [-name name]  estimated frequency of a value", freqCmd},
+	"hist":        {"[-bins 10] [-min x -max y] [-equidepth] [-splits a,b,c] [-type float|int] [-name name]  histogram of a KLL sketch", histCmd},
+	"cardinality": {"[-type float|int] [-name name]  estimated distinct values of an HLL sketch", cardinalityCmd},
+	"list":        {"[-type float|int]  fields with an ASketch", listCmd},
+	"snapshot":    {"[-type float|int] [-name name]  summary of every sketch kept under a name", snapshotCmd},
+	"save":        {"-out file [-name name | -all] [-kind kll|count|hll|asketch] [-type float|int]  write sketches of the server to a file", saveCmd},
+	"describe":    {"[-name name] [-kind kll|count|hll|asketch] [-type float|int]  parameters, size and merges of the sketches kept under a name", describeCmd},
+	"load":        {"-in file [-mode merge|replace] [-as name]  send the sketches of a file to the server", loadCmd},
+}
+
+// Usage lists the commands.
+func Usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s %s\n", name, commands[name].usage)
+	}
+}
+
+// Run executes args, a command followed by its flags, and writes the result
+// in the format of c. Mistakes in args are reported as ErrUsage.
+func (c *Ctl) Run(ctx context.Context, args []string) error {
+	switch c.Format {
+	case "", "table", "json", "csv":
+	default:
+		return usageError("%s is not an output format, use table, json or csv", c.Format)
+	}
+	if len(args) == 0 {
+		return usageError("no command given")
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return usageError("%s is not a command", args[0])
+	}
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	fs.SetOutput(c.Err)
+	fs.Usage = func() {
+		fmt.Fprintf(c.Err, "usage: %s %s\n", args[0], cmd.usage)
+		fs.PrintDefaults()
+	}
+	table, err := cmd.run(ctx, c.Client, fs, args[1:])
+	if err != nil {
+		var parseErr *flagError
+		if errors.As(err, &parseErr) || errors.Is(err, flag.ErrHelp) {
+			return fmt.Errorf("%w: %w", ErrUsage, err)
+		}
+		return err
+	}
+	return table.Write(c.Out, c.Format)
+}
+
+type flagError struct{ err error }
+
+func (e *flagError) Error() string { return e.err.Error() }
+
+// sketchFlags are the flags most commands share
+type sketchFlags struct {
+	typ  *string
+	name *string
+}
+
+func addSketchFlags(fs *flag.FlagSet) sketchFlags {
+	return sketchFlags{
+		typ:  fs.String("type", "float", "element type of the sketch, float or int"),
+		name: fs.String("name", "", "name of the sketch, empty for the unnamed one"),
+	}
+}
+
+// parseFlags parses args, leaving the arguments after the flags in fs.Args
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &flagError{err}
+	}
+	return nil
+}
+
+// parse parses args, which must all be flags
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return &flagError{fmt.Errorf("unexpected argument %s", fs.Arg(0))}
+	}
+	return nil
+}
+
+// protoType maps the float/int of the command line to the type names of the
+// server.
+func protoType(typ string) (string, error) {
+	switch typ {
+	case "float", "float64":
+		return "float64", nil
+	case "int":
+		return "int", nil
+	}
+	return "", usageError("%s is not a valid type, use float or int", typ)
+}
+
+func numericValue(typ string, x string) (*pb.NumericValue, error) {
+	if typ == "int" {
+		i, err := strconv.ParseInt(x, 10, 64)
+		if err != nil {
+			return nil, usageError("%s is not an int", x)
+		}
+		return &pb.NumericValue{Value: &pb.NumericValue_IntVal{IntVal: i}, Type: typ}, nil
+	}
+	f, err := strconv.ParseFloat(x, 64)
+	if err != nil {
+		return nil, usageError("%s is not a float", x)
+	}
+	return &pb.NumericValue{Value: &pb.NumericValue_FloatVal{FloatVal: f}, Type: typ}, nil
+}
+
+// number unwraps v as an int64 or float64
+func number(v *pb.NumericValue) any {
+	if i, ok := v.GetValue().(*pb.NumericValue_IntVal); ok {
+		return i.IntVal
+	}
+	return v.GetFloatVal()
+}
+
+func quantileCmd(ctx context.Context, c pb.SketcherClient, fs *flag.FlagSet, args []string) (*output.Table, error) {
+	sf := addSketchFlags(fs)
+	phis := fs.String("phi", "0.5", "comma separated quantiles between 0 and 1")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	typ, err := protoType(*sf.typ)
+	if err != nil {
+		return nil, err
+	}
+	t := &output.Table{Columns: []string{"phi", "value"}}
+	for _, s := range strings.Split(*phis, ",") {
+		phi, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil || phi < 0 || phi > 1 {
+			return nil, usageError("%s is not a quantile between 0 and 1", s)
+		}
+		res, err := c.ReverseQueryKll(ctx, &pb.ReverseQuery{Phi: phi, Type: typ, Name: *sf.name})
+		if err != nil {
+			return nil, err
+		}
+		t.Add(phi, number(res))
+	}
+	return t, nil
+}
+
+func rankCmd(ctx context.Context, c pb.SketcherClient, fs *flag.FlagSet, args []string) (*output.Table, error) {
+	sf := addSketchFlags(fs)
+	x := fs.String("x", "", "value to rank")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	typ, err := protoType(*sf.typ)
+	if err != nil {
+		return nil, err
+	}
+	if *x == "" {
+		return nil, usageError("rank needs -x")
+	}
+	v, err := numericValue(typ, *x)
+	if err != nil {
+		return nil, err
+	}
+	v.Name = *sf.name
+	res, err := c.QueryKll(ctx, v)
+	if err != nil {
+		return nil, err
+	}
+	quantile := 0.0
+	if res.N > 0 {
+		quantile = float64(res.Phi) / float64(res.N)
+	}
+	t := &output.Table{Columns: []string{"x", "rank", "n", "quantile"}}
+	t.Add(number(v), res.Phi, res.N, quantile)
+	return t, nil
+}
+
+func topkCmd(ctx context.Context, c pb.SketcherClient, fs *flag.FlagSet, args []string) (*output.Table, error) {
+	sf := addSketchFlags(fs)
+	k := fs.Uint("k", 10, "number of values")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	typ, err := protoType(*sf.typ)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.TopKASketch(ctx, &pb.TopKRequest{K: uint32(*k), Type: typ, Name: *sf.name})
+	if err != nil {
+		return nil, err
+	}
+	t := &output.Table{Columns: []string{"rank", "value", "count"}}
+	for i, e := range res.Entries {
+		t.Add(int64(i+1), number(e.Key), e.EstFreq)
+	}
+	return t, nil
+}
+
+func freqCmd(ctx context.Context, c pb.SketcherClient, fs *flag.FlagSet, args []string) (*output.Table, error) {
+	sf := addSketchFlags(fs)
+	x := fs.String("x", "", "value to count")
+	sketch := fs.String("sketch", "asketch", "sketch to ask, asketch or count")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	typ, err := protoType(*sf.typ)
+	if err != nil {
+		return nil, err
+	}
+	if *x == "" {
+		return nil, usageError("freq needs -x")
+	}
+	v, err := numericValue(typ, *x)
+	if err != nil {
+		return nil, err
+	}
+	var res *pb.CountQueryReply
+	switch *sketch {
+	case "asketch":
+		v.Name = *sf.name
+		res, err = c.QueryASketch(ctx, v)
+	case "count":
+		v.Name = *sf.name
+		res, err = c.QueryCount(ctx, v)
+	default:
+		return nil, usageError("%s is not a frequency sketch, use asketch or count", *sketch)
+	}
+	if err != nil {
+		return nil, err
+	}
+	t := &output.Table{Columns: []string{"value", "count"}}
+	t.Add(number(v), res.Res)
+	return t, nil
+}
+
+func histCmd(ctx context.Context, c pb.SketcherClient, fs *flag.FlagSet, args []string) (*output.Table, error) {
+	sf := addSketchFlags(fs)
+	bf := addBinFlags(fs)
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	typ, err := protoType(*sf.typ)
+	if err != nil {
+		return nil, err
+	}
+	req, err := bf.request(typ, *sf.name)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.PlotKll(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	edges, err := binEdges(ctx, c, res, typ, *sf.name)
+	if err != nil {
+		return nil, err
+	}
+	mass := binMass(res)
+	t := &output.Table{Columns: []string{"lower", "upper", "count", "mass"}}
+	for i, count := range res.Pmf {
+		t.Add(edges[i], edges[i+1], count, mass[i])
+	}
+	return t, nil
+}
+
+// binFlags choose the bins of hist and plot
+type binFlags struct {
+	bins      *int
+	splits    *string
+	min       *float64
+	max       *float64
+	equiDepth *bool
+}
+
+func addBinFlags(fs *flag.FlagSet) binFlags {
+	return binFlags{
+		bins:      fs.Int("bins", 10, "number of bins"),
+		splits:    fs.String("splits", "", "comma separated increasing bin edges, overrides the other bin flags"),
+		min:       fs.Float64("min", 0, "lower end of equal width bins, by default the minimum of the sketch"),
+		max:       fs.Float64("max", 0, "upper end of equal width bins, by default the maximum of the sketch"),
+		equiDepth: fs.Bool("equidepth", false, "bins holding about the same number of items instead of equal width"),
+	}
+}
+
+func (bf binFlags) request(typ string, name string) (*pb.PlotRequest, error) {
+	req := &pb.PlotRequest{NumBins: int64(*bf.bins), Type: typ, Name: name, EquiDepth: *bf.equiDepth}
+	if *bf.splits != "" {
+		for _, part := range strings.Split(*bf.splits, ",") {
+			split, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return nil, usageError("%s is not a bin edge", part)
+			}
+			if len(req.Splits) > 0 && split <= req.Splits[len(req.Splits)-1] {
+				return nil, usageError("-splits must be increasing")
+			}
+			req.Splits = append(req.Splits, split)
+		}
+		if len(req.Splits) < 2 {
+			return nil, usageError("-splits needs at least 2 edges")
+		}
+		req.NumBins, req.EquiDepth = 0, false
+		return req, nil
+	}
+	if *bf.bins < 1 {
+		return nil, usageError("-bins must be positive")
+	}
+	if *bf.min != 0 || *bf.max != 0 {
+		if *bf.min >= *bf.max {
+			return nil, usageError("-min must be smaller than -max")
+		}
+		req.RangeMin, req.RangeMax = *bf.min, *bf.max
+	}
+	return req, nil
+}
+
+// binMass returns the masses of a PlotKll reply. Servers before masses were
+// added only send counts, their masses are relative to the binned items.
+func binMass(res *pb.PlotKllReply) []float64 {
+	if len(res.Mass) == len(res.Pmf) {
+		return res.Mass
+	}
+	total := 0.0
+	for _, count := range res.Pmf {
+		total += count
+	}
+	mass := make([]float64, len(res.Pmf))
+	for i, count := range res.Pmf {
+		if total > 0 {
+			mass[i] = count / total
+		}
+	}
+	return mass
+}
+
+func cardinalityCmd(ctx context.Context, c pb.SketcherClient, fs *flag.FlagSet, args []string) (*output.Table, error) {
+	sf := addSketchFlags(fs)
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	typ, err := protoType(*sf.typ)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.QueryHll(ctx, &pb.CardinalityRequest{Type: typ, Name: *sf.name})
+	if err != nil {
+		return nil, err
+	}
+	t := &output.Table{Columns: []string{"name", "estimate"}}
+	t.Add(*sf.name, res.Estimate)
+	return t, nil
+}
+
+func listCmd(ctx context.Context, c pb.SketcherClient, fs *flag.FlagSet, args []string) (*output.Table, error) {
+	typ := fs.String("type", "", "only fields of this type, float or int")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	req := &pb.ListFieldsRequest{}
+	if *typ != "" {
+		t, err := protoType(*typ)
+		if err != nil {
+			return nil, err
+		}
+		req.Type = t
+	}
+	res, err := c.ListFields(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	t := &output.Table{Columns: []string{"field", "type"}}
+	for _, f := range res.Fields {
+		t.Add(f.Field, f.Type)
+	}
+	return t, nil
+}
+
+// snapshotPhis are the quantiles a snapshot reports
+var snapshotPhis = []float64{0, 0.25, 0.5, 0.75, 0.9, 0.99, 1}
+
+func snapshotCmd(ctx context.Context, c pb.SketcherClient, fs *flag.FlagSet, args []string) (*output.Table, error) {
+	sf := addSketchFlags(fs)
+	k := fs.Uint("k", 5, "number of frequent values")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	typ, err := protoType(*sf.typ)
+	if err != nil {
+		return nil, err
+	}
+	t := &output.Table{Columns: []string{"sketch", "metric", "value"}}
+	if err := snapshotKll(ctx, c, t, typ, *sf.name); err != nil && status.Code(err) != codes.NotFound {
+		return nil, err
+	}
+	card, err := c.QueryHll(ctx, &pb.CardinalityRequest{Type: typ, Name: *sf.name})
+	if err != nil && status.Code(err) != codes.NotFound {
+		return nil, err
+	}
+	if err == nil {
+		t.Add("hll", "estimate", card.Estimate)
+	}
+	top, err := c.TopKASketch(ctx, &pb.TopKRequest{K: uint32(*k), Type: typ, Name: *sf.name})
+	if err != nil && status.Code(err) != codes.NotFound {
+		return nil, err
+	}
+	if err == nil {
+		for i, e := range top.Entries {
+			t.Add("asketch", fmt.Sprintf("top%d", i+1), fmt.Sprintf("%v=%d", number(e.Key), e.EstFreq))
+		}
+	}
+	if len(t.Rows) == 0 {
+		return nil, fmt.Errorf("there is no kll, hll or asketch sketch %q of type %s", *sf.name, typ)
+	}
+	return t, nil
+}
+
+// snapshotKll adds the quantiles and items of the KLL sketch, kinds that were
+// never merged under the name fail with NotFound and are left out of snapshots.
+func snapshotKll(ctx context.Context, c pb.SketcherClient, t *output.Table, typ string, name string) error {
+	var first *pb.NumericValue
+	for _, phi := range snapshotPhis {
+		res, err := c.ReverseQueryKll(ctx, &pb.ReverseQuery{Phi: phi, Type: typ, Name: name})
+		if err != nil {
+			return err
+		}
+		if first == nil {
+			first = res
+		}
+		t.Add("kll", "p"+strconv.FormatFloat(phi*100, 'g', -1, 64), number(res))
+	}
+	first.Type, first.Name = typ, name
+	rank, err := c.QueryKll(ctx, first)
+	if err != nil {
+		return err
+	}
+	t.Add("kll", "n", rank.N)
+	return nil
+}
+
+func describeCmd(ctx context.Context, c pb.SketcherClient, fs *flag.FlagSet, args []string) (*output.Table, error) {
+	name := fs.String("name", "", "name of the sketches, empty for the unnamed ones")
+	kind := fs.String("kind", "", "only sketches of this kind, kll, count, hll or asketch")
+	typ := fs.String("type", "", "only sketches of this type, float or int")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	req := &pb.DescribeRequest{Name: *name, Kind: *kind}
+	if *typ != "" {
+		t, err := protoType(*typ)
+		if err != nil {
+			return nil, err
+		}
+		req.Type = t
+	}
+	res, err := c.DescribeSketch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	t := &output.Table{Columns: []string{"kind", "type", "n", "bytes", "merges", "last_merge", "params", "levels"}}
+	for _, s := range res.Sketches {
+		last := ""
+		if s.LastMergeUnixNano != 0 {
+			last = time.Unix(0, s.LastMergeUnixNano).Format(time.RFC3339)
+		}
+		keys := make([]string, 0, len(s.Params))
+		for k := range s.Params {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		params := make([]string, len(keys))
+		for i, k := range keys {
+			params[i] = fmt.Sprintf("%s=%d", k, s.Params[k])
+		}
+		levels := make([]string, len(s.LevelSizes))
+		for i, size := range s.LevelSizes {
+			levels[i] = strconv.FormatInt(size, 10)
+		}
+		t.Add(s.Kind, s.Type, s.N, s.MemoryBytes, s.Merges, last, strings.Join(params, " "), strings.Join(levels, ","))
+	}
+	return t, nil
+}
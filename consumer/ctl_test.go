@@ -0,0 +1,210 @@
+package consumer_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bruhng/distributed-sketching/consumer"
+	"github.com/bruhng/distributed-sketching/mock_proto"
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/server/servertest"
+	"github.com/bruhng/distributed-sketching/sketches/convert"
+	"github.com/bruhng/distributed-sketching/sketches/kll"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+func run(t *testing.T, c pb.SketcherClient, format string, args ...string) (string, error) {
+	var out bytes.Buffer
+	ctl := consumer.Ctl{Client: c, Out: &out, Err: io.Discard, Format: format}
+	err := ctl.Run(context.Background(), args)
+	return out.String(), err
+}
+
+func TestQuantileFormats(t *testing.T) {
+	c := mock_proto.NewMockSketcherClient(gomock.NewController(t))
+	c.EXPECT().ReverseQueryKll(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, in *pb.ReverseQuery, _ ...grpc.CallOption) (*pb.NumericValue, error) {
+		if in.Name != "speeds" || in.Type != "float64" {
+			t.Errorf("unexpected request %v", in)
+		}
+		return &pb.NumericValue{Value: &pb.NumericValue_FloatVal{FloatVal: in.Phi * 10}}, nil
+	}).Times(6)
+
+	out, err := run(t, c, "json", "quantile", "-name", "speeds", "-phi", "0.5,0.9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, `"phi": 0.9`) || !strings.Contains(out, `"value": 9`) {
+		t.Errorf("json output:\n%s", out)
+	}
+	out, _ = run(t, c, "csv", "quantile", "-name", "speeds", "-phi", "0.5,0.9")
+	if out != "phi,value\n0.5,5\n0.9,9\n" {
+		t.Errorf("csv output:\n%s", out)
+	}
+	out, _ = run(t, c, "table", "quantile", "-name", "speeds", "-phi", "0.5,0.9")
+	if !strings.HasPrefix(out, "PHI  VALUE\n0.5  5\n") {
+		t.Errorf("table output:\n%s", out)
+	}
+}
+
+func TestTopK(t *testing.T) {
+	c := mock_proto.NewMockSketcherClient(gomock.NewController(t))
+	c.EXPECT().TopKASketch(gomock.Any(), &pb.TopKRequest{K: 2, Type: "int", Name: "events"}).Return(&pb.TopKReply{Entries: []*pb.TopKEntry{
+		{Key: &pb.NumericValue{Value: &pb.NumericValue_IntVal{IntVal: 7}}, EstFreq: 30},
+		{Key: &pb.NumericValue{Value: &pb.NumericValue_IntVal{IntVal: 3}}, EstFreq: 12},
+	}}, nil)
+	out, err := run(t, c, "csv", "topk", "-k", "2", "-type", "int", "-name", "events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "rank,value,count\n1,7,30\n2,3,12\n" {
+		t.Errorf("output:\n%s", out)
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	c := mock_proto.NewMockSketcherClient(gomock.NewController(t))
+	for _, args := range [][]string{
+		{},
+		{"nope"},
+		{"rank"},
+		{"rank", "-x", "abc"},
+		{"rank", "-x", "1", "-type", "string"},
+		{"quantile", "-phi", "2"},
+		{"freq", "-x", "1", "-sketch", "kll"},
+		{"list", "extra"},
+		{"topk", "-bogus"},
+	} {
+		if _, err := run(t, c, "table", args...); !errors.Is(err, consumer.ErrUsage) {
+			t.Errorf("%v: got %v, want a usage error", args, err)
+		}
+	}
+	if _, err := run(t, c, "yaml", "list"); !errors.Is(err, consumer.ErrUsage) {
+		t.Errorf("yaml output: got %v, want a usage error", err)
+	}
+}
+
+func TestServerError(t *testing.T) {
+	c := mock_proto.NewMockSketcherClient(gomock.NewController(t))
+	c.EXPECT().QueryHll(gomock.Any(), gomock.Any()).Return(nil, errors.New("unavailable"))
+	if _, err := run(t, c, "table", "cardinality"); err == nil || errors.Is(err, consumer.ErrUsage) {
+		t.Errorf("got %v, want the server error", err)
+	}
+}
+
+func TestHist(t *testing.T) {
+	c := mock_proto.NewMockSketcherClient(gomock.NewController(t))
+	c.EXPECT().PlotKll(gomock.Any(), &pb.PlotRequest{Type: "int", Name: "speeds", Splits: []float64{0, 10, 50}}).Return(&pb.PlotKllReply{
+		Pmf: []float64{10, 40}, Edges: []float64{0, 10, 50}, Mass: []float64{0.1, 0.4},
+	}, nil)
+	c.EXPECT().PlotKll(gomock.Any(), &pb.PlotRequest{NumBins: 2, Type: "float64", RangeMin: -1, RangeMax: 1}).Return(&pb.PlotKllReply{
+		Step: 1, Pmf: []float64{3, 1}, Edges: []float64{-1, 0, 1},
+	}, nil)
+	c.EXPECT().PlotKll(gomock.Any(), &pb.PlotRequest{NumBins: 3, Type: "float64", EquiDepth: true}).Return(&pb.PlotKllReply{Pmf: []float64{1}, Edges: []float64{0, 1}, Mass: []float64{1}}, nil)
+
+	out, err := run(t, c, "csv", "hist", "-type", "int", "-name", "speeds", "-splits", "0,10,50")
+	if err != nil || out != "lower,upper,count,mass\n0,10,10,0.1\n10,50,40,0.4\n" {
+		t.Errorf("splits: %v, output:\n%s", err, out)
+	}
+	out, err = run(t, c, "csv", "hist", "-bins", "2", "-min", "-1", "-max", "1")
+	if err != nil || out != "lower,upper,count,mass\n-1,0,3,0.75\n0,1,1,0.25\n" {
+		t.Errorf("range: %v, output:\n%s", err, out)
+	}
+	if _, err := run(t, c, "csv", "hist", "-bins", "3", "-equidepth"); err != nil {
+		t.Errorf("equidepth: %v", err)
+	}
+	for _, args := range [][]string{
+		{"hist", "-splits", "5,1"},
+		{"hist", "-splits", "5"},
+		{"hist", "-min", "3"},
+		{"hist", "-bins", "0"},
+	} {
+		if _, err := run(t, c, "table", args...); !errors.Is(err, consumer.ErrUsage) {
+			t.Errorf("%v: got %v, want a usage error", args, err)
+		}
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	c := mock_proto.NewMockSketcherClient(gomock.NewController(t))
+	state := &pb.ServerState{
+		Kll:     []*pb.KLLSketch{{N: 3, Type: "int", Name: "speeds"}},
+		Asketch: []*pb.ASketch{{Type: "int", Name: "speeds"}},
+	}
+	c.EXPECT().ExportSketch(gomock.Any(), &pb.ExportRequest{Name: "speeds", Type: "int"}).Return(state, nil)
+	file := filepath.Join(t.TempDir(), "speeds.sketch")
+	out, err := run(t, c, "csv", "save", "-name", "speeds", "-type", "int", "-out", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "kind,name,type\nkll,speeds,int\nasketch,speeds,int\n" {
+		t.Errorf("save output:\n%s", out)
+	}
+
+	c.EXPECT().ImportSketch(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, in *pb.ImportRequest, _ ...grpc.CallOption) (*pb.ImportReply, error) {
+		if !proto.Equal(in.Sketches, state) || in.Mode != "replace" || in.GetName() != "copy" {
+			t.Errorf("unexpected request %v", in)
+		}
+		return &pb.ImportReply{Imported: 2}, nil
+	})
+	out, err = run(t, c, "csv", "load", "-in", file, "-mode", "replace", "-as", "copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(out, ",replace,2\n") {
+		t.Errorf("load output:\n%s", out)
+	}
+
+	if _, err := run(t, c, "csv", "load", "-in", file, "-mode", "append"); !errors.Is(err, consumer.ErrUsage) {
+		t.Errorf("unknown mode gave %v, want a usage error", err)
+	}
+	if _, err := run(t, c, "csv", "save", "-name", "speeds"); !errors.Is(err, consumer.ErrUsage) {
+		t.Errorf("missing -out gave %v, want a usage error", err)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	c := mock_proto.NewMockSketcherClient(gomock.NewController(t))
+	c.EXPECT().DescribeSketch(gomock.Any(), &pb.DescribeRequest{Name: "speeds", Kind: "kll"}).Return(&pb.DescribeReply{Sketches: []*pb.SketchInfo{
+		{Kind: "kll", Type: "float64", Name: "speeds", Params: map[string]int64{"k": 200}, N: 1000, MemoryBytes: 4096, Merges: 3, LevelSizes: []int64{12, 130}},
+	}}, nil)
+	out, err := run(t, c, "csv", "describe", "-name", "speeds", "-kind", "kll")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "kind,type,n,bytes,merges,last_merge,params,levels\nkll,float64,1000,4096,3,,k=200,\"12,130\"\n" {
+		t.Errorf("output:\n%s", out)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+	c := servertest.NewServer(t).Client(t)
+	sketch := kll.NewKLLSketch[float64](200)
+	for i := range 100 {
+		sketch.Add(float64(i))
+	}
+	speeds := convert.ToProtoKLL(sketch)
+	speeds.Name = "speed"
+	if _, err := c.MergeKll(ctx, speeds); err != nil {
+		t.Fatal(err)
+	}
+
+	// speed has no hll or asketch, the snapshot leaves them out
+	out, err := run(t, c, "csv", "snapshot", "-name", "speed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "kll,p100,99\n") || !strings.Contains(out, "kll,n,100\n") || strings.Contains(out, "hll") || strings.Contains(out, "asketch") {
+		t.Errorf("output:\n%s", out)
+	}
+	if _, err := run(t, c, "csv", "snapshot", "-name", "nothing"); err == nil {
+		t.Error("a snapshot of a name without sketches did not fail")
+	}
+}
@@ -0,0 +1,268 @@
+package consumer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// LineEditor reads lines with emacs style editing, history and tab completion
+// when its input is a terminal, and plain lines otherwise.
+type LineEditor struct {
+	in  *bufio.Reader
+	out io.Writer
+	fd  int
+	// Terminal enables editing, it is set by NewLineEditor when the input is
+	// a terminal.
+	Terminal bool
+	Prompt   string
+	History  []string
+	// MaxHistory caps History, 0 keeps everything.
+	MaxHistory int
+	// Complete returns the candidates for the last word of line.
+	Complete func(line string) []string
+
+	mu      sync.Mutex
+	restore func()
+}
+
+func NewLineEditor(in io.Reader, out io.Writer) *LineEditor {
+	e := &LineEditor{in: bufio.NewReader(in), out: out, fd: -1}
+	if f, ok := in.(*os.File); ok && isTerminal(int(f.Fd())) {
+		e.fd = int(f.Fd())
+		e.Terminal = true
+	}
+	return e
+}
+
+// AddHistory appends line unless it is empty or repeats the previous line.
+func (e *LineEditor) AddHistory(line string) {
+	if strings.TrimSpace(line) == "" || (len(e.History) > 0 && e.History[len(e.History)-1] == line) {
+		return
+	}
+	e.History = append(e.History, line)
+	if e.MaxHistory > 0 && len(e.History) > e.MaxHistory {
+		e.History = e.History[len(e.History)-e.MaxHistory:]
+	}
+}
+
+// Close restores the terminal if a ReadLine is still waiting.
+func (e *LineEditor) Close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.restore != nil {
+		e.restore()
+		e.restore = nil
+	}
+}
+
+// ReadLine returns the next line without its newline. Ctrl-C discards the
+// line being edited and returns an empty one, Ctrl-D on an empty line returns
+// io.EOF.
+func (e *LineEditor) ReadLine() (string, error) {
+	if !e.Terminal {
+		line, err := e.in.ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+	if e.fd >= 0 {
+		restore, err := makeRaw(e.fd)
+		if err != nil {
+			return "", err
+		}
+		e.mu.Lock()
+		e.restore = restore
+		e.mu.Unlock()
+		defer e.Close()
+	}
+	ed := editState{e: e, histPos: len(e.History)}
+	ed.redraw()
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(e.out, "\n")
+			return string(ed.buf), nil
+		case 3: // Ctrl-C
+			fmt.Fprint(e.out, "^C\n")
+			return "", nil
+		case 4: // Ctrl-D
+			if len(ed.buf) == 0 {
+				fmt.Fprint(e.out, "\n")
+				return "", io.EOF
+			}
+			ed.delete()
+		case 127, 8:
+			if ed.pos > 0 {
+				ed.pos--
+				ed.delete()
+			}
+		case 1:
+			ed.pos = 0
+		case 5:
+			ed.pos = len(ed.buf)
+		case 2:
+			ed.move(-1)
+		case 6:
+			ed.move(1)
+		case 11:
+			ed.buf = ed.buf[:ed.pos]
+		case 21:
+			ed.buf = ed.buf[ed.pos:]
+			ed.pos = 0
+		case 23:
+			ed.killWord()
+		case 12:
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		case 16:
+			ed.history(-1)
+		case 14:
+			ed.history(1)
+		case '\t':
+			ed.complete()
+		case 27:
+			ed.escape()
+		default:
+			if unicode.IsPrint(r) {
+				ed.insert(r)
+			}
+		}
+		ed.redraw()
+	}
+}
+
+// editState is the line being edited
+type editState struct {
+	e       *LineEditor
+	buf     []rune
+	pos     int
+	histPos int
+	// saved is the new line while browsing the history
+	saved []rune
+}
+
+func (ed *editState) redraw() {
+	fmt.Fprintf(ed.e.out, "\r%s%s\x1b[K", ed.e.Prompt, string(ed.buf))
+	if back := len(ed.buf) - ed.pos; back > 0 {
+		fmt.Fprintf(ed.e.out, "\x1b[%dD", back)
+	}
+}
+
+func (ed *editState) insert(r ...rune) {
+	ed.buf = append(ed.buf[:ed.pos], append(r, ed.buf[ed.pos:]...)...)
+	ed.pos += len(r)
+}
+
+func (ed *editState) delete() {
+	if ed.pos < len(ed.buf) {
+		ed.buf = append(ed.buf[:ed.pos], ed.buf[ed.pos+1:]...)
+	}
+}
+
+func (ed *editState) move(by int) {
+	ed.pos = max(0, min(len(ed.buf), ed.pos+by))
+}
+
+func (ed *editState) killWord() {
+	start := ed.pos
+	for start > 0 && ed.buf[start-1] == ' ' {
+		start--
+	}
+	for start > 0 && ed.buf[start-1] != ' ' {
+		start--
+	}
+	ed.buf = append(ed.buf[:start], ed.buf[ed.pos:]...)
+	ed.pos = start
+}
+
+func (ed *editState) history(by int) {
+	next := ed.histPos + by
+	if next < 0 || next > len(ed.e.History) {
+		return
+	}
+	if ed.histPos == len(ed.e.History) {
+		ed.saved = ed.buf
+	}
+	ed.histPos = next
+	if next == len(ed.e.History) {
+		ed.buf = ed.saved
+	} else {
+		ed.buf = []rune(ed.e.History[next])
+	}
+	ed.pos = len(ed.buf)
+}
+
+// complete replaces the word before the cursor with the only candidate, or
+// with the prefix all candidates share and lists them.
+func (ed *editState) complete() {
+	if ed.e.Complete == nil {
+		return
+	}
+	before := string(ed.buf[:ed.pos])
+	candidates := ed.e.Complete(before)
+	if len(candidates) == 0 {
+		return
+	}
+	word := before[strings.LastIndex(before, " ")+1:]
+	common := candidates[0]
+	for _, c := range candidates[1:] {
+		for !strings.HasPrefix(c, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	if len(common) > len(word) {
+		ed.insert([]rune(common[len(word):])...)
+	}
+	if len(candidates) == 1 {
+		ed.insert(' ')
+		return
+	}
+	fmt.Fprintf(ed.e.out, "\n%s\n", strings.Join(candidates, "  "))
+}
+
+// escape handles the cursor and delete keys, which send escape sequences
+func (ed *editState) escape() {
+	in := ed.e.in
+	if b, err := in.ReadByte(); err != nil || (b != '[' && b != 'O') {
+		return
+	}
+	b, err := in.ReadByte()
+	if err != nil {
+		return
+	}
+	switch b {
+	case 'A':
+		ed.history(-1)
+	case 'B':
+		ed.history(1)
+	case 'C':
+		ed.move(1)
+	case 'D':
+		ed.move(-1)
+	case 'H':
+		ed.pos = 0
+	case 'F':
+		ed.pos = len(ed.buf)
+	case '1', '3', '4', '7', '8':
+		if next, err := in.ReadByte(); err != nil || next != '~' {
+			return
+		}
+		switch b {
+		case '3':
+			ed.delete()
+		case '1', '7':
+			ed.pos = 0
+		case '4', '8':
+			ed.pos = len(ed.buf)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package consumer
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+)
+
+// plotFormats are the formats gonum writes that make sense for a chart
+var plotFormats = map[string]bool{"png": true, "svg": true, "pdf": true, "eps": true, "jpg": true, "jpeg": true, "tif": true, "tiff": true}
+
+var barColor = color.RGBA{R: 135, G: 206, B: 250, A: 255}
+
+// binEdges returns the numBins+1 edges of a PlotKll reply. Servers before
+// edges were added only send the step, the first edge then is the minimum of
+// the sketch.
+func binEdges(ctx context.Context, c pb.SketcherClient, res *pb.PlotKllReply, typ string, name string) ([]float64, error) {
+	if len(res.Edges) == len(res.Pmf)+1 {
+		return res.Edges, nil
+	}
+	min, err := c.ReverseQueryKll(ctx, &pb.ReverseQuery{Phi: 0, Type: typ, Name: name})
+	if err != nil {
+		return nil, err
+	}
+	lower := min.GetFloatVal()
+	if i, ok := min.GetValue().(*pb.NumericValue_IntVal); ok {
+		lower = float64(i.IntVal)
+	}
+	edges := make([]float64, len(res.Pmf)+1)
+	for i := range edges {
+		edges[i] = lower + res.Step*float64(i)
+	}
+	return edges, nil
+}
+
+func plotCmd(s *Shell, ctx context.Context, fs *flag.FlagSet, args []string) error {
+	sf := addSketchFlags(fs)
+	bf := addBinFlags(fs)
+	density := fs.Bool("density", false, "plot mass per unit instead of counts, for bins of different widths")
+	mode := fs.String("mode", "hist", "hist for a histogram, cdf for the cumulative distribution")
+	points := fs.Int("points", 100, "quantiles queried for a cdf")
+	out := fs.String("out", "histogram.png", "file the chart is saved to")
+	format := fs.String("format", "", "png, svg or pdf, by default the extension of -out")
+	title := fs.String("title", "", "title of the chart, by default the sketch name")
+	xLabel := fs.String("xlabel", "value", "label of the x axis")
+	yLabel := fs.String("ylabel", "", "label of the y axis, by default count or fraction")
+	logY := fs.Bool("logy", false, "log scaled y axis")
+	width := fs.Float64("width", 12, "width in inches")
+	height := fs.Float64("height", 6, "height in inches")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	typ, err := protoType(*sf.typ)
+	if err != nil {
+		return err
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*out)), ".")
+	}
+	if !plotFormats[*format] {
+		return usageError("%q is not a chart format, use png, svg or pdf", *format)
+	}
+	if *width <= 0 || *height <= 0 {
+		return usageError("-width and -height must be positive")
+	}
+
+	p := plot.New()
+	p.Title.Text = *title
+	if p.Title.Text == "" {
+		p.Title.Text = "KLL sketch " + *sf.name
+	}
+	p.X.Label.Text = *xLabel
+	if *logY {
+		p.Y.Scale = plot.LogScale{}
+		p.Y.Tick.Marker = plot.LogTicks{}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+	switch *mode {
+	case "hist":
+		req, err := bf.request(typ, *sf.name)
+		if err != nil {
+			return err
+		}
+		p.Y.Label.Text = "count"
+		if *density {
+			p.Y.Label.Text = "density"
+		}
+		if err := addHistogram(ctx, s.Client, p, req, *density, *logY); err != nil {
+			return err
+		}
+	case "cdf":
+		if *points < 2 {
+			return usageError("-points must be at least 2")
+		}
+		p.Y.Label.Text = "fraction"
+		if err := addCDF(ctx, s.Client, p, *points, typ, *sf.name, *logY); err != nil {
+			return err
+		}
+	default:
+		return usageError("%s is not a plot mode, use hist or cdf", *mode)
+	}
+	if *yLabel != "" {
+		p.Y.Label.Text = *yLabel
+	}
+
+	w, err := p.WriterTo(vg.Length(*width)*vg.Inch, vg.Length(*height)*vg.Inch, *format)
+	if err != nil {
+		return fmt.Errorf("drawing the chart: %w", err)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if _, err := w.WriteTo(f); err != nil {
+		f.Close()
+		return fmt.Errorf("saving the chart: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(s.Out, "Chart saved as %s\n", *out)
+	return nil
+}
+
+func addHistogram(ctx context.Context, c pb.SketcherClient, p *plot.Plot, req *pb.PlotRequest, density bool, logY bool) error {
+	res, err := c.PlotKll(ctx, req)
+	if err != nil {
+		return err
+	}
+	edges, err := binEdges(ctx, c, res, req.Type, req.Name)
+	if err != nil {
+		return err
+	}
+	mass := binMass(res)
+	h := &plotter.Histogram{
+		Width:     res.Step,
+		FillColor: barColor,
+		LineStyle: plotter.DefaultLineStyle,
+		LogY:      logY,
+	}
+	for i, count := range res.Pmf {
+		if density {
+			if width := edges[i+1] - edges[i]; width > 0 {
+				count = mass[i] / width
+			}
+		}
+		h.Bins = append(h.Bins, plotter.HistogramBin{Min: edges[i], Max: edges[i+1], Weight: count})
+	}
+	p.Add(h)
+	return nil
+}
+
+func addCDF(ctx context.Context, c pb.SketcherClient, p *plot.Plot, points int, typ string, name string, logY bool) error {
+	var xys plotter.XYs
+	for i := range points {
+		phi := float64(i) / float64(points-1)
+		if logY && phi == 0 {
+			continue
+		}
+		v, err := c.ReverseQueryKll(ctx, &pb.ReverseQuery{Phi: phi, Type: typ, Name: name})
+		if err != nil {
+			return err
+		}
+		x := v.GetFloatVal()
+		if i, ok := v.GetValue().(*pb.NumericValue_IntVal); ok {
+			x = float64(i.IntVal)
+		}
+		xys = append(xys, plotter.XY{X: x, Y: phi})
+	}
+	line, err := plotter.NewLine(xys)
+	if err != nil {
+		return err
+	}
+	line.Color = barColor
+	line.Width = vg.Points(2)
+	p.Add(line)
+	return nil
+}
@@ -0,0 +1,506 @@
+package consumer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bruhng/distributed-sketching/output"
+	pb "github.com/bruhng/distributed-sketching/proto"
+)
+
+// errExit ends the shell
+var errExit = errors.New("exit")
+
+// Shell is the interactive consumer. It runs the commands of sketchctl and a
+// few of its own, see help.
+type Shell struct {
+	Ctl
+	Editor *LineEditor
+	// Timeout bounds every query, a watch applies it to each run.
+	Timeout time.Duration
+	// HistoryFile keeps the history between sessions when set.
+	HistoryFile string
+
+	namesMu   sync.Mutex
+	names     []string
+	namesTime time.Time
+}
+
+type builtin struct {
+	usage string
+	run   func(s *Shell, ctx context.Context, fs *flag.FlagSet, args []string) error
+}
+
+var builtins map[string]builtin
+
+func init() {
+	// set here as help refers to builtins
+	builtins = map[string]builtin{
+		"help":    {"[command]  list the commands or show the flags of one", helpCmd},
+		"watch":   {"[-n 2s] [-count 0] <command> [flags]  rerun a command until Ctrl-C", watchCmd},
+		"latency": {"[-n 10]  time TestLatency requests", latencyCmd},
+		"plot":    {"[-mode hist|cdf] [-bins 10] [-equidepth] [-splits a,b,c] [-type float|int] [-name name] [-out histogram.png] [-logy]  save a chart of a KLL sketch", plotCmd},
+		"format":  {"table|json|csv  output format of the commands", formatCmd},
+		"history": {"  list the previous commands", historyCmd},
+		"exit":    {"  leave the shell", exitCmd},
+	}
+}
+
+// renamed are the commands of the old REPL
+var renamed = map[string]string{
+	"QueryKll":        "rank",
+	"ReverseQueryKll": "quantile",
+	"PlotKll":         "plot",
+	"QueryASketch":    "freq",
+	"TopKASketch":     "topk",
+	"QueryHll":        "cardinality",
+	"ListFields":      "list",
+	"TestLatency":     "latency",
+}
+
+func NewShell(c pb.SketcherClient, in io.Reader, out io.Writer) *Shell {
+	s := &Shell{
+		Ctl:     Ctl{Client: c, Out: out, Err: out, Format: "table"},
+		Editor:  NewLineEditor(in, out),
+		Timeout: 5 * time.Second,
+	}
+	s.Editor.Prompt = "sketch> "
+	s.Editor.MaxHistory = 1000
+	s.Editor.Complete = s.Complete
+	return s
+}
+
+// Run reads commands until the input ends, exit is entered or ctx is done.
+// An interrupt cancels the running command, and ends the shell while it waits
+// for input unless the terminal is in editing mode, where Ctrl-C clears the
+// line instead.
+func (s *Shell) Run(ctx context.Context, interrupt <-chan os.Signal) {
+	s.loadHistory()
+	defer s.Editor.Close()
+
+	type result struct {
+		line string
+		err  error
+	}
+	next := make(chan struct{})
+	lines := make(chan result)
+	go func() {
+		for range next {
+			line, err := s.Editor.ReadLine()
+			lines <- result{line, err}
+		}
+	}()
+	defer close(next)
+
+	fmt.Fprintln(s.Out, "Write help for help")
+	for {
+		next <- struct{}{}
+		var r result
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(s.Out, "Shutting down")
+			return
+		case <-interrupt:
+			return
+		case r = <-lines:
+		}
+		if r.err != nil {
+			return
+		}
+		if strings.TrimSpace(r.line) == "" {
+			continue
+		}
+		s.Editor.AddHistory(r.line)
+		s.appendHistory(r.line)
+
+		cmdCtx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			select {
+			case <-interrupt:
+				cancel()
+			case <-done:
+			}
+		}()
+		err := s.Execute(cmdCtx, r.line)
+		close(done)
+		cancel()
+		if errors.Is(err, errExit) {
+			return
+		}
+		if err != nil {
+			fmt.Fprintln(s.Err, "error:", err)
+		}
+	}
+}
+
+// Execute runs one line. Words are split on white space, double quotes keep
+// a word together.
+func (s *Shell) Execute(ctx context.Context, line string) error {
+	words, err := splitWords(line)
+	if err != nil {
+		return usageError("%v", err)
+	}
+	if len(words) == 0 {
+		return nil
+	}
+	if b, ok := builtins[words[0]]; ok {
+		fs := s.flagSet(words[0], b.usage)
+		err := b.run(s, ctx, fs, words[1:])
+		var parseErr *flagError
+		if errors.As(err, &parseErr) {
+			return fmt.Errorf("%w: %v", ErrUsage, err)
+		}
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if name, ok := renamed[words[0]]; ok {
+		return usageError("%s is now %s, see help %s", words[0], name, name)
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+	err = s.Ctl.Run(ctx, words)
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	return err
+}
+
+func (s *Shell) flagSet(name string, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(s.Err)
+	fs.Usage = func() {
+		fmt.Fprintf(s.Err, "usage: %s %s\n", name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func splitWords(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord, quoted := false, false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inWord = true
+		case (r == ' ' || r == '\t') && !quoted:
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quoted {
+		return nil, errors.New("unterminated quote")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// commandNames are the commands of the shell, sorted
+func commandNames() []string {
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// commandFlags lists the flags of a command by asking it for help
+func commandFlags(name string) []string {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {}
+	if cmd, ok := commands[name]; ok {
+		cmd.run(context.Background(), nil, fs, []string{"-h"})
+	} else if b, ok := builtins[name]; ok && name != "help" {
+		b.run(nil, context.Background(), fs, []string{"-h"})
+	}
+	var flags []string
+	fs.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, "-"+f.Name)
+	})
+	return flags
+}
+
+// sketchNames are the names of the sketches the server keeps, fetched at most
+// every few seconds
+func (s *Shell) sketchNames() []string {
+	s.namesMu.Lock()
+	defer s.namesMu.Unlock()
+	if time.Since(s.namesTime) < 5*time.Second {
+		return s.names
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	res, err := s.Client.DescribeSketch(ctx, &pb.DescribeRequest{All: true})
+	if err != nil {
+		return s.names
+	}
+	seen := map[string]bool{}
+	var names []string
+	for _, info := range res.Sketches {
+		if info.Name != "" && !seen[info.Name] {
+			seen[info.Name] = true
+			names = append(names, info.Name)
+		}
+	}
+	sort.Strings(names)
+	s.names = names
+	s.namesTime = time.Now()
+	return s.names
+}
+
+// Complete returns the candidates for the last word of line: commands first,
+// then the flags of the command and the values of -type, -sketch and -name.
+func (s *Shell) Complete(line string) []string {
+	words := strings.Fields(line)
+	if line == "" || strings.HasSuffix(line, " ") {
+		words = append(words, "")
+	}
+	// watch and help take a command
+	for len(words) > 1 && words[0] == "watch" {
+		i := 1
+		for i < len(words)-1 && strings.HasPrefix(words[i], "-") {
+			i += 2
+		}
+		if i > len(words)-1 {
+			i = len(words) - 1
+		}
+		words = words[i:]
+	}
+	word := words[len(words)-1]
+	var candidates []string
+	switch {
+	case len(words) == 1 || (len(words) == 2 && words[0] == "help"):
+		candidates = commandNames()
+	case words[len(words)-2] == "-type":
+		candidates = []string{"float", "int"}
+	case words[len(words)-2] == "-sketch":
+		candidates = []string{"asketch", "count"}
+	case words[len(words)-2] == "-name":
+		candidates = s.sketchNames()
+	case words[0] == "format":
+		candidates = []string{"csv", "json", "table"}
+	case strings.HasPrefix(word, "-"):
+		candidates = commandFlags(words[0])
+	}
+	var out []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, word) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (s *Shell) loadHistory() {
+	if s.HistoryFile == "" {
+		return
+	}
+	f, err := os.Open(s.HistoryFile)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		s.Editor.AddHistory(scanner.Text())
+	}
+}
+
+func (s *Shell) appendHistory(line string) {
+	if s.HistoryFile == "" {
+		return
+	}
+	f, err := os.OpenFile(s.HistoryFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	fmt.Fprintln(f, line)
+}
+
+// DefaultHistoryFile is ~/.sketch_history
+func DefaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".sketch_history")
+}
+
+func helpCmd(s *Shell, ctx context.Context, fs *flag.FlagSet, args []string) error {
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		return s.Execute(ctx, fs.Arg(0)+" -h")
+	default:
+		return usageError("help takes one command")
+	}
+	fmt.Fprintln(s.Out, "Commands, add -h or use help <command> for their flags:")
+	Usage(s.Out)
+	var names []string
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(s.Out, "  %s %s\n", name, builtins[name].usage)
+	}
+	fmt.Fprintln(s.Out, "\nTypes are float or int, -name selects a named sketch (see -columns) and Tab completes commands, flags and names.")
+	return nil
+}
+
+func watchCmd(s *Shell, ctx context.Context, fs *flag.FlagSet, args []string) error {
+	interval := fs.Duration("n", 2*time.Second, "time between runs")
+	count := fs.Int("count", 0, "stop after this many runs, 0 runs until Ctrl-C")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageError("watch needs a command")
+	}
+	if fs.Arg(0) == "watch" {
+		return usageError("watch can not watch itself")
+	}
+	if *interval <= 0 {
+		return usageError("-n must be positive")
+	}
+	line := strings.Join(quoteWords(fs.Args()), " ")
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for run := 1; ; run++ {
+		if s.Editor.Terminal {
+			fmt.Fprint(s.Out, "\x1b[H\x1b[2J")
+		}
+		fmt.Fprintf(s.Out, "Every %v: %s    %s\n\n", *interval, line, time.Now().Format(time.TimeOnly))
+		if err := s.Execute(ctx, line); err != nil {
+			if errors.Is(err, ErrUsage) || ctx.Err() != nil {
+				return err
+			}
+			fmt.Fprintln(s.Err, "error:", err)
+		}
+		if *count > 0 && run >= *count {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// quoteWords undoes splitWords
+func quoteWords(words []string) []string {
+	out := make([]string, len(words))
+	for i, w := range words {
+		if w == "" || strings.ContainsAny(w, " \t") {
+			w = `"` + w + `"`
+		}
+		out[i] = w
+	}
+	return out
+}
+
+func latencyCmd(s *Shell, ctx context.Context, fs *flag.FlagSet, args []string) error {
+	n := fs.Int("n", 10, "number of requests")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *n < 1 {
+		return usageError("-n must be positive")
+	}
+	var total, fastest, slowest time.Duration
+	failed := 0
+	for range *n {
+		reqCtx, cancel := context.WithTimeout(ctx, s.Timeout)
+		start := time.Now()
+		_, err := s.Client.TestLatency(reqCtx, &pb.EmptyMessage{})
+		took := time.Since(start)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			failed++
+			continue
+		}
+		total += took
+		if fastest == 0 || took < fastest {
+			fastest = took
+		}
+		slowest = max(slowest, took)
+	}
+	if failed == *n {
+		return fmt.Errorf("all %d requests failed", failed)
+	}
+	t := &output.Table{Columns: []string{"requests", "failed", "mean", "min", "max"}}
+	t.Add(int64(*n), int64(failed), (total / time.Duration(*n-failed)).String(), fastest.String(), slowest.String())
+	return t.Write(s.Out, s.Format)
+}
+
+func formatCmd(s *Shell, _ context.Context, fs *flag.FlagSet, args []string) error {
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	switch fs.NArg() {
+	case 0:
+		fmt.Fprintln(s.Out, s.Format)
+		return nil
+	case 1:
+	default:
+		return usageError("format takes one format")
+	}
+	switch fs.Arg(0) {
+	case "table", "json", "csv":
+		s.Format = fs.Arg(0)
+		return nil
+	}
+	return usageError("%s is not an output format, use table, json or csv", fs.Arg(0))
+}
+
+func historyCmd(s *Shell, _ context.Context, fs *flag.FlagSet, args []string) error {
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	for i, line := range s.Editor.History {
+		fmt.Fprintf(s.Out, "%5d  %s\n", i+1, line)
+	}
+	return nil
+}
+
+func exitCmd(_ *Shell, _ context.Context, fs *flag.FlagSet, args []string) error {
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	return errExit
+}
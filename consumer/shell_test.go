@@ -0,0 +1,184 @@
+package consumer_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bruhng/distributed-sketching/consumer"
+	"github.com/bruhng/distributed-sketching/mock_proto"
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+)
+
+func fields(names ...string) *pb.ListFieldsReply {
+	out := &pb.ListFieldsReply{}
+	for _, name := range names {
+		out.Fields = append(out.Fields, &pb.FieldInfo{Field: name, Type: "int"})
+	}
+	return out
+}
+
+func TestLineEditor(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		keys    string
+		history []string
+		want    string
+	}{
+		{"backspace", "abc\x7fd\r", nil, "abd"},
+		{"home", "bc\x01a\r", nil, "abc"},
+		{"left and delete", "abc\x1b[D\x1b[D\x1b[3~\r", nil, "ac"},
+		{"kill word", "rank -x 12\x17\x17\r", nil, "rank "},
+		{"history", "\x1b[A\x1b[A\x1b[B\r", []string{"one", "two"}, "two"},
+		{"history keeps new line", "new\x10\x0e\r", []string{"one"}, "new"},
+		{"complete", "qu\t-phi 1\r", nil, "quantile -phi 1"},
+		{"complete common prefix", "c\t\r", nil, "c"},
+		{"interrupt", "abc\x03", nil, ""},
+	} {
+		e := consumer.NewLineEditor(strings.NewReader(tc.keys), io.Discard)
+		e.Terminal = true
+		e.History = tc.history
+		e.Complete = func(line string) []string {
+			var out []string
+			for _, c := range []string{"quantile", "cardinality", "count"} {
+				if strings.HasPrefix(c, line) {
+					out = append(out, c)
+				}
+			}
+			return out
+		}
+		got, err := e.ReadLine()
+		if err != nil || got != tc.want {
+			t.Errorf("%s: got %q, %v, want %q", tc.name, got, err, tc.want)
+		}
+	}
+
+	e := consumer.NewLineEditor(strings.NewReader("\x04"), io.Discard)
+	e.Terminal = true
+	if _, err := e.ReadLine(); err != io.EOF {
+		t.Errorf("Ctrl-D: got %v, want EOF", err)
+	}
+}
+
+func TestComplete(t *testing.T) {
+	c := mock_proto.NewMockSketcherClient(gomock.NewController(t))
+	c.EXPECT().DescribeSketch(gomock.Any(), &pb.DescribeRequest{All: true}).Return(&pb.DescribeReply{Sketches: []*pb.SketchInfo{
+		{Kind: "kll", Type: "float64"},
+		{Kind: "kll", Type: "float64", Name: "speeds"},
+		{Kind: "hll", Type: "int", Name: "users"},
+		{Kind: "asketch", Type: "int", Name: "events"},
+		{Kind: "asketch", Type: "float64", Name: "events"},
+	}}, nil)
+	s := consumer.NewShell(c, strings.NewReader(""), io.Discard)
+	for line, want := range map[string][]string{
+		"qu":                  {"quantile"},
+		"h":                   {"help", "hist", "history"},
+		"rank -ty":            {"-type"},
+		"rank -type ":         {"float", "int"},
+		"freq -sketch c":      {"count"},
+		"topk -name ":         {"events", "speeds", "users"},
+		"topk -name s":        {"speeds"},
+		"watch -n 1s ra":      {"rank"},
+		"help wa":             {"watch"},
+		"format j":            {"json"},
+		"quantile -phi 0.5 x": nil,
+	} {
+		if got := s.Complete(line); !reflect.DeepEqual(got, want) {
+			t.Errorf("Complete(%q) = %v, want %v", line, got, want)
+		}
+	}
+}
+
+func TestShellExecute(t *testing.T) {
+	c := mock_proto.NewMockSketcherClient(gomock.NewController(t))
+	c.EXPECT().ListFields(gomock.Any(), gomock.Any()).Return(fields("events"), nil).Times(3)
+	var out bytes.Buffer
+	s := consumer.NewShell(c, strings.NewReader(""), &out)
+	ctx := context.Background()
+
+	if err := s.Execute(ctx, "format csv"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Execute(ctx, "list"); err != nil || out.String() != "field,type\nevents,int\n" {
+		t.Fatalf("list: %v, output:\n%s", err, out.String())
+	}
+	out.Reset()
+	if err := s.Execute(ctx, "watch -n 1ms -count 2 list -type int"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(out.String(), "events,int") != 2 {
+		t.Errorf("watch output:\n%s", out.String())
+	}
+
+	for _, line := range []string{"QueryKll 3", "format yaml", "watch", "nope", `rank -name "unterminated`, "latency -n 0"} {
+		if err := s.Execute(ctx, line); !errors.Is(err, consumer.ErrUsage) {
+			t.Errorf("%s: got %v, want a usage error", line, err)
+		}
+	}
+
+	out.Reset()
+	if err := s.Execute(ctx, "help"); err != nil || !strings.Contains(out.String(), "watch") || !strings.Contains(out.String(), "topk") {
+		t.Errorf("help: %v, output:\n%s", err, out.String())
+	}
+	out.Reset()
+	if err := s.Execute(ctx, "help quantile"); err != nil || !strings.Contains(out.String(), "-phi") {
+		t.Errorf("help quantile: %v, output:\n%s", err, out.String())
+	}
+}
+
+func TestShellRun(t *testing.T) {
+	c := mock_proto.NewMockSketcherClient(gomock.NewController(t))
+	c.EXPECT().QueryHll(gomock.Any(), &pb.CardinalityRequest{Type: "int", Name: "vehicle id"}).Return(&pb.CardinalityReply{Estimate: 42}, nil)
+	var out bytes.Buffer
+	s := consumer.NewShell(c, strings.NewReader("format json\n\ncardinality -type int -name \"vehicle id\"\nexit\nlist\n"), &out)
+	s.HistoryFile = t.TempDir() + "/history"
+	s.Run(context.Background(), make(chan os.Signal))
+
+	if !strings.Contains(out.String(), `"estimate": 42`) {
+		t.Errorf("output:\n%s", out.String())
+	}
+	history, _ := os.ReadFile(s.HistoryFile)
+	if strings.Count(string(history), "\n") != 3 {
+		t.Errorf("history file:\n%s", history)
+	}
+}
+
+func TestPlot(t *testing.T) {
+	c := mock_proto.NewMockSketcherClient(gomock.NewController(t))
+	c.EXPECT().PlotKll(gomock.Any(), &pb.PlotRequest{NumBins: 3, Type: "float64", Name: "speeds"}).Return(&pb.PlotKllReply{
+		Step: 2, Pmf: []float64{1, 0, 5}, Edges: []float64{10, 12, 14, 16},
+	}, nil).Times(2)
+	c.EXPECT().ReverseQueryKll(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, in *pb.ReverseQuery, _ ...grpc.CallOption) (*pb.NumericValue, error) {
+		return &pb.NumericValue{Value: &pb.NumericValue_IntVal{IntVal: int64(in.Phi * 100)}}, nil
+	}).Times(5)
+	s := consumer.NewShell(c, strings.NewReader(""), io.Discard)
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	for line, magic := range map[string]string{
+		"plot -name speeds -bins 3 -out " + dir + "/h.svg":                        "<?xml",
+		"plot -name speeds -bins 3 -logy -format pdf -out " + dir + "/h":          "%PDF",
+		"plot -mode cdf -points 5 -type int -xlabel speed -out " + dir + "/c.png": "\x89PNG",
+	} {
+		if err := s.Execute(ctx, line); err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+		out := strings.Fields(line)[len(strings.Fields(line))-1]
+		data, err := os.ReadFile(out)
+		if err != nil || !strings.HasPrefix(string(data), magic) {
+			t.Errorf("%s: wrote %.10q, %v", line, data, err)
+		}
+	}
+	for _, line := range []string{"plot -out h.txt", "plot -mode pie", "plot -bins 0", "plot -mode cdf -points 1"} {
+		if err := s.Execute(ctx, line); !errors.Is(err, consumer.ErrUsage) {
+			t.Errorf("%s: got %v, want a usage error", line, err)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package consumer
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bruhng/distributed-sketching/output"
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"google.golang.org/protobuf/proto"
+)
+
+// saveCmd writes sketches of the server to a file in the format of the
+// server's -state file, so the file can also seed a new server.
+func saveCmd(ctx context.Context, c pb.SketcherClient, fs *flag.FlagSet, args []string) (*output.Table, error) {
+	name := fs.String("name", "", "name of the sketches, empty for the unnamed ones")
+	kind := fs.String("kind", "", "only sketches of this kind, kll, count, hll or asketch")
+	typ := fs.String("type", "", "only sketches of this type, float or int")
+	all := fs.Bool("all", false, "save the sketches of every name")
+	out := fs.String("out", "", "file to write")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	if *out == "" {
+		return nil, usageError("-out is required")
+	}
+	req := &pb.ExportRequest{Name: *name, Kind: *kind, All: *all}
+	if *typ != "" {
+		t, err := protoType(*typ)
+		if err != nil {
+			return nil, err
+		}
+		req.Type = t
+	}
+	state, err := c.ExportSketch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	data, err := proto.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		return nil, err
+	}
+	return stateTable(state), nil
+}
+
+// loadCmd sends the sketches of a file written by save, or a server's -state
+// file, to the server.
+func loadCmd(ctx context.Context, c pb.SketcherClient, fs *flag.FlagSet, args []string) (*output.Table, error) {
+	in := fs.String("in", "", "file to read")
+	mode := fs.String("mode", "merge", "merge into the server's sketches or replace them")
+	as := fs.String("as", "", "load every sketch under this name instead of its own")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	if *in == "" {
+		return nil, usageError("-in is required")
+	}
+	if *mode != "merge" && *mode != "replace" {
+		return nil, usageError("%s is not a mode, use merge or replace", *mode)
+	}
+	data, err := os.ReadFile(*in)
+	if err != nil {
+		return nil, err
+	}
+	state := &pb.ServerState{}
+	if err := proto.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("could not read sketches %s: %w", *in, err)
+	}
+	req := &pb.ImportRequest{Sketches: state, Mode: *mode}
+	isSet(fs, "as", func() { req.Name = as })
+	res, err := c.ImportSketch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	t := &output.Table{Columns: []string{"file", "mode", "imported"}}
+	t.Add(*in, *mode, res.Imported)
+	return t, nil
+}
+
+// isSet calls f if the flag name was given on the command line
+func isSet(fs *flag.FlagSet, name string, f func()) {
+	fs.Visit(func(fl *flag.Flag) {
+		if fl.Name == name {
+			f()
+		}
+	})
+}
+
+// stateTable lists the sketches of state
+func stateTable(state *pb.ServerState) *output.Table {
+	t := &output.Table{Columns: []string{"kind", "name", "type"}}
+	for _, s := range state.Kll {
+		t.Add("kll", s.Name, s.Type)
+	}
+	for _, s := range state.Count {
+		t.Add("count", s.Name, s.Type)
+	}
+	for _, s := range state.Hll {
+		t.Add("hll", s.Name, s.Type)
+	}
+	for _, s := range state.Asketch {
+		t.Add("asketch", s.Name, s.Type)
+	}
+	return t
+}
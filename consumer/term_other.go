@@ -0,0 +1,14 @@
+//go:build !linux
+
+package consumer
+
+import "errors"
+
+// Line editing is only supported on linux, other systems read plain lines.
+func isTerminal(int) bool {
+	return false
+}
+
+func makeRaw(int) (func(), error) {
+	return nil, errors.New("raw terminal mode is not supported on this system")
+}
@@ -2,10 +2,16 @@ package main
 
 import (
 	"flag"
+	"log"
+	"strconv"
+	"strings"
 
 	"github.com/bruhng/distributed-sketching/client"
 	"github.com/bruhng/distributed-sketching/consumer"
+	"github.com/bruhng/distributed-sketching/metrics"
+	"github.com/bruhng/distributed-sketching/security"
 	"github.com/bruhng/distributed-sketching/server"
+	"github.com/bruhng/distributed-sketching/stream"
 )
 
 func main() {
@@ -14,23 +20,99 @@ func main() {
 	port := flag.String("port", "8080", "Choose what port to use")
 	address := flag.String("a", "127.0.0.1", "Choose what ip to connect to")
 	sketchType := flag.String("sketch", "kll", "Choose what sketch to use")
-	dataSetPath := flag.String("d", "./data/PVS 1/dataset_gps.csv", "Choose what data set path to use as data stream")
+	dataSetPath := flag.String("d", "./data/PVS 1/dataset_gps.csv", "Choose what data set to use as data stream: a csv path, stdin, jsonl:<path>, parquet:<path>, tcp:<addr>, udp:<addr> or gen:<dist>?<params>")
 	dataSetName := flag.String("name", "speed_meters_per_second", "Choose what part of the data set to use as data stream")
 	dataSetType := flag.String("type", "float", "Choose what type the data set is")
 	mergeRate := flag.Int("merge", 1000, "merge rate for clients")
+	mergeInterval := flag.Duration("mergeInterval", 0, "also merge on a ticker of this period, e.g. 30s")
+	mergeBytes := flag.Int("mergeBytes", 0, "also merge once the sketch is at least this many bytes (kll and streamClient only)")
 	streamRate := flag.Int("stream", 10, "stream rate for clients")
+	rate := flag.Float64("rate", 0, "items per second streamed by clients (overrides -stream)")
+	arrival := flag.String("arrival", "uniform", "arrival pattern for clients: uniform, bursty or poisson")
+	burst := flag.Int("burst", stream.DefaultBurstSize, "items per burst with -arrival bursty")
+	statePath := flag.String("state", "", "file the server saves its sketches to on shutdown and loads on start")
+	metricsAddr := flag.String("metrics", "", "serve Prometheus metrics on this address under /metrics, e.g. :9100")
+	httpAddr := flag.String("http", "", "serve the HTTP/JSON query API of the server on this address, e.g. :8081")
+	exportPhis := flag.String("exportPhis", "0.5,0.9,0.99", "quantiles of KLL sketches the server publishes under /sketches with -metrics")
+	exportTopK := flag.Int("exportTopK", 10, "heavy hitters per ASketch and Count sketch published under /sketches")
+	exportCountItems := flag.String("exportCountItems", "", "comma separated candidate items ranked for Count sketches under /sketches")
+	columns := flag.String("columns", "", "sketch several columns at once, comma separated column=sketch:type[@name] (overrides -sketch, -name and -type)")
 
+	security.RegisterFlags(flag.CommandLine)
 	flag.Parse()
-	if *isClient {
+	if err := security.Load(); err != nil {
+		log.Fatal(err)
+	}
+
+	mode, err := stream.ParseArrival(*arrival)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pacing := []stream.Option{stream.WithArrival(mode), stream.WithBurstSize(*burst)}
+	if *rate > 0 {
+		*streamRate = int(1e9 / *rate)
+	}
+	srv := server.NewServer(server.WithTokens(security.ServerTokens), server.WithTLS(security.TLS), server.WithMetrics(metrics.Default))
+	if *metricsAddr != "" {
+		serve := metrics.Serve // client and consumer metrics do not describe sketches
+		if !*isClient && !*isConsumer {
+			phis, err := parseFloats(*exportPhis)
+			if err != nil {
+				log.Fatal(err)
+			}
+			items, err := parseFloats(*exportCountItems)
+			if err != nil {
+				log.Fatal(err)
+			}
+			srv.RegisterMetrics(metrics.Default)
+			srv.ExportSketches("/sketches", server.ExportConfig{Phis: phis, TopK: *exportTopK, CountItems: items})
+			serve = srv.ServeMetrics
+		}
+		addr, err := serve(*metricsAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Metrics at http://%s/metrics", addr)
+	}
+	if *httpAddr != "" && !*isClient && !*isConsumer {
+		addr, err := srv.ServeGateway(*httpAddr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("HTTP API at http://%s/v1/", addr)
+	}
+	policy := client.MergePolicy{Every: *mergeRate, Interval: *mergeInterval, MaxBytes: *mergeBytes}
+	if *isClient && *columns != "" {
+		mappings, err := client.ParseColumnMappings(*columns)
+		if err != nil {
+			log.Fatal(err)
+		}
+		client.InitColumns(*port, *address, *dataSetPath, mappings, -1, *streamRate, policy, pacing...)
+	} else if *isClient {
 		switch *dataSetType {
 		case "float":
-			client.Init[float64](*port, *address, *sketchType, *dataSetPath, *dataSetName, -1, *streamRate, *mergeRate)
+			client.Init[float64](*port, *address, *sketchType, *dataSetPath, *dataSetName, -1, *streamRate, policy, pacing...)
 		case "int":
-			client.Init[int](*port, *address, *sketchType, *dataSetPath, *dataSetName, -1, *streamRate, *mergeRate)
+			client.Init[int](*port, *address, *sketchType, *dataSetPath, *dataSetName, -1, *streamRate, policy, pacing...)
 		}
 	} else if *isConsumer {
 		consumer.Init(*port, *address)
 	} else {
-		server.Init(*port)
+		srv.Init(*port, *statePath)
+	}
+}
+
+func parseFloats(list string) ([]float64, error) {
+	var out []float64
+	for _, part := range strings.Split(list, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, f)
 	}
+	return out, nil
 }
@@ -3,6 +3,8 @@ package main_test
 import (
 	"testing"
 	"time"
+
+	"github.com/bruhng/distributed-sketching/stream"
 )
 
 func preciseSleep(duration time.Duration) {
@@ -28,3 +30,12 @@ func BenchmarkTimeSleep(b *testing.B) {
 	}
 
 }
+func BenchmarkLimiter(b *testing.B) {
+	l := stream.NewLimiter(1e6, stream.Uniform)
+	for b.Loop() {
+		for range 1000 {
+			l.Wait()
+		}
+	}
+
+}
@@ -0,0 +1,331 @@
+// Package metrics exposes counters, gauges and histograms in the Prometheus
+// text format over HTTP, without depending on a Prometheus client library.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// Registry holds the metrics written by its handler.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+// Default is the registry the server and clients record to.
+var Default = &Registry{}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	r.collectors = append(r.collectors, c)
+	r.mu.Unlock()
+}
+
+// Handler writes every registered metric in the Prometheus text format.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.mu.Lock()
+		collectors := append([]collector(nil), r.collectors...)
+		r.mu.Unlock()
+		bw := bufio.NewWriter(w)
+		for _, c := range collectors {
+			c.write(bw)
+		}
+		bw.Flush()
+	})
+}
+
+var mux = http.NewServeMux()
+
+func init() {
+	mux.Handle("/metrics", Default.Handler())
+}
+
+// Handle adds another endpoint to the server started by Serve.
+func Handle(path string, r *Registry) {
+	mux.Handle(path, r.Handler())
+}
+
+// Handler serves the default registry under /metrics and whatever was added
+// with Handle.
+func Handler() http.Handler {
+	return mux
+}
+
+// Serve serves Handler on addr until the process ends and returns the address
+// it listens on. It neither authenticates nor encrypts, so addr has to be
+// private.
+func Serve(addr string) (net.Addr, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	go http.Serve(lis, mux)
+	return lis.Addr(), nil
+}
+
+// family is the shared part of a labelled metric
+type family struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+}
+
+func (f *family) header(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.typ)
+}
+
+func (f *family) key(values []string) string {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metric %s takes %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// series formats the name and labels of one sample, extra is appended as
+// already formatted labels, e.g. le="0.5".
+func (f *family) series(name string, key string, extra string) string {
+	var pairs []string
+	if len(f.labels) > 0 {
+		for i, v := range strings.Split(key, "\xff") {
+			pairs = append(pairs, f.labels[i]+"="+strconv.Quote(v))
+		}
+	}
+	if extra != "" {
+		pairs = append(pairs, extra)
+	}
+	if len(pairs) == 0 {
+		return name
+	}
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Value is a counter or gauge, one value per combination of label values.
+type Value struct {
+	family
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+func newValue(r *Registry, typ string, name string, help string, labels []string) *Value {
+	v := &Value{family: family{name: name, help: help, typ: typ, labels: labels}, values: map[string]float64{}}
+	r.register(v)
+	return v
+}
+
+// NewCounter registers a counter, which should only ever be increased.
+func (r *Registry) NewCounter(name string, help string, labels ...string) *Value {
+	return newValue(r, "counter", name, help, labels)
+}
+
+// NewGauge registers a value that can go up and down.
+func (r *Registry) NewGauge(name string, help string, labels ...string) *Value {
+	return newValue(r, "gauge", name, help, labels)
+}
+
+func (v *Value) Add(delta float64, labelValues ...string) {
+	key := v.key(labelValues)
+	v.mu.Lock()
+	v.values[key] += delta
+	v.mu.Unlock()
+}
+
+func (v *Value) Inc(labelValues ...string) {
+	v.Add(1, labelValues...)
+}
+
+func (v *Value) Set(value float64, labelValues ...string) {
+	key := v.key(labelValues)
+	v.mu.Lock()
+	v.values[key] = value
+	v.mu.Unlock()
+}
+
+// Get returns the current value, mostly for tests.
+func (v *Value) Get(labelValues ...string) float64 {
+	key := v.key(labelValues)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.values[key]
+}
+
+func (v *Value) write(w *bufio.Writer) {
+	v.header(w)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, key := range sortedKeys(v.values) {
+		fmt.Fprintf(w, "%s %s\n", v.series(v.name, key, ""), formatFloat(v.values[key]))
+	}
+}
+
+// GaugeFunc is a gauge whose values are read when scraped.
+type GaugeFunc struct {
+	family
+	collect func(emit func(value float64, labelValues ...string))
+}
+
+// NewGaugeFunc registers a gauge that calls collect on every scrape, collect
+// emits one value per combination of label values.
+func (r *Registry) NewGaugeFunc(name string, help string, labels []string, collect func(emit func(value float64, labelValues ...string))) *GaugeFunc {
+	g := &GaugeFunc{family: family{name: name, help: help, typ: "gauge", labels: labels}, collect: collect}
+	r.register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(w *bufio.Writer) {
+	values := map[string]float64{}
+	g.collect(func(value float64, labelValues ...string) {
+		values[g.key(labelValues)] = value
+	})
+	g.header(w)
+	for _, key := range sortedKeys(values) {
+		fmt.Fprintf(w, "%s %s\n", g.series(g.name, key, ""), formatFloat(values[key]))
+	}
+}
+
+// Histogram counts observations into cumulative buckets.
+type Histogram struct {
+	family
+	buckets  []float64
+	mu       sync.Mutex
+	byLabels map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// DefaultBuckets suit request latencies in seconds.
+var DefaultBuckets = []float64{.0001, .0005, .001, .005, .01, .05, .1, .5, 1, 5}
+
+// NewHistogram registers a histogram with the given upper bounds, which must be
+// sorted. A +Inf bucket is always added.
+func (r *Registry) NewHistogram(name string, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{family: family{name: name, help: help, typ: "histogram", labels: labels}, buckets: buckets, byLabels: map[string]*histogramSeries{}}
+	r.register(h)
+	return h
+}
+
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	key := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.byLabels[key]
+	if !ok {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.byLabels[key] = s
+	}
+	for i, bound := range h.buckets {
+		if value <= bound {
+			s.counts[i]++
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+// Count returns the number of observations, mostly for tests.
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	key := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok := h.byLabels[key]; ok {
+		return s.count
+	}
+	return 0
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.header(w)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, key := range sortedKeys(h.byLabels) {
+		s := h.byLabels[key]
+		for i, bound := range h.buckets {
+			fmt.Fprintf(w, "%s %d\n", h.series(h.name+"_bucket", key, `le="`+formatFloat(bound)+`"`), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s %d\n", h.series(h.name+"_bucket", key, `le="+Inf"`), s.count)
+		fmt.Fprintf(w, "%s %s\n", h.series(h.name+"_sum", key, ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s %d\n", h.series(h.name+"_count", key, ""), s.count)
+	}
+}
+
+// Quantiles is one series of a summary.
+type Quantiles struct {
+	// Values maps a quantile in [0, 1] to its value.
+	Values map[float64]float64
+	Sum    float64
+	Count  float64
+}
+
+// SummaryFunc is a summary whose quantiles are computed when scraped.
+type SummaryFunc struct {
+	family
+	collect func(emit func(q Quantiles, labelValues ...string))
+}
+
+// NewSummaryFunc registers a summary that calls collect on every scrape,
+// collect emits the quantiles of every combination of label values.
+func (r *Registry) NewSummaryFunc(name string, help string, labels []string, collect func(emit func(q Quantiles, labelValues ...string))) *SummaryFunc {
+	s := &SummaryFunc{family: family{name: name, help: help, typ: "summary", labels: labels}, collect: collect}
+	r.register(s)
+	return s
+}
+
+func (s *SummaryFunc) write(w *bufio.Writer) {
+	all := map[string]Quantiles{}
+	s.collect(func(q Quantiles, labelValues ...string) {
+		all[s.key(labelValues)] = q
+	})
+	s.header(w)
+	for _, key := range sortedKeys(all) {
+		q := all[key]
+		phis := make([]float64, 0, len(q.Values))
+		for phi := range q.Values {
+			phis = append(phis, phi)
+		}
+		sort.Float64s(phis)
+		for _, phi := range phis {
+			fmt.Fprintf(w, "%s %s\n", s.series(s.name, key, `quantile="`+formatFloat(phi)+`"`), formatFloat(q.Values[phi]))
+		}
+		fmt.Fprintf(w, "%s %s\n", s.series(s.name+"_sum", key, ""), formatFloat(q.Sum))
+		fmt.Fprintf(w, "%s %s\n", s.series(s.name+"_count", key, ""), formatFloat(q.Count))
+	}
+}
@@ -0,0 +1,67 @@
+package metrics_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bruhng/distributed-sketching/metrics"
+)
+
+func TestTextFormat(t *testing.T) {
+	r := &metrics.Registry{}
+	merges := r.NewCounter("merges_total", "Merges.", "kind")
+	merges.Inc("kll")
+	merges.Add(2, "count")
+	r.NewGauge("up", "Up.").Set(1)
+	latency := r.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 1}, "kind")
+	latency.Observe(0.05, "kll")
+	latency.Observe(0.5, "kll")
+	latency.Observe(5, "kll")
+	r.NewGaugeFunc("sketch_n", "N.", []string{"name"}, func(emit func(float64, ...string)) {
+		emit(42, `a "quoted" name`)
+	})
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	want := `# HELP merges_total Merges.
+# TYPE merges_total counter
+merges_total{kind="count"} 2
+merges_total{kind="kll"} 1
+# HELP up Up.
+# TYPE up gauge
+up 1
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{kind="kll",le="0.1"} 1
+latency_seconds_bucket{kind="kll",le="1"} 2
+latency_seconds_bucket{kind="kll",le="+Inf"} 3
+latency_seconds_sum{kind="kll"} 5.55
+latency_seconds_count{kind="kll"} 3
+# HELP sketch_n N.
+# TYPE sketch_n gauge
+sketch_n{name="a \"quoted\" name"} 42
+`
+	if got := rec.Body.String(); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestServe(t *testing.T) {
+	metrics.Default.NewCounter("test_serve_total", "Test.").Inc()
+	addr, err := metrics.Serve("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get("http://" + addr.String() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "test_serve_total 1\n") {
+		t.Fatalf("metrics do not contain the counter:\n%s", body)
+	}
+}
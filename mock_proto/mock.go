@@ -82,6 +82,146 @@ func (mr *MockSketcherClientMockRecorder) BadKll(ctx, in any, opts ...any) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BadKll", reflect.TypeOf((*MockSketcherClient)(nil).BadKll), varargs...)
 }
 
+// DescribeSketch mocks base method.
+func (m *MockSketcherClient) DescribeSketch(ctx context.Context, in *proto.DescribeRequest, opts ...grpc.CallOption) (*proto.DescribeReply, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DescribeSketch", varargs...)
+	ret0, _ := ret[0].(*proto.DescribeReply)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DescribeSketch indicates an expected call of DescribeSketch.
+func (mr *MockSketcherClientMockRecorder) DescribeSketch(ctx, in any, opts ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DescribeSketch", reflect.TypeOf((*MockSketcherClient)(nil).DescribeSketch), varargs...)
+}
+
+// DumpFilter mocks base method.
+func (m *MockSketcherClient) DumpFilter(ctx context.Context, in *proto.DumpFilterRequest, opts ...grpc.CallOption) (*proto.DumpFilterReply, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "DumpFilter", varargs...)
+	ret0, _ := ret[0].(*proto.DumpFilterReply)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DumpFilter indicates an expected call of DumpFilter.
+func (mr *MockSketcherClientMockRecorder) DumpFilter(ctx, in any, opts ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DumpFilter", reflect.TypeOf((*MockSketcherClient)(nil).DumpFilter), varargs...)
+}
+
+// ExportSketch mocks base method.
+func (m *MockSketcherClient) ExportSketch(ctx context.Context, in *proto.ExportRequest, opts ...grpc.CallOption) (*proto.ServerState, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ExportSketch", varargs...)
+	ret0, _ := ret[0].(*proto.ServerState)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExportSketch indicates an expected call of ExportSketch.
+func (mr *MockSketcherClientMockRecorder) ExportSketch(ctx, in any, opts ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExportSketch", reflect.TypeOf((*MockSketcherClient)(nil).ExportSketch), varargs...)
+}
+
+// ImportSketch mocks base method.
+func (m *MockSketcherClient) ImportSketch(ctx context.Context, in *proto.ImportRequest, opts ...grpc.CallOption) (*proto.ImportReply, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ImportSketch", varargs...)
+	ret0, _ := ret[0].(*proto.ImportReply)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportSketch indicates an expected call of ImportSketch.
+func (mr *MockSketcherClientMockRecorder) ImportSketch(ctx, in any, opts ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportSketch", reflect.TypeOf((*MockSketcherClient)(nil).ImportSketch), varargs...)
+}
+
+// ListFields mocks base method.
+func (m *MockSketcherClient) ListFields(ctx context.Context, in *proto.ListFieldsRequest, opts ...grpc.CallOption) (*proto.ListFieldsReply, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "ListFields", varargs...)
+	ret0, _ := ret[0].(*proto.ListFieldsReply)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListFields indicates an expected call of ListFields.
+func (mr *MockSketcherClientMockRecorder) ListFields(ctx, in any, opts ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFields", reflect.TypeOf((*MockSketcherClient)(nil).ListFields), varargs...)
+}
+
+// MergeASketch mocks base method.
+func (m *MockSketcherClient) MergeASketch(ctx context.Context, in *proto.ASketch, opts ...grpc.CallOption) (*proto.MergeReply, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "MergeASketch", varargs...)
+	ret0, _ := ret[0].(*proto.MergeReply)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MergeASketch indicates an expected call of MergeASketch.
+func (mr *MockSketcherClientMockRecorder) MergeASketch(ctx, in any, opts ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergeASketch", reflect.TypeOf((*MockSketcherClient)(nil).MergeASketch), varargs...)
+}
+
+// MergeBufIntoASketch mocks base method.
+func (m *MockSketcherClient) MergeBufIntoASketch(ctx context.Context, in *proto.BufBatch, opts ...grpc.CallOption) (*proto.MergeReply, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "MergeBufIntoASketch", varargs...)
+	ret0, _ := ret[0].(*proto.MergeReply)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MergeBufIntoASketch indicates an expected call of MergeBufIntoASketch.
+func (mr *MockSketcherClientMockRecorder) MergeBufIntoASketch(ctx, in any, opts ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergeBufIntoASketch", reflect.TypeOf((*MockSketcherClient)(nil).MergeBufIntoASketch), varargs...)
+}
+
 // MergeCount mocks base method.
 func (m *MockSketcherClient) MergeCount(ctx context.Context, in *proto.CountSketch, opts ...grpc.CallOption) (*proto.MergeReply, error) {
 	m.ctrl.T.Helper()
@@ -102,6 +242,26 @@ func (mr *MockSketcherClientMockRecorder) MergeCount(ctx, in any, opts ...any) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergeCount", reflect.TypeOf((*MockSketcherClient)(nil).MergeCount), varargs...)
 }
 
+// MergeHll mocks base method.
+func (m *MockSketcherClient) MergeHll(ctx context.Context, in *proto.HLLSketch, opts ...grpc.CallOption) (*proto.MergeReply, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "MergeHll", varargs...)
+	ret0, _ := ret[0].(*proto.MergeReply)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MergeHll indicates an expected call of MergeHll.
+func (mr *MockSketcherClientMockRecorder) MergeHll(ctx, in any, opts ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergeHll", reflect.TypeOf((*MockSketcherClient)(nil).MergeHll), varargs...)
+}
+
 // MergeKll mocks base method.
 func (m *MockSketcherClient) MergeKll(ctx context.Context, in *proto.KLLSketch, opts ...grpc.CallOption) (*proto.MergeReply, error) {
 	m.ctrl.T.Helper()
@@ -142,6 +302,26 @@ func (mr *MockSketcherClientMockRecorder) PlotKll(ctx, in any, opts ...any) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PlotKll", reflect.TypeOf((*MockSketcherClient)(nil).PlotKll), varargs...)
 }
 
+// QueryASketch mocks base method.
+func (m *MockSketcherClient) QueryASketch(ctx context.Context, in *proto.NumericValue, opts ...grpc.CallOption) (*proto.CountQueryReply, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "QueryASketch", varargs...)
+	ret0, _ := ret[0].(*proto.CountQueryReply)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryASketch indicates an expected call of QueryASketch.
+func (mr *MockSketcherClientMockRecorder) QueryASketch(ctx, in any, opts ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryASketch", reflect.TypeOf((*MockSketcherClient)(nil).QueryASketch), varargs...)
+}
+
 // QueryCount mocks base method.
 func (m *MockSketcherClient) QueryCount(ctx context.Context, in *proto.NumericValue, opts ...grpc.CallOption) (*proto.CountQueryReply, error) {
 	m.ctrl.T.Helper()
@@ -162,6 +342,26 @@ func (mr *MockSketcherClientMockRecorder) QueryCount(ctx, in any, opts ...any) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryCount", reflect.TypeOf((*MockSketcherClient)(nil).QueryCount), varargs...)
 }
 
+// QueryHll mocks base method.
+func (m *MockSketcherClient) QueryHll(ctx context.Context, in *proto.CardinalityRequest, opts ...grpc.CallOption) (*proto.CardinalityReply, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "QueryHll", varargs...)
+	ret0, _ := ret[0].(*proto.CardinalityReply)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// QueryHll indicates an expected call of QueryHll.
+func (mr *MockSketcherClientMockRecorder) QueryHll(ctx, in any, opts ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryHll", reflect.TypeOf((*MockSketcherClient)(nil).QueryHll), varargs...)
+}
+
 // QueryKll mocks base method.
 func (m *MockSketcherClient) QueryKll(ctx context.Context, in *proto.NumericValue, opts ...grpc.CallOption) (*proto.QueryReturn, error) {
 	m.ctrl.T.Helper()
@@ -183,7 +383,7 @@ func (mr *MockSketcherClientMockRecorder) QueryKll(ctx, in any, opts ...any) *go
 }
 
 // RestartServer mocks base method.
-func (m *MockSketcherClient) RestartServer(ctx context.Context, in *proto.EmptyMessage, opts ...grpc.CallOption) (*proto.EmptyMessage, error) {
+func (m *MockSketcherClient) RestartServer(ctx context.Context, in *proto.RestartMessage, opts ...grpc.CallOption) (*proto.EmptyMessage, error) {
 	m.ctrl.T.Helper()
 	varargs := []any{ctx, in}
 	for _, a := range opts {
@@ -241,3 +441,23 @@ func (mr *MockSketcherClientMockRecorder) TestLatency(ctx, in any, opts ...any)
 	varargs := append([]any{ctx, in}, opts...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TestLatency", reflect.TypeOf((*MockSketcherClient)(nil).TestLatency), varargs...)
 }
+
+// TopKASketch mocks base method.
+func (m *MockSketcherClient) TopKASketch(ctx context.Context, in *proto.TopKRequest, opts ...grpc.CallOption) (*proto.TopKReply, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, in}
+	for _, a := range opts {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "TopKASketch", varargs...)
+	ret0, _ := ret[0].(*proto.TopKReply)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TopKASketch indicates an expected call of TopKASketch.
+func (mr *MockSketcherClientMockRecorder) TopKASketch(ctx, in any, opts ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, in}, opts...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TopKASketch", reflect.TypeOf((*MockSketcherClient)(nil).TopKASketch), varargs...)
+}
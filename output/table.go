@@ -0,0 +1,70 @@
+// Package output prints the tables the command line tools answer with.
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Table is the result of a command. Values are strings, int64 or float64 so
+// JSON keeps numbers as numbers.
+type Table struct {
+	Columns []string
+	Rows    [][]any
+}
+
+// Add appends a row to t
+func (t *Table) Add(row ...any) {
+	t.Rows = append(t.Rows, row)
+}
+
+// Write prints t as "table", "json" (an array of objects) or "csv".
+func (t *Table) Write(w io.Writer, format string) error {
+	switch format {
+	case "table", "":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(t.Columns, "\t")))
+		for _, row := range t.Rows {
+			fmt.Fprintln(tw, strings.Join(cells(row), "\t"))
+		}
+		return tw.Flush()
+	case "json":
+		out := make([]map[string]any, len(t.Rows))
+		for i, row := range t.Rows {
+			out[i] = map[string]any{}
+			for j, col := range t.Columns {
+				out[i][col] = row[j]
+			}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(t.Columns)
+		for _, row := range t.Rows {
+			cw.Write(cells(row))
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("%s is not an output format, use table, json or csv", format)
+}
+
+func cells(row []any) []string {
+	out := make([]string, len(row))
+	for i, v := range row {
+		switch v := v.(type) {
+		case float64:
+			out[i] = strconv.FormatFloat(v, 'g', -1, 64)
+		default:
+			out[i] = fmt.Sprint(v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,27 @@
+package output_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bruhng/distributed-sketching/output"
+)
+
+func TestTableWrite(t *testing.T) {
+	table := &output.Table{Columns: []string{"phi", "value"}}
+	table.Add(0.5, int64(3))
+	table.Add(0.99, "x,y")
+	for format, want := range map[string]string{
+		"table": "PHI   VALUE\n0.5   3\n0.99  x,y\n",
+		"csv":   "phi,value\n0.5,3\n0.99,\"x,y\"\n",
+		"json":  "[\n  {\n    \"phi\": 0.5,\n    \"value\": 3\n  },\n  {\n    \"phi\": 0.99,\n    \"value\": \"x,y\"\n  }\n]\n",
+	} {
+		var out bytes.Buffer
+		if err := table.Write(&out, format); err != nil || out.String() != want {
+			t.Errorf("%s: got %q, %v, want %q", format, out.String(), err, want)
+		}
+	}
+	if err := table.Write(&bytes.Buffer{}, "yaml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
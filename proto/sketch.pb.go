@@ -26,6 +26,7 @@ type CountSketch struct {
 	Rows          []*IntRow              `protobuf:"bytes,1,rep,name=rows,proto3" json:"rows,omitempty"`
 	Seeds         []uint32               `protobuf:"varint,2,rep,packed,name=seeds,proto3" json:"seeds,omitempty"`
 	Type          string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	Name          string                 `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"` // empty merges into the default sketch
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -81,6 +82,13 @@ func (x *CountSketch) GetType() string {
 	return ""
 }
 
+func (x *CountSketch) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
 type IntRow struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Val           []int64                `protobuf:"varint,1,rep,packed,name=val,proto3" json:"val,omitempty"`
@@ -174,6 +182,7 @@ type KLLSketch struct {
 	Rows          []*NumericRow          `protobuf:"bytes,1,rep,name=rows,proto3" json:"rows,omitempty"`
 	N             int64                  `protobuf:"varint,2,opt,name=n,proto3" json:"n,omitempty"`
 	Type          string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	Name          string                 `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"` // empty merges into the default sketch
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -229,6 +238,13 @@ func (x *KLLSketch) GetType() string {
 	return ""
 }
 
+func (x *KLLSketch) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
 type BadArray struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Arr           *NumericRow            `protobuf:"bytes,1,opt,name=arr,proto3" json:"arr,omitempty"`
@@ -333,6 +349,7 @@ type NumericValue struct {
 	//	*NumericValue_FloatVal
 	Value         isNumericValue_Value `protobuf_oneof:"value"`
 	Type          string               `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	Name          string               `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"` // sketch to query, empty is the default sketch
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -399,6 +416,13 @@ func (x *NumericValue) GetType() string {
 	return ""
 }
 
+func (x *NumericValue) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
 type isNumericValue_Value interface {
 	isNumericValue_Value()
 }
@@ -419,6 +443,7 @@ type ReverseQuery struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Phi           float64                `protobuf:"fixed64,1,opt,name=phi,proto3" json:"phi,omitempty"`
 	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -467,6 +492,13 @@ func (x *ReverseQuery) GetType() string {
 	return ""
 }
 
+func (x *ReverseQuery) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
 type QueryReturn struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Phi           int64                  `protobuf:"varint,1,opt,name=phi,proto3" json:"phi,omitempty"`
@@ -564,9 +596,18 @@ func (x *MergeReply) GetStatus() int64 {
 }
 
 type PlotRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	NumBins       int64                  `protobuf:"varint,1,opt,name=numBins,proto3" json:"numBins,omitempty"`
-	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	NumBins int64                  `protobuf:"varint,1,opt,name=numBins,proto3" json:"numBins,omitempty"`
+	Type    string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Name    string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	// explicit bin edges in increasing order, numBins is ignored when given
+	Splits []float64 `protobuf:"fixed64,4,rep,packed,name=splits,proto3" json:"splits,omitempty"`
+	// equal width bins over [rangeMin, rangeMax] instead of the range of the
+	// sketch, used when rangeMin < rangeMax
+	RangeMin float64 `protobuf:"fixed64,5,opt,name=rangeMin,proto3" json:"rangeMin,omitempty"`
+	RangeMax float64 `protobuf:"fixed64,6,opt,name=rangeMax,proto3" json:"rangeMax,omitempty"`
+	// bins holding about the same number of items, their edges are quantiles
+	EquiDepth     bool `protobuf:"varint,7,opt,name=equiDepth,proto3" json:"equiDepth,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -615,10 +656,51 @@ func (x *PlotRequest) GetType() string {
 	return ""
 }
 
+func (x *PlotRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *PlotRequest) GetSplits() []float64 {
+	if x != nil {
+		return x.Splits
+	}
+	return nil
+}
+
+func (x *PlotRequest) GetRangeMin() float64 {
+	if x != nil {
+		return x.RangeMin
+	}
+	return 0
+}
+
+func (x *PlotRequest) GetRangeMax() float64 {
+	if x != nil {
+		return x.RangeMax
+	}
+	return 0
+}
+
+func (x *PlotRequest) GetEquiDepth() bool {
+	if x != nil {
+		return x.EquiDepth
+	}
+	return false
+}
+
 type PlotKllReply struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Step          float64                `protobuf:"fixed64,1,opt,name=step,proto3" json:"step,omitempty"`
-	Pmf           []float64              `protobuf:"fixed64,2,rep,packed,name=pmf,proto3" json:"pmf,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// width of equal width bins, 0 for other bins
+	Step float64 `protobuf:"fixed64,1,opt,name=step,proto3" json:"step,omitempty"`
+	// items in each bin
+	Pmf []float64 `protobuf:"fixed64,2,rep,packed,name=pmf,proto3" json:"pmf,omitempty"`
+	// edges[i] and edges[i+1] bound bin i, so there is one more edge than bins
+	Edges []float64 `protobuf:"fixed64,3,rep,packed,name=edges,proto3" json:"edges,omitempty"`
+	// pmf divided by all items of the sketch, including those outside the bins
+	Mass          []float64 `protobuf:"fixed64,4,rep,packed,name=mass,proto3" json:"mass,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -667,6 +749,20 @@ func (x *PlotKllReply) GetPmf() []float64 {
 	return nil
 }
 
+func (x *PlotKllReply) GetEdges() []float64 {
+	if x != nil {
+		return x.Edges
+	}
+	return nil
+}
+
+func (x *PlotKllReply) GetMass() []float64 {
+	if x != nil {
+		return x.Mass
+	}
+	return nil
+}
+
 type EmptyMessage struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
@@ -752,7 +848,7 @@ type ASketch struct {
 	Filter        []*ASketchFilterEntry  `protobuf:"bytes,1,rep,name=filter,proto3" json:"filter,omitempty"`
 	CountMin      *CountMin              `protobuf:"bytes,2,opt,name=count_min,json=countMin,proto3" json:"count_min,omitempty"`
 	Type          string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
-	Field         string                 `protobuf:"bytes,4,opt,name=field,proto3" json:"field,omitempty"`
+	Name          string                 `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"` // empty merges into the default sketch
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -808,9 +904,9 @@ func (x *ASketch) GetType() string {
 	return ""
 }
 
-func (x *ASketch) GetField() string {
+func (x *ASketch) GetName() string {
 	if x != nil {
-		return x.Field
+		return x.Name
 	}
 	return ""
 }
@@ -879,6 +975,7 @@ type BufBatch struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Items         []*NumericValue        `protobuf:"bytes,1,rep,name=items,proto3" json:"items,omitempty"`
 	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"` // e.g., "int", "double"
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -927,6 +1024,13 @@ func (x *BufBatch) GetType() string {
 	return ""
 }
 
+func (x *BufBatch) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
 type CountMin struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Rows          []*IntRow              `protobuf:"bytes,1,rep,name=rows,proto3" json:"rows,omitempty"`
@@ -983,7 +1087,7 @@ type TopKRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	K             uint32                 `protobuf:"varint,1,opt,name=k,proto3" json:"k,omitempty"`
 	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
-	Field         string                 `protobuf:"bytes,3,opt,name=field,proto3" json:"field,omitempty"`
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -1032,9 +1136,9 @@ func (x *TopKRequest) GetType() string {
 	return ""
 }
 
-func (x *TopKRequest) GetField() string {
+func (x *TopKRequest) GetName() string {
 	if x != nil {
-		return x.Field
+		return x.Name
 	}
 	return ""
 }
@@ -1135,27 +1239,27 @@ func (x *TopKReply) GetEntries() []*TopKEntry {
 	return nil
 }
 
-type DumpFilterRequest struct {
+type ListFieldsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"` // empty lists fields of every type
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DumpFilterRequest) Reset() {
-	*x = DumpFilterRequest{}
+func (x *ListFieldsRequest) Reset() {
+	*x = ListFieldsRequest{}
 	mi := &file_sketch_proto_msgTypes[21]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DumpFilterRequest) String() string {
+func (x *ListFieldsRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DumpFilterRequest) ProtoMessage() {}
+func (*ListFieldsRequest) ProtoMessage() {}
 
-func (x *DumpFilterRequest) ProtoReflect() protoreflect.Message {
+func (x *ListFieldsRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_sketch_proto_msgTypes[21]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1167,39 +1271,40 @@ func (x *DumpFilterRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DumpFilterRequest.ProtoReflect.Descriptor instead.
-func (*DumpFilterRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use ListFieldsRequest.ProtoReflect.Descriptor instead.
+func (*ListFieldsRequest) Descriptor() ([]byte, []int) {
 	return file_sketch_proto_rawDescGZIP(), []int{21}
 }
 
-func (x *DumpFilterRequest) GetType() string {
+func (x *ListFieldsRequest) GetType() string {
 	if x != nil {
 		return x.Type
 	}
 	return ""
 }
 
-type DumpFilterReply struct {
+type FieldInfo struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Entries       []*ASketchFilterEntry  `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	Field         string                 `protobuf:"bytes,1,opt,name=field,proto3" json:"field,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DumpFilterReply) Reset() {
-	*x = DumpFilterReply{}
+func (x *FieldInfo) Reset() {
+	*x = FieldInfo{}
 	mi := &file_sketch_proto_msgTypes[22]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DumpFilterReply) String() string {
+func (x *FieldInfo) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DumpFilterReply) ProtoMessage() {}
+func (*FieldInfo) ProtoMessage() {}
 
-func (x *DumpFilterReply) ProtoReflect() protoreflect.Message {
+func (x *FieldInfo) ProtoReflect() protoreflect.Message {
 	mi := &file_sketch_proto_msgTypes[22]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -1211,111 +1316,986 @@ func (x *DumpFilterReply) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DumpFilterReply.ProtoReflect.Descriptor instead.
-func (*DumpFilterReply) Descriptor() ([]byte, []int) {
+// Deprecated: Use FieldInfo.ProtoReflect.Descriptor instead.
+func (*FieldInfo) Descriptor() ([]byte, []int) {
 	return file_sketch_proto_rawDescGZIP(), []int{22}
 }
 
-func (x *DumpFilterReply) GetEntries() []*ASketchFilterEntry {
+func (x *FieldInfo) GetField() string {
 	if x != nil {
-		return x.Entries
+		return x.Field
+	}
+	return ""
+}
+
+func (x *FieldInfo) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+type ListFieldsReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Fields        []*FieldInfo           `protobuf:"bytes,1,rep,name=fields,proto3" json:"fields,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFieldsReply) Reset() {
+	*x = ListFieldsReply{}
+	mi := &file_sketch_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFieldsReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFieldsReply) ProtoMessage() {}
+
+func (x *ListFieldsReply) ProtoReflect() protoreflect.Message {
+	mi := &file_sketch_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFieldsReply.ProtoReflect.Descriptor instead.
+func (*ListFieldsReply) Descriptor() ([]byte, []int) {
+	return file_sketch_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *ListFieldsReply) GetFields() []*FieldInfo {
+	if x != nil {
+		return x.Fields
 	}
 	return nil
 }
 
-var File_sketch_proto protoreflect.FileDescriptor
+type HLLSketch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Registers     []int64                `protobuf:"varint,1,rep,packed,name=registers,proto3" json:"registers,omitempty"`
+	H             uint32                 `protobuf:"varint,2,opt,name=h,proto3" json:"h,omitempty"` // bucket hash seed
+	G             uint32                 `protobuf:"varint,3,opt,name=g,proto3" json:"g,omitempty"` // value hash seed
+	Type          string                 `protobuf:"bytes,4,opt,name=type,proto3" json:"type,omitempty"`
+	Name          string                 `protobuf:"bytes,5,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_sketch_proto_rawDesc = "" +
-	"\n" +
-	"\fsketch.proto\x12\x05proto\"Z\n" +
-	"\vCountSketch\x12!\n" +
-	"\x04rows\x18\x01 \x03(\v2\r.proto.IntRowR\x04rows\x12\x14\n" +
-	"\x05seeds\x18\x02 \x03(\rR\x05seeds\x12\x12\n" +
-	"\x04type\x18\x03 \x01(\tR\x04type\"\x1a\n" +
-	"\x06IntRow\x12\x10\n" +
-	"\x03val\x18\x01 \x03(\x03R\x03val\"#\n" +
-	"\x0fCountQueryReply\x12\x10\n" +
-	"\x03res\x18\x01 \x01(\x03R\x03res\"T\n" +
-	"\tKLLSketch\x12%\n" +
-	"\x04rows\x18\x01 \x03(\v2\x11.proto.NumericRowR\x04rows\x12\f\n" +
-	"\x01n\x18\x02 \x01(\x03R\x01n\x12\x12\n" +
-	"\x04type\x18\x03 \x01(\tR\x04type\"C\n" +
-	"\bBadArray\x12#\n" +
-	"\x03arr\x18\x01 \x01(\v2\x11.proto.NumericRowR\x03arr\x12\x12\n" +
-	"\x04type\x18\x02 \x01(\tR\x04type\"9\n" +
-	"\n" +
-	"NumericRow\x12+\n" +
-	"\x06values\x18\x01 \x03(\v2\x13.proto.NumericValueR\x06values\"e\n" +
-	"\fNumericValue\x12\x19\n" +
-	"\aint_val\x18\x01 \x01(\x03H\x00R\x06intVal\x12\x1d\n" +
-	"\tfloat_val\x18\x02 \x01(\x01H\x00R\bfloatVal\x12\x12\n" +
-	"\x04type\x18\x03 \x01(\tR\x04typeB\a\n" +
-	"\x05value\"4\n" +
-	"\fReverseQuery\x12\x10\n" +
-	"\x03phi\x18\x01 \x01(\x01R\x03phi\x12\x12\n" +
-	"\x04type\x18\x02 \x01(\tR\x04type\"-\n" +
-	"\vQueryReturn\x12\x10\n" +
-	"\x03phi\x18\x01 \x01(\x03R\x03phi\x12\f\n" +
-	"\x01N\x18\x02 \x01(\x03R\x01N\"$\n" +
-	"\n" +
-	"MergeReply\x12\x16\n" +
-	"\x06status\x18\x01 \x01(\x03R\x06status\";\n" +
-	"\vPlotRequest\x12\x18\n" +
-	"\anumBins\x18\x01 \x01(\x03R\anumBins\x12\x12\n" +
-	"\x04type\x18\x02 \x01(\tR\x04type\"4\n" +
-	"\fPlotKllReply\x12\x12\n" +
-	"\x04step\x18\x01 \x01(\x01R\x04step\x12\x10\n" +
-	"\x03pmf\x18\x02 \x03(\x01R\x03pmf\"\x0e\n" +
-	"\fEmptyMessage\"(\n" +
-	"\x0eRestartMessage\x12\x16\n" +
-	"\x06numMsg\x18\x01 \x01(\x03R\x06numMsg\"\x94\x01\n" +
-	"\aASketch\x121\n" +
-	"\x06filter\x18\x01 \x03(\v2\x19.proto.ASketchFilterEntryR\x06filter\x12,\n" +
-	"\tcount_min\x18\x02 \x01(\v2\x0f.proto.CountMinR\bcountMin\x12\x12\n" +
-	"\x04type\x18\x03 \x01(\tR\x04type\x12\x14\n" +
-	"\x05field\x18\x04 \x01(\tR\x05field\"a\n" +
-	"\x12ASketchFilterEntry\x12'\n" +
-	"\x04item\x18\x01 \x01(\v2\x13.proto.NumericValueR\x04item\x12\x10\n" +
-	"\x03old\x18\x02 \x01(\x03R\x03old\x12\x10\n" +
-	"\x03new\x18\x03 \x01(\x03R\x03new\"I\n" +
-	"\bBufBatch\x12)\n" +
-	"\x05items\x18\x01 \x03(\v2\x13.proto.NumericValueR\x05items\x12\x12\n" +
-	"\x04type\x18\x02 \x01(\tR\x04type\"C\n" +
-	"\bCountMin\x12!\n" +
-	"\x04rows\x18\x01 \x03(\v2\r.proto.IntRowR\x04rows\x12\x14\n" +
-	"\x05seeds\x18\x02 \x03(\rR\x05seeds\"E\n" +
-	"\vTopKRequest\x12\f\n" +
-	"\x01k\x18\x01 \x01(\rR\x01k\x12\x12\n" +
-	"\x04type\x18\x02 \x01(\tR\x04type\x12\x14\n" +
-	"\x05field\x18\x03 \x01(\tR\x05field\"M\n" +
-	"\tTopKEntry\x12%\n" +
-	"\x03key\x18\x01 \x01(\v2\x13.proto.NumericValueR\x03key\x12\x19\n" +
-	"\best_freq\x18\x02 \x01(\x03R\aestFreq\"7\n" +
-	"\tTopKReply\x12*\n" +
-	"\aentries\x18\x01 \x03(\v2\x10.proto.TopKEntryR\aentries\"'\n" +
-	"\x11DumpFilterRequest\x12\x12\n" +
-	"\x04type\x18\x01 \x01(\tR\x04type\"F\n" +
-	"\x0fDumpFilterReply\x123\n" +
-	"\aentries\x18\x01 \x03(\v2\x19.proto.ASketchFilterEntryR\aentries2\xdf\x06\n" +
-	"\bSketcher\x121\n" +
-	"\bMergeKll\x12\x10.proto.KLLSketch\x1a\x11.proto.MergeReply\"\x00\x125\n" +
-	"\bQueryKll\x12\x13.proto.NumericValue\x1a\x12.proto.QueryReturn\"\x00\x12=\n" +
-	"\x0fReverseQueryKll\x12\x13.proto.ReverseQuery\x1a\x13.proto.NumericValue\"\x00\x124\n" +
-	"\aPlotKll\x12\x12.proto.PlotRequest\x1a\x13.proto.PlotKllReply\"\x00\x125\n" +
-	"\n" +
-	"MergeCount\x12\x12.proto.CountSketch\x1a\x11.proto.MergeReply\"\x00\x12;\n" +
-	"\n" +
-	"QueryCount\x12\x13.proto.NumericValue\x1a\x16.proto.CountQueryReply\"\x00\x129\n" +
-	"\vTestLatency\x12\x13.proto.EmptyMessage\x1a\x13.proto.EmptyMessage\"\x00\x12.\n" +
-	"\x06BadKll\x12\x0f.proto.BadArray\x1a\x11.proto.MergeReply\"\x00\x120\n" +
-	"\bBadCount\x12\x0f.proto.BadArray\x1a\x11.proto.MergeReply\"\x00\x123\n" +
-	"\fMergeASketch\x12\x0e.proto.ASketch\x1a\x11.proto.MergeReply\"\x00\x12=\n" +
-	"\fQueryASketch\x12\x13.proto.NumericValue\x1a\x16.proto.CountQueryReply\"\x00\x12=\n" +
-	"\rRestartServer\x12\x15.proto.RestartMessage\x1a\x13.proto.EmptyMessage\"\x00\x123\n" +
-	"\vTopKASketch\x12\x12.proto.TopKRequest\x1a\x10.proto.TopKReply\x12>\n" +
-	"\n" +
-	"DumpFilter\x12\x18.proto.DumpFilterRequest\x1a\x16.proto.DumpFilterReply\x12;\n" +
-	"\x13MergeBufIntoASketch\x12\x0f.proto.BufBatch\x1a\x11.proto.MergeReply\"\x00B/Z-github.com/bruhng/distributed-sketching/protob\x06proto3"
+func (x *HLLSketch) Reset() {
+	*x = HLLSketch{}
+	mi := &file_sketch_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HLLSketch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HLLSketch) ProtoMessage() {}
+
+func (x *HLLSketch) ProtoReflect() protoreflect.Message {
+	mi := &file_sketch_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HLLSketch.ProtoReflect.Descriptor instead.
+func (*HLLSketch) Descriptor() ([]byte, []int) {
+	return file_sketch_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *HLLSketch) GetRegisters() []int64 {
+	if x != nil {
+		return x.Registers
+	}
+	return nil
+}
+
+func (x *HLLSketch) GetH() uint32 {
+	if x != nil {
+		return x.H
+	}
+	return 0
+}
+
+func (x *HLLSketch) GetG() uint32 {
+	if x != nil {
+		return x.G
+	}
+	return 0
+}
+
+func (x *HLLSketch) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *HLLSketch) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type CardinalityRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CardinalityRequest) Reset() {
+	*x = CardinalityRequest{}
+	mi := &file_sketch_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CardinalityRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CardinalityRequest) ProtoMessage() {}
+
+func (x *CardinalityRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sketch_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CardinalityRequest.ProtoReflect.Descriptor instead.
+func (*CardinalityRequest) Descriptor() ([]byte, []int) {
+	return file_sketch_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *CardinalityRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CardinalityRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+type CardinalityReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Estimate      float64                `protobuf:"fixed64,1,opt,name=estimate,proto3" json:"estimate,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CardinalityReply) Reset() {
+	*x = CardinalityReply{}
+	mi := &file_sketch_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CardinalityReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CardinalityReply) ProtoMessage() {}
+
+func (x *CardinalityReply) ProtoReflect() protoreflect.Message {
+	mi := &file_sketch_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CardinalityReply.ProtoReflect.Descriptor instead.
+func (*CardinalityReply) Descriptor() ([]byte, []int) {
+	return file_sketch_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *CardinalityReply) GetEstimate() float64 {
+	if x != nil {
+		return x.Estimate
+	}
+	return 0
+}
+
+type DumpFilterRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DumpFilterRequest) Reset() {
+	*x = DumpFilterRequest{}
+	mi := &file_sketch_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DumpFilterRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DumpFilterRequest) ProtoMessage() {}
+
+func (x *DumpFilterRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sketch_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DumpFilterRequest.ProtoReflect.Descriptor instead.
+func (*DumpFilterRequest) Descriptor() ([]byte, []int) {
+	return file_sketch_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *DumpFilterRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *DumpFilterRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type DumpFilterReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*ASketchFilterEntry  `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DumpFilterReply) Reset() {
+	*x = DumpFilterReply{}
+	mi := &file_sketch_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DumpFilterReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DumpFilterReply) ProtoMessage() {}
+
+func (x *DumpFilterReply) ProtoReflect() protoreflect.Message {
+	mi := &file_sketch_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DumpFilterReply.ProtoReflect.Descriptor instead.
+func (*DumpFilterReply) Descriptor() ([]byte, []int) {
+	return file_sketch_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *DumpFilterReply) GetEntries() []*ASketchFilterEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+// Every named sketch of a server, written on shutdown and read on start
+type ServerState struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Kll           []*KLLSketch           `protobuf:"bytes,1,rep,name=kll,proto3" json:"kll,omitempty"`
+	Count         []*CountSketch         `protobuf:"bytes,2,rep,name=count,proto3" json:"count,omitempty"`
+	Hll           []*HLLSketch           `protobuf:"bytes,3,rep,name=hll,proto3" json:"hll,omitempty"`
+	Asketch       []*ASketch             `protobuf:"bytes,4,rep,name=asketch,proto3" json:"asketch,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ServerState) Reset() {
+	*x = ServerState{}
+	mi := &file_sketch_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ServerState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServerState) ProtoMessage() {}
+
+func (x *ServerState) ProtoReflect() protoreflect.Message {
+	mi := &file_sketch_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServerState.ProtoReflect.Descriptor instead.
+func (*ServerState) Descriptor() ([]byte, []int) {
+	return file_sketch_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *ServerState) GetKll() []*KLLSketch {
+	if x != nil {
+		return x.Kll
+	}
+	return nil
+}
+
+func (x *ServerState) GetCount() []*CountSketch {
+	if x != nil {
+		return x.Count
+	}
+	return nil
+}
+
+func (x *ServerState) GetHll() []*HLLSketch {
+	if x != nil {
+		return x.Hll
+	}
+	return nil
+}
+
+func (x *ServerState) GetAsketch() []*ASketch {
+	if x != nil {
+		return x.Asketch
+	}
+	return nil
+}
+
+type ExportRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// sketches merged under this name, empty for the unnamed ones
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// kll, count, hll or asketch, empty for every kind
+	Kind string `protobuf:"bytes,2,opt,name=kind,proto3" json:"kind,omitempty"`
+	// int or float64, empty for both
+	Type string `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	// every name instead of name
+	All           bool `protobuf:"varint,4,opt,name=all,proto3" json:"all,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportRequest) Reset() {
+	*x = ExportRequest{}
+	mi := &file_sketch_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportRequest) ProtoMessage() {}
+
+func (x *ExportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sketch_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportRequest.ProtoReflect.Descriptor instead.
+func (*ExportRequest) Descriptor() ([]byte, []int) {
+	return file_sketch_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *ExportRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ExportRequest) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *ExportRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ExportRequest) GetAll() bool {
+	if x != nil {
+		return x.All
+	}
+	return false
+}
+
+type ImportRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Sketches *ServerState           `protobuf:"bytes,1,opt,name=sketches,proto3" json:"sketches,omitempty"`
+	// merge (the default) adds to the sketches of the server, replace drops
+	// the server's sketches of the same kind, name and type first
+	Mode string `protobuf:"bytes,2,opt,name=mode,proto3" json:"mode,omitempty"`
+	// imports every sketch under this name instead of its own
+	Name          *string `protobuf:"bytes,3,opt,name=name,proto3,oneof" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportRequest) Reset() {
+	*x = ImportRequest{}
+	mi := &file_sketch_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportRequest) ProtoMessage() {}
+
+func (x *ImportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sketch_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportRequest.ProtoReflect.Descriptor instead.
+func (*ImportRequest) Descriptor() ([]byte, []int) {
+	return file_sketch_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *ImportRequest) GetSketches() *ServerState {
+	if x != nil {
+		return x.Sketches
+	}
+	return nil
+}
+
+func (x *ImportRequest) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *ImportRequest) GetName() string {
+	if x != nil && x.Name != nil {
+		return *x.Name
+	}
+	return ""
+}
+
+type ImportReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Imported      int64                  `protobuf:"varint,1,opt,name=imported,proto3" json:"imported,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportReply) Reset() {
+	*x = ImportReply{}
+	mi := &file_sketch_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportReply) ProtoMessage() {}
+
+func (x *ImportReply) ProtoReflect() protoreflect.Message {
+	mi := &file_sketch_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportReply.ProtoReflect.Descriptor instead.
+func (*ImportReply) Descriptor() ([]byte, []int) {
+	return file_sketch_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *ImportReply) GetImported() int64 {
+	if x != nil {
+		return x.Imported
+	}
+	return 0
+}
+
+type DescribeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// sketches merged under this name, empty for the unnamed ones
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// kll, count, hll or asketch, empty for every kind
+	Kind string `protobuf:"bytes,2,opt,name=kind,proto3" json:"kind,omitempty"`
+	// int or float64, empty for both
+	Type string `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	// every name instead of name
+	All           bool `protobuf:"varint,4,opt,name=all,proto3" json:"all,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DescribeRequest) Reset() {
+	*x = DescribeRequest{}
+	mi := &file_sketch_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DescribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DescribeRequest) ProtoMessage() {}
+
+func (x *DescribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sketch_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DescribeRequest.ProtoReflect.Descriptor instead.
+func (*DescribeRequest) Descriptor() ([]byte, []int) {
+	return file_sketch_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *DescribeRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *DescribeRequest) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *DescribeRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *DescribeRequest) GetAll() bool {
+	if x != nil {
+		return x.All
+	}
+	return false
+}
+
+type SketchInfo struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Kind  string                 `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"`
+	Type  string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Name  string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	// k of KLL, width and depth of Count and ASketch, slots of ASketch,
+	// registers of HLL
+	Params map[string]int64 `protobuf:"bytes,4,rep,name=params,proto3" json:"params,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	// items summarized, 0 for count and hll sketches, which do not keep it
+	N int64 `protobuf:"varint,5,opt,name=n,proto3" json:"n,omitempty"`
+	// approximate size of the sketch in bytes
+	MemoryBytes int64 `protobuf:"varint,6,opt,name=memory_bytes,json=memoryBytes,proto3" json:"memory_bytes,omitempty"`
+	// merges received since the server started
+	Merges int64 `protobuf:"varint,7,opt,name=merges,proto3" json:"merges,omitempty"`
+	// unix time of the last merge in nanoseconds, 0 if there was none
+	LastMergeUnixNano int64 `protobuf:"varint,8,opt,name=last_merge_unix_nano,json=lastMergeUnixNano,proto3" json:"last_merge_unix_nano,omitempty"`
+	// items kept on each level of a KLL sketch, from the lowest
+	LevelSizes    []int64 `protobuf:"varint,9,rep,packed,name=level_sizes,json=levelSizes,proto3" json:"level_sizes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SketchInfo) Reset() {
+	*x = SketchInfo{}
+	mi := &file_sketch_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SketchInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SketchInfo) ProtoMessage() {}
+
+func (x *SketchInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_sketch_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SketchInfo.ProtoReflect.Descriptor instead.
+func (*SketchInfo) Descriptor() ([]byte, []int) {
+	return file_sketch_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *SketchInfo) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *SketchInfo) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *SketchInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *SketchInfo) GetParams() map[string]int64 {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+func (x *SketchInfo) GetN() int64 {
+	if x != nil {
+		return x.N
+	}
+	return 0
+}
+
+func (x *SketchInfo) GetMemoryBytes() int64 {
+	if x != nil {
+		return x.MemoryBytes
+	}
+	return 0
+}
+
+func (x *SketchInfo) GetMerges() int64 {
+	if x != nil {
+		return x.Merges
+	}
+	return 0
+}
+
+func (x *SketchInfo) GetLastMergeUnixNano() int64 {
+	if x != nil {
+		return x.LastMergeUnixNano
+	}
+	return 0
+}
+
+func (x *SketchInfo) GetLevelSizes() []int64 {
+	if x != nil {
+		return x.LevelSizes
+	}
+	return nil
+}
+
+type DescribeReply struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sketches      []*SketchInfo          `protobuf:"bytes,1,rep,name=sketches,proto3" json:"sketches,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DescribeReply) Reset() {
+	*x = DescribeReply{}
+	mi := &file_sketch_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DescribeReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DescribeReply) ProtoMessage() {}
+
+func (x *DescribeReply) ProtoReflect() protoreflect.Message {
+	mi := &file_sketch_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DescribeReply.ProtoReflect.Descriptor instead.
+func (*DescribeReply) Descriptor() ([]byte, []int) {
+	return file_sketch_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *DescribeReply) GetSketches() []*SketchInfo {
+	if x != nil {
+		return x.Sketches
+	}
+	return nil
+}
+
+var File_sketch_proto protoreflect.FileDescriptor
+
+const file_sketch_proto_rawDesc = "" +
+	"\n" +
+	"\fsketch.proto\x12\x05proto\"n\n" +
+	"\vCountSketch\x12!\n" +
+	"\x04rows\x18\x01 \x03(\v2\r.proto.IntRowR\x04rows\x12\x14\n" +
+	"\x05seeds\x18\x02 \x03(\rR\x05seeds\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x12\n" +
+	"\x04name\x18\x04 \x01(\tR\x04name\"\x1a\n" +
+	"\x06IntRow\x12\x10\n" +
+	"\x03val\x18\x01 \x03(\x03R\x03val\"#\n" +
+	"\x0fCountQueryReply\x12\x10\n" +
+	"\x03res\x18\x01 \x01(\x03R\x03res\"h\n" +
+	"\tKLLSketch\x12%\n" +
+	"\x04rows\x18\x01 \x03(\v2\x11.proto.NumericRowR\x04rows\x12\f\n" +
+	"\x01n\x18\x02 \x01(\x03R\x01n\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x12\n" +
+	"\x04name\x18\x04 \x01(\tR\x04name\"C\n" +
+	"\bBadArray\x12#\n" +
+	"\x03arr\x18\x01 \x01(\v2\x11.proto.NumericRowR\x03arr\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\"9\n" +
+	"\n" +
+	"NumericRow\x12+\n" +
+	"\x06values\x18\x01 \x03(\v2\x13.proto.NumericValueR\x06values\"y\n" +
+	"\fNumericValue\x12\x19\n" +
+	"\aint_val\x18\x01 \x01(\x03H\x00R\x06intVal\x12\x1d\n" +
+	"\tfloat_val\x18\x02 \x01(\x01H\x00R\bfloatVal\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x12\n" +
+	"\x04name\x18\x04 \x01(\tR\x04nameB\a\n" +
+	"\x05value\"H\n" +
+	"\fReverseQuery\x12\x10\n" +
+	"\x03phi\x18\x01 \x01(\x01R\x03phi\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\"-\n" +
+	"\vQueryReturn\x12\x10\n" +
+	"\x03phi\x18\x01 \x01(\x03R\x03phi\x12\f\n" +
+	"\x01N\x18\x02 \x01(\x03R\x01N\"$\n" +
+	"\n" +
+	"MergeReply\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\x03R\x06status\"\xbd\x01\n" +
+	"\vPlotRequest\x12\x18\n" +
+	"\anumBins\x18\x01 \x01(\x03R\anumBins\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x16\n" +
+	"\x06splits\x18\x04 \x03(\x01R\x06splits\x12\x1a\n" +
+	"\brangeMin\x18\x05 \x01(\x01R\brangeMin\x12\x1a\n" +
+	"\brangeMax\x18\x06 \x01(\x01R\brangeMax\x12\x1c\n" +
+	"\tequiDepth\x18\a \x01(\bR\tequiDepth\"^\n" +
+	"\fPlotKllReply\x12\x12\n" +
+	"\x04step\x18\x01 \x01(\x01R\x04step\x12\x10\n" +
+	"\x03pmf\x18\x02 \x03(\x01R\x03pmf\x12\x14\n" +
+	"\x05edges\x18\x03 \x03(\x01R\x05edges\x12\x12\n" +
+	"\x04mass\x18\x04 \x03(\x01R\x04mass\"\x0e\n" +
+	"\fEmptyMessage\"(\n" +
+	"\x0eRestartMessage\x12\x16\n" +
+	"\x06numMsg\x18\x01 \x01(\x03R\x06numMsg\"\x92\x01\n" +
+	"\aASketch\x121\n" +
+	"\x06filter\x18\x01 \x03(\v2\x19.proto.ASketchFilterEntryR\x06filter\x12,\n" +
+	"\tcount_min\x18\x02 \x01(\v2\x0f.proto.CountMinR\bcountMin\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x12\n" +
+	"\x04name\x18\x04 \x01(\tR\x04name\"a\n" +
+	"\x12ASketchFilterEntry\x12'\n" +
+	"\x04item\x18\x01 \x01(\v2\x13.proto.NumericValueR\x04item\x12\x10\n" +
+	"\x03old\x18\x02 \x01(\x03R\x03old\x12\x10\n" +
+	"\x03new\x18\x03 \x01(\x03R\x03new\"]\n" +
+	"\bBufBatch\x12)\n" +
+	"\x05items\x18\x01 \x03(\v2\x13.proto.NumericValueR\x05items\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\"C\n" +
+	"\bCountMin\x12!\n" +
+	"\x04rows\x18\x01 \x03(\v2\r.proto.IntRowR\x04rows\x12\x14\n" +
+	"\x05seeds\x18\x02 \x03(\rR\x05seeds\"C\n" +
+	"\vTopKRequest\x12\f\n" +
+	"\x01k\x18\x01 \x01(\rR\x01k\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\"M\n" +
+	"\tTopKEntry\x12%\n" +
+	"\x03key\x18\x01 \x01(\v2\x13.proto.NumericValueR\x03key\x12\x19\n" +
+	"\best_freq\x18\x02 \x01(\x03R\aestFreq\"7\n" +
+	"\tTopKReply\x12*\n" +
+	"\aentries\x18\x01 \x03(\v2\x10.proto.TopKEntryR\aentries\"'\n" +
+	"\x11ListFieldsRequest\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\"5\n" +
+	"\tFieldInfo\x12\x14\n" +
+	"\x05field\x18\x01 \x01(\tR\x05field\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\";\n" +
+	"\x0fListFieldsReply\x12(\n" +
+	"\x06fields\x18\x01 \x03(\v2\x10.proto.FieldInfoR\x06fields\"m\n" +
+	"\tHLLSketch\x12\x1c\n" +
+	"\tregisters\x18\x01 \x03(\x03R\tregisters\x12\f\n" +
+	"\x01h\x18\x02 \x01(\rR\x01h\x12\f\n" +
+	"\x01g\x18\x03 \x01(\rR\x01g\x12\x12\n" +
+	"\x04type\x18\x04 \x01(\tR\x04type\x12\x12\n" +
+	"\x04name\x18\x05 \x01(\tR\x04name\"<\n" +
+	"\x12CardinalityRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\".\n" +
+	"\x10CardinalityReply\x12\x1a\n" +
+	"\bestimate\x18\x01 \x01(\x01R\bestimate\";\n" +
+	"\x11DumpFilterRequest\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\"F\n" +
+	"\x0fDumpFilterReply\x123\n" +
+	"\aentries\x18\x01 \x03(\v2\x19.proto.ASketchFilterEntryR\aentries\"\xa9\x01\n" +
+	"\vServerState\x12\"\n" +
+	"\x03kll\x18\x01 \x03(\v2\x10.proto.KLLSketchR\x03kll\x12(\n" +
+	"\x05count\x18\x02 \x03(\v2\x12.proto.CountSketchR\x05count\x12\"\n" +
+	"\x03hll\x18\x03 \x03(\v2\x10.proto.HLLSketchR\x03hll\x12(\n" +
+	"\aasketch\x18\x04 \x03(\v2\x0e.proto.ASketchR\aasketch\"]\n" +
+	"\rExportRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04kind\x18\x02 \x01(\tR\x04kind\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x10\n" +
+	"\x03all\x18\x04 \x01(\bR\x03all\"u\n" +
+	"\rImportRequest\x12.\n" +
+	"\bsketches\x18\x01 \x01(\v2\x12.proto.ServerStateR\bsketches\x12\x12\n" +
+	"\x04mode\x18\x02 \x01(\tR\x04mode\x12\x17\n" +
+	"\x04name\x18\x03 \x01(\tH\x00R\x04name\x88\x01\x01B\a\n" +
+	"\x05_name\")\n" +
+	"\vImportReply\x12\x1a\n" +
+	"\bimported\x18\x01 \x01(\x03R\bimported\"_\n" +
+	"\x0fDescribeRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04kind\x18\x02 \x01(\tR\x04kind\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x10\n" +
+	"\x03all\x18\x04 \x01(\bR\x03all\"\xd5\x02\n" +
+	"\n" +
+	"SketchInfo\x12\x12\n" +
+	"\x04kind\x18\x01 \x01(\tR\x04kind\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x125\n" +
+	"\x06params\x18\x04 \x03(\v2\x1d.proto.SketchInfo.ParamsEntryR\x06params\x12\f\n" +
+	"\x01n\x18\x05 \x01(\x03R\x01n\x12!\n" +
+	"\fmemory_bytes\x18\x06 \x01(\x03R\vmemoryBytes\x12\x16\n" +
+	"\x06merges\x18\a \x01(\x03R\x06merges\x12/\n" +
+	"\x14last_merge_unix_nano\x18\b \x01(\x03R\x11lastMergeUnixNano\x12\x1f\n" +
+	"\vlevel_sizes\x18\t \x03(\x03R\n" +
+	"levelSizes\x1a9\n" +
+	"\vParamsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\">\n" +
+	"\rDescribeReply\x12-\n" +
+	"\bsketches\x18\x01 \x03(\v2\x11.proto.SketchInfoR\bsketches2\xd0\t\n" +
+	"\bSketcher\x121\n" +
+	"\bMergeKll\x12\x10.proto.KLLSketch\x1a\x11.proto.MergeReply\"\x00\x125\n" +
+	"\bQueryKll\x12\x13.proto.NumericValue\x1a\x12.proto.QueryReturn\"\x00\x12=\n" +
+	"\x0fReverseQueryKll\x12\x13.proto.ReverseQuery\x1a\x13.proto.NumericValue\"\x00\x124\n" +
+	"\aPlotKll\x12\x12.proto.PlotRequest\x1a\x13.proto.PlotKllReply\"\x00\x125\n" +
+	"\n" +
+	"MergeCount\x12\x12.proto.CountSketch\x1a\x11.proto.MergeReply\"\x00\x12;\n" +
+	"\n" +
+	"QueryCount\x12\x13.proto.NumericValue\x1a\x16.proto.CountQueryReply\"\x00\x129\n" +
+	"\vTestLatency\x12\x13.proto.EmptyMessage\x1a\x13.proto.EmptyMessage\"\x00\x12.\n" +
+	"\x06BadKll\x12\x0f.proto.BadArray\x1a\x11.proto.MergeReply\"\x00\x120\n" +
+	"\bBadCount\x12\x0f.proto.BadArray\x1a\x11.proto.MergeReply\"\x00\x123\n" +
+	"\fMergeASketch\x12\x0e.proto.ASketch\x1a\x11.proto.MergeReply\"\x00\x12=\n" +
+	"\fQueryASketch\x12\x13.proto.NumericValue\x1a\x16.proto.CountQueryReply\"\x00\x12=\n" +
+	"\rRestartServer\x12\x15.proto.RestartMessage\x1a\x13.proto.EmptyMessage\"\x00\x123\n" +
+	"\vTopKASketch\x12\x12.proto.TopKRequest\x1a\x10.proto.TopKReply\x12>\n" +
+	"\n" +
+	"DumpFilter\x12\x18.proto.DumpFilterRequest\x1a\x16.proto.DumpFilterReply\x12;\n" +
+	"\x13MergeBufIntoASketch\x12\x0f.proto.BufBatch\x1a\x11.proto.MergeReply\"\x00\x12@\n" +
+	"\n" +
+	"ListFields\x12\x18.proto.ListFieldsRequest\x1a\x16.proto.ListFieldsReply\"\x00\x121\n" +
+	"\bMergeHll\x12\x10.proto.HLLSketch\x1a\x11.proto.MergeReply\"\x00\x12@\n" +
+	"\bQueryHll\x12\x19.proto.CardinalityRequest\x1a\x17.proto.CardinalityReply\"\x00\x12:\n" +
+	"\fExportSketch\x12\x14.proto.ExportRequest\x1a\x12.proto.ServerState\"\x00\x12:\n" +
+	"\fImportSketch\x12\x14.proto.ImportRequest\x1a\x12.proto.ImportReply\"\x00\x12@\n" +
+	"\x0eDescribeSketch\x12\x16.proto.DescribeRequest\x1a\x14.proto.DescribeReply\"\x00B/Z-github.com/bruhng/distributed-sketching/protob\x06proto3"
 
 var (
 	file_sketch_proto_rawDescOnce sync.Once
@@ -1329,7 +2309,7 @@ func file_sketch_proto_rawDescGZIP() []byte {
 	return file_sketch_proto_rawDescData
 }
 
-var file_sketch_proto_msgTypes = make([]protoimpl.MessageInfo, 23)
+var file_sketch_proto_msgTypes = make([]protoimpl.MessageInfo, 37)
 var file_sketch_proto_goTypes = []any{
 	(*CountSketch)(nil),        // 0: proto.CountSketch
 	(*IntRow)(nil),             // 1: proto.IntRow
@@ -1352,8 +2332,22 @@ var file_sketch_proto_goTypes = []any{
 	(*TopKRequest)(nil),        // 18: proto.TopKRequest
 	(*TopKEntry)(nil),          // 19: proto.TopKEntry
 	(*TopKReply)(nil),          // 20: proto.TopKReply
-	(*DumpFilterRequest)(nil),  // 21: proto.DumpFilterRequest
-	(*DumpFilterReply)(nil),    // 22: proto.DumpFilterReply
+	(*ListFieldsRequest)(nil),  // 21: proto.ListFieldsRequest
+	(*FieldInfo)(nil),          // 22: proto.FieldInfo
+	(*ListFieldsReply)(nil),    // 23: proto.ListFieldsReply
+	(*HLLSketch)(nil),          // 24: proto.HLLSketch
+	(*CardinalityRequest)(nil), // 25: proto.CardinalityRequest
+	(*CardinalityReply)(nil),   // 26: proto.CardinalityReply
+	(*DumpFilterRequest)(nil),  // 27: proto.DumpFilterRequest
+	(*DumpFilterReply)(nil),    // 28: proto.DumpFilterReply
+	(*ServerState)(nil),        // 29: proto.ServerState
+	(*ExportRequest)(nil),      // 30: proto.ExportRequest
+	(*ImportRequest)(nil),      // 31: proto.ImportRequest
+	(*ImportReply)(nil),        // 32: proto.ImportReply
+	(*DescribeRequest)(nil),    // 33: proto.DescribeRequest
+	(*SketchInfo)(nil),         // 34: proto.SketchInfo
+	(*DescribeReply)(nil),      // 35: proto.DescribeReply
+	nil,                        // 36: proto.SketchInfo.ParamsEntry
 }
 var file_sketch_proto_depIdxs = []int32{
 	1,  // 0: proto.CountSketch.rows:type_name -> proto.IntRow
@@ -1367,42 +2361,62 @@ var file_sketch_proto_depIdxs = []int32{
 	1,  // 8: proto.CountMin.rows:type_name -> proto.IntRow
 	6,  // 9: proto.TopKEntry.key:type_name -> proto.NumericValue
 	19, // 10: proto.TopKReply.entries:type_name -> proto.TopKEntry
-	15, // 11: proto.DumpFilterReply.entries:type_name -> proto.ASketchFilterEntry
-	3,  // 12: proto.Sketcher.MergeKll:input_type -> proto.KLLSketch
-	6,  // 13: proto.Sketcher.QueryKll:input_type -> proto.NumericValue
-	7,  // 14: proto.Sketcher.ReverseQueryKll:input_type -> proto.ReverseQuery
-	10, // 15: proto.Sketcher.PlotKll:input_type -> proto.PlotRequest
-	0,  // 16: proto.Sketcher.MergeCount:input_type -> proto.CountSketch
-	6,  // 17: proto.Sketcher.QueryCount:input_type -> proto.NumericValue
-	12, // 18: proto.Sketcher.TestLatency:input_type -> proto.EmptyMessage
-	4,  // 19: proto.Sketcher.BadKll:input_type -> proto.BadArray
-	4,  // 20: proto.Sketcher.BadCount:input_type -> proto.BadArray
-	14, // 21: proto.Sketcher.MergeASketch:input_type -> proto.ASketch
-	6,  // 22: proto.Sketcher.QueryASketch:input_type -> proto.NumericValue
-	13, // 23: proto.Sketcher.RestartServer:input_type -> proto.RestartMessage
-	18, // 24: proto.Sketcher.TopKASketch:input_type -> proto.TopKRequest
-	21, // 25: proto.Sketcher.DumpFilter:input_type -> proto.DumpFilterRequest
-	16, // 26: proto.Sketcher.MergeBufIntoASketch:input_type -> proto.BufBatch
-	9,  // 27: proto.Sketcher.MergeKll:output_type -> proto.MergeReply
-	8,  // 28: proto.Sketcher.QueryKll:output_type -> proto.QueryReturn
-	6,  // 29: proto.Sketcher.ReverseQueryKll:output_type -> proto.NumericValue
-	11, // 30: proto.Sketcher.PlotKll:output_type -> proto.PlotKllReply
-	9,  // 31: proto.Sketcher.MergeCount:output_type -> proto.MergeReply
-	2,  // 32: proto.Sketcher.QueryCount:output_type -> proto.CountQueryReply
-	12, // 33: proto.Sketcher.TestLatency:output_type -> proto.EmptyMessage
-	9,  // 34: proto.Sketcher.BadKll:output_type -> proto.MergeReply
-	9,  // 35: proto.Sketcher.BadCount:output_type -> proto.MergeReply
-	9,  // 36: proto.Sketcher.MergeASketch:output_type -> proto.MergeReply
-	2,  // 37: proto.Sketcher.QueryASketch:output_type -> proto.CountQueryReply
-	12, // 38: proto.Sketcher.RestartServer:output_type -> proto.EmptyMessage
-	20, // 39: proto.Sketcher.TopKASketch:output_type -> proto.TopKReply
-	22, // 40: proto.Sketcher.DumpFilter:output_type -> proto.DumpFilterReply
-	9,  // 41: proto.Sketcher.MergeBufIntoASketch:output_type -> proto.MergeReply
-	27, // [27:42] is the sub-list for method output_type
-	12, // [12:27] is the sub-list for method input_type
-	12, // [12:12] is the sub-list for extension type_name
-	12, // [12:12] is the sub-list for extension extendee
-	0,  // [0:12] is the sub-list for field type_name
+	22, // 11: proto.ListFieldsReply.fields:type_name -> proto.FieldInfo
+	15, // 12: proto.DumpFilterReply.entries:type_name -> proto.ASketchFilterEntry
+	3,  // 13: proto.ServerState.kll:type_name -> proto.KLLSketch
+	0,  // 14: proto.ServerState.count:type_name -> proto.CountSketch
+	24, // 15: proto.ServerState.hll:type_name -> proto.HLLSketch
+	14, // 16: proto.ServerState.asketch:type_name -> proto.ASketch
+	29, // 17: proto.ImportRequest.sketches:type_name -> proto.ServerState
+	36, // 18: proto.SketchInfo.params:type_name -> proto.SketchInfo.ParamsEntry
+	34, // 19: proto.DescribeReply.sketches:type_name -> proto.SketchInfo
+	3,  // 20: proto.Sketcher.MergeKll:input_type -> proto.KLLSketch
+	6,  // 21: proto.Sketcher.QueryKll:input_type -> proto.NumericValue
+	7,  // 22: proto.Sketcher.ReverseQueryKll:input_type -> proto.ReverseQuery
+	10, // 23: proto.Sketcher.PlotKll:input_type -> proto.PlotRequest
+	0,  // 24: proto.Sketcher.MergeCount:input_type -> proto.CountSketch
+	6,  // 25: proto.Sketcher.QueryCount:input_type -> proto.NumericValue
+	12, // 26: proto.Sketcher.TestLatency:input_type -> proto.EmptyMessage
+	4,  // 27: proto.Sketcher.BadKll:input_type -> proto.BadArray
+	4,  // 28: proto.Sketcher.BadCount:input_type -> proto.BadArray
+	14, // 29: proto.Sketcher.MergeASketch:input_type -> proto.ASketch
+	6,  // 30: proto.Sketcher.QueryASketch:input_type -> proto.NumericValue
+	13, // 31: proto.Sketcher.RestartServer:input_type -> proto.RestartMessage
+	18, // 32: proto.Sketcher.TopKASketch:input_type -> proto.TopKRequest
+	27, // 33: proto.Sketcher.DumpFilter:input_type -> proto.DumpFilterRequest
+	16, // 34: proto.Sketcher.MergeBufIntoASketch:input_type -> proto.BufBatch
+	21, // 35: proto.Sketcher.ListFields:input_type -> proto.ListFieldsRequest
+	24, // 36: proto.Sketcher.MergeHll:input_type -> proto.HLLSketch
+	25, // 37: proto.Sketcher.QueryHll:input_type -> proto.CardinalityRequest
+	30, // 38: proto.Sketcher.ExportSketch:input_type -> proto.ExportRequest
+	31, // 39: proto.Sketcher.ImportSketch:input_type -> proto.ImportRequest
+	33, // 40: proto.Sketcher.DescribeSketch:input_type -> proto.DescribeRequest
+	9,  // 41: proto.Sketcher.MergeKll:output_type -> proto.MergeReply
+	8,  // 42: proto.Sketcher.QueryKll:output_type -> proto.QueryReturn
+	6,  // 43: proto.Sketcher.ReverseQueryKll:output_type -> proto.NumericValue
+	11, // 44: proto.Sketcher.PlotKll:output_type -> proto.PlotKllReply
+	9,  // 45: proto.Sketcher.MergeCount:output_type -> proto.MergeReply
+	2,  // 46: proto.Sketcher.QueryCount:output_type -> proto.CountQueryReply
+	12, // 47: proto.Sketcher.TestLatency:output_type -> proto.EmptyMessage
+	9,  // 48: proto.Sketcher.BadKll:output_type -> proto.MergeReply
+	9,  // 49: proto.Sketcher.BadCount:output_type -> proto.MergeReply
+	9,  // 50: proto.Sketcher.MergeASketch:output_type -> proto.MergeReply
+	2,  // 51: proto.Sketcher.QueryASketch:output_type -> proto.CountQueryReply
+	12, // 52: proto.Sketcher.RestartServer:output_type -> proto.EmptyMessage
+	20, // 53: proto.Sketcher.TopKASketch:output_type -> proto.TopKReply
+	28, // 54: proto.Sketcher.DumpFilter:output_type -> proto.DumpFilterReply
+	9,  // 55: proto.Sketcher.MergeBufIntoASketch:output_type -> proto.MergeReply
+	23, // 56: proto.Sketcher.ListFields:output_type -> proto.ListFieldsReply
+	9,  // 57: proto.Sketcher.MergeHll:output_type -> proto.MergeReply
+	26, // 58: proto.Sketcher.QueryHll:output_type -> proto.CardinalityReply
+	29, // 59: proto.Sketcher.ExportSketch:output_type -> proto.ServerState
+	32, // 60: proto.Sketcher.ImportSketch:output_type -> proto.ImportReply
+	35, // 61: proto.Sketcher.DescribeSketch:output_type -> proto.DescribeReply
+	41, // [41:62] is the sub-list for method output_type
+	20, // [20:41] is the sub-list for method input_type
+	20, // [20:20] is the sub-list for extension type_name
+	20, // [20:20] is the sub-list for extension extendee
+	0,  // [0:20] is the sub-list for field type_name
 }
 
 func init() { file_sketch_proto_init() }
@@ -1414,13 +2428,14 @@ func file_sketch_proto_init() {
 		(*NumericValue_IntVal)(nil),
 		(*NumericValue_FloatVal)(nil),
 	}
+	file_sketch_proto_msgTypes[31].OneofWrappers = []any{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_sketch_proto_rawDesc), len(file_sketch_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   23,
+			NumMessages:   37,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
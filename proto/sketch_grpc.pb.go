@@ -34,6 +34,12 @@ const (
 	Sketcher_TopKASketch_FullMethodName         = "/proto.Sketcher/TopKASketch"
 	Sketcher_DumpFilter_FullMethodName          = "/proto.Sketcher/DumpFilter"
 	Sketcher_MergeBufIntoASketch_FullMethodName = "/proto.Sketcher/MergeBufIntoASketch"
+	Sketcher_ListFields_FullMethodName          = "/proto.Sketcher/ListFields"
+	Sketcher_MergeHll_FullMethodName            = "/proto.Sketcher/MergeHll"
+	Sketcher_QueryHll_FullMethodName            = "/proto.Sketcher/QueryHll"
+	Sketcher_ExportSketch_FullMethodName        = "/proto.Sketcher/ExportSketch"
+	Sketcher_ImportSketch_FullMethodName        = "/proto.Sketcher/ImportSketch"
+	Sketcher_DescribeSketch_FullMethodName      = "/proto.Sketcher/DescribeSketch"
 )
 
 // SketcherClient is the client API for Sketcher service.
@@ -56,6 +62,16 @@ type SketcherClient interface {
 	TopKASketch(ctx context.Context, in *TopKRequest, opts ...grpc.CallOption) (*TopKReply, error)
 	DumpFilter(ctx context.Context, in *DumpFilterRequest, opts ...grpc.CallOption) (*DumpFilterReply, error)
 	MergeBufIntoASketch(ctx context.Context, in *BufBatch, opts ...grpc.CallOption) (*MergeReply, error)
+	// Lists the fields the server keeps an ASketch for
+	ListFields(ctx context.Context, in *ListFieldsRequest, opts ...grpc.CallOption) (*ListFieldsReply, error)
+	MergeHll(ctx context.Context, in *HLLSketch, opts ...grpc.CallOption) (*MergeReply, error)
+	QueryHll(ctx context.Context, in *CardinalityRequest, opts ...grpc.CallOption) (*CardinalityReply, error)
+	// Copies sketches out of the server, in the format of the state file
+	ExportSketch(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (*ServerState, error)
+	// Merges sketches into the server or replaces its sketches with them
+	ImportSketch(ctx context.Context, in *ImportRequest, opts ...grpc.CallOption) (*ImportReply, error)
+	// Describes the sketches the server keeps under a name
+	DescribeSketch(ctx context.Context, in *DescribeRequest, opts ...grpc.CallOption) (*DescribeReply, error)
 }
 
 type sketcherClient struct {
@@ -216,6 +232,66 @@ func (c *sketcherClient) MergeBufIntoASketch(ctx context.Context, in *BufBatch,
 	return out, nil
 }
 
+func (c *sketcherClient) ListFields(ctx context.Context, in *ListFieldsRequest, opts ...grpc.CallOption) (*ListFieldsReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListFieldsReply)
+	err := c.cc.Invoke(ctx, Sketcher_ListFields_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sketcherClient) MergeHll(ctx context.Context, in *HLLSketch, opts ...grpc.CallOption) (*MergeReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MergeReply)
+	err := c.cc.Invoke(ctx, Sketcher_MergeHll_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sketcherClient) QueryHll(ctx context.Context, in *CardinalityRequest, opts ...grpc.CallOption) (*CardinalityReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CardinalityReply)
+	err := c.cc.Invoke(ctx, Sketcher_QueryHll_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sketcherClient) ExportSketch(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (*ServerState, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ServerState)
+	err := c.cc.Invoke(ctx, Sketcher_ExportSketch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sketcherClient) ImportSketch(ctx context.Context, in *ImportRequest, opts ...grpc.CallOption) (*ImportReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ImportReply)
+	err := c.cc.Invoke(ctx, Sketcher_ImportSketch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sketcherClient) DescribeSketch(ctx context.Context, in *DescribeRequest, opts ...grpc.CallOption) (*DescribeReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DescribeReply)
+	err := c.cc.Invoke(ctx, Sketcher_DescribeSketch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // SketcherServer is the server API for Sketcher service.
 // All implementations must embed UnimplementedSketcherServer
 // for forward compatibility.
@@ -236,6 +312,16 @@ type SketcherServer interface {
 	TopKASketch(context.Context, *TopKRequest) (*TopKReply, error)
 	DumpFilter(context.Context, *DumpFilterRequest) (*DumpFilterReply, error)
 	MergeBufIntoASketch(context.Context, *BufBatch) (*MergeReply, error)
+	// Lists the fields the server keeps an ASketch for
+	ListFields(context.Context, *ListFieldsRequest) (*ListFieldsReply, error)
+	MergeHll(context.Context, *HLLSketch) (*MergeReply, error)
+	QueryHll(context.Context, *CardinalityRequest) (*CardinalityReply, error)
+	// Copies sketches out of the server, in the format of the state file
+	ExportSketch(context.Context, *ExportRequest) (*ServerState, error)
+	// Merges sketches into the server or replaces its sketches with them
+	ImportSketch(context.Context, *ImportRequest) (*ImportReply, error)
+	// Describes the sketches the server keeps under a name
+	DescribeSketch(context.Context, *DescribeRequest) (*DescribeReply, error)
 	mustEmbedUnimplementedSketcherServer()
 }
 
@@ -291,6 +377,24 @@ func (UnimplementedSketcherServer) DumpFilter(context.Context, *DumpFilterReques
 func (UnimplementedSketcherServer) MergeBufIntoASketch(context.Context, *BufBatch) (*MergeReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method MergeBufIntoASketch not implemented")
 }
+func (UnimplementedSketcherServer) ListFields(context.Context, *ListFieldsRequest) (*ListFieldsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFields not implemented")
+}
+func (UnimplementedSketcherServer) MergeHll(context.Context, *HLLSketch) (*MergeReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MergeHll not implemented")
+}
+func (UnimplementedSketcherServer) QueryHll(context.Context, *CardinalityRequest) (*CardinalityReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryHll not implemented")
+}
+func (UnimplementedSketcherServer) ExportSketch(context.Context, *ExportRequest) (*ServerState, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExportSketch not implemented")
+}
+func (UnimplementedSketcherServer) ImportSketch(context.Context, *ImportRequest) (*ImportReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ImportSketch not implemented")
+}
+func (UnimplementedSketcherServer) DescribeSketch(context.Context, *DescribeRequest) (*DescribeReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DescribeSketch not implemented")
+}
 func (UnimplementedSketcherServer) mustEmbedUnimplementedSketcherServer() {}
 func (UnimplementedSketcherServer) testEmbeddedByValue()                  {}
 
@@ -582,6 +686,114 @@ func _Sketcher_MergeBufIntoASketch_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Sketcher_ListFields_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFieldsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SketcherServer).ListFields(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sketcher_ListFields_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SketcherServer).ListFields(ctx, req.(*ListFieldsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sketcher_MergeHll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HLLSketch)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SketcherServer).MergeHll(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sketcher_MergeHll_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SketcherServer).MergeHll(ctx, req.(*HLLSketch))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sketcher_QueryHll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CardinalityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SketcherServer).QueryHll(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sketcher_QueryHll_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SketcherServer).QueryHll(ctx, req.(*CardinalityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sketcher_ExportSketch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SketcherServer).ExportSketch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sketcher_ExportSketch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SketcherServer).ExportSketch(ctx, req.(*ExportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sketcher_ImportSketch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SketcherServer).ImportSketch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sketcher_ImportSketch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SketcherServer).ImportSketch(ctx, req.(*ImportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sketcher_DescribeSketch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DescribeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SketcherServer).DescribeSketch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sketcher_DescribeSketch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SketcherServer).DescribeSketch(ctx, req.(*DescribeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Sketcher_ServiceDesc is the grpc.ServiceDesc for Sketcher service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -649,6 +861,30 @@ var Sketcher_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "MergeBufIntoASketch",
 			Handler:    _Sketcher_MergeBufIntoASketch_Handler,
 		},
+		{
+			MethodName: "ListFields",
+			Handler:    _Sketcher_ListFields_Handler,
+		},
+		{
+			MethodName: "MergeHll",
+			Handler:    _Sketcher_MergeHll_Handler,
+		},
+		{
+			MethodName: "QueryHll",
+			Handler:    _Sketcher_QueryHll_Handler,
+		},
+		{
+			MethodName: "ExportSketch",
+			Handler:    _Sketcher_ExportSketch_Handler,
+		},
+		{
+			MethodName: "ImportSketch",
+			Handler:    _Sketcher_ImportSketch_Handler,
+		},
+		{
+			MethodName: "DescribeSketch",
+			Handler:    _Sketcher_DescribeSketch_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "sketch.proto",
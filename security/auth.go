@@ -0,0 +1,192 @@
+package security
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Role is what a token may do. Every role includes the ones before it, except
+// that producers can not query and consumers can not merge.
+type Role int
+
+const (
+	// Producer merges sketches.
+	Producer Role = iota
+	// Consumer queries sketches.
+	Consumer
+	// Admin may do anything, including restarting the server.
+	Admin
+)
+
+func ParseRole(s string) (Role, error) {
+	switch s {
+	case "producer":
+		return Producer, nil
+	case "consumer":
+		return Consumer, nil
+	case "admin":
+		return Admin, nil
+	}
+	return 0, fmt.Errorf("%s is not supported, please submit a valid role", s)
+}
+
+func (r Role) String() string {
+	switch r {
+	case Consumer:
+		return "consumer"
+	case Admin:
+		return "admin"
+	}
+	return "producer"
+}
+
+const (
+	// UnnamedSketch stands for the sketch clients use when they do not name
+	// theirs in access lists.
+	UnnamedSketch = "_"
+	// AnySketch skips the access list in Authorize, for requests that are not
+	// about one sketch.
+	AnySketch = "*"
+	// EverySketch is for requests about all sketches, only tokens without an
+	// access list may make them.
+	EverySketch = "**"
+)
+
+// Principal is who a token belongs to.
+type Principal struct {
+	Role Role
+	// Sketches the token may access, nil means all of them.
+	Sketches map[string]bool
+}
+
+// Tokens maps a secret token to its principal.
+type Tokens map[string]Principal
+
+var (
+	ErrUnauthenticated  = errors.New("missing or unknown token")
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+// Authenticate checks that token is known.
+func (t Tokens) Authenticate(token string) error {
+	if _, ok := t[token]; !ok || token == "" {
+		return ErrUnauthenticated
+	}
+	return nil
+}
+
+// Authorize checks that token has role and may access sketch. Admin tokens
+// pass every check.
+func (t Tokens) Authorize(token string, role Role, sketch string) error {
+	p, ok := t[token]
+	if !ok || token == "" {
+		return ErrUnauthenticated
+	}
+	if p.Role == Admin {
+		return nil
+	}
+	if p.Role != role {
+		return fmt.Errorf("%w: a %s token can not act as %s", ErrPermissionDenied, p.Role, role)
+	}
+	if sketch == "" {
+		sketch = UnnamedSketch
+	}
+	if sketch == EverySketch && p.Sketches != nil {
+		return fmt.Errorf("%w: no access to every sketch", ErrPermissionDenied)
+	}
+	if sketch != AnySketch && sketch != EverySketch && p.Sketches != nil && !p.Sketches[sketch] {
+		return fmt.Errorf("%w: no access to sketch %s", ErrPermissionDenied, sketch)
+	}
+	return nil
+}
+
+// ParseTokens reads one token per line as "token role [sketch,sketch,...]".
+// Without sketches, or with *, the token may access every sketch. Empty lines
+// and lines starting with # are ignored.
+func ParseTokens(r io.Reader) (Tokens, error) {
+	tokens := Tokens{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("line %d: want token, role and optionally sketches", line)
+		}
+		role, err := ParseRole(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		p := Principal{Role: role}
+		if len(fields) == 3 && fields[2] != "*" {
+			p.Sketches = map[string]bool{}
+			for _, name := range strings.Split(fields[2], ",") {
+				p.Sketches[name] = true
+			}
+		}
+		if _, dup := tokens[fields[0]]; dup {
+			return nil, fmt.Errorf("line %d: duplicate token", line)
+		}
+		tokens[fields[0]] = p
+	}
+	return tokens, scanner.Err()
+}
+
+func LoadTokens(path string) (Tokens, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseTokens(f)
+}
+
+// Server side tokens, nil disables authentication. Token is what this process
+// sends as a client.
+var (
+	ServerTokens Tokens
+	Token        string
+	tokensFile   string
+)
+
+// Load reads the files named by the flags, call it after flag.Parse.
+func Load() error {
+	if tokensFile == "" {
+		return nil
+	}
+	tokens, err := LoadTokens(tokensFile)
+	if err != nil {
+		return err
+	}
+	ServerTokens = tokens
+	return nil
+}
+
+// BearerToken extracts the token of an "authorization: Bearer <token>" value.
+func BearerToken(header string) string {
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// tokenCredentials sends Token with every call
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity is false so tokens also work on insecure test
+// setups, use TLS in production.
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
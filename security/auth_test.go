@@ -0,0 +1,59 @@
+package security_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bruhng/distributed-sketching/security"
+)
+
+func TestParseTokens(t *testing.T) {
+	tokens, err := security.ParseTokens(strings.NewReader(`
+# comment
+p1 producer speeds,_
+c1 consumer *
+a1 admin
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		token  string
+		role   security.Role
+		sketch string
+		want   error
+	}{
+		{"p1", security.Producer, "speeds", nil},
+		{"p1", security.Producer, "", nil},
+		{"p1", security.Producer, "other", security.ErrPermissionDenied},
+		{"p1", security.Consumer, "speeds", security.ErrPermissionDenied},
+		{"c1", security.Consumer, "other", nil},
+		{"c1", security.Admin, "other", security.ErrPermissionDenied},
+		{"a1", security.Producer, "other", nil},
+		{"a1", security.Admin, security.AnySketch, nil},
+		{"p1", security.Producer, security.EverySketch, security.ErrPermissionDenied},
+		{"c1", security.Consumer, security.EverySketch, nil},
+		{"nope", security.Consumer, "speeds", security.ErrUnauthenticated},
+		{"", security.Consumer, "speeds", security.ErrUnauthenticated},
+	} {
+		if err := tokens.Authorize(tc.token, tc.role, tc.sketch); !errors.Is(err, tc.want) {
+			t.Errorf("Authorize(%s, %s, %q) = %v, want %v", tc.token, tc.role, tc.sketch, err, tc.want)
+		}
+	}
+
+	for _, bad := range []string{"t1", "t1 root", "t1 admin a b", "t1 admin\nt1 consumer"} {
+		if _, err := security.ParseTokens(strings.NewReader(bad)); err == nil {
+			t.Errorf("ParseTokens(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	if got := security.BearerToken("Bearer abc"); got != "abc" {
+		t.Errorf("got %q", got)
+	}
+	if got := security.BearerToken("Basic abc"); got != "" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,138 @@
+// Package security configures TLS for the gRPC connections between servers,
+// clients and consumers.
+package security
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// TLSConfig holds PEM file paths. A server needs Cert and Key and verifies
+// client certificates against CA when it is set (mutual TLS). A client trusts
+// CA (or the system roots) and presents Cert and Key when they are set.
+type TLSConfig struct {
+	Cert string
+	Key  string
+	CA   string
+	// ServerName overrides the name clients expect in the server certificate.
+	ServerName string
+}
+
+// TLS is the configuration of this process, connections are insecure while it
+// is empty.
+var TLS TLSConfig
+
+// RegisterFlags adds -tlsCert, -tlsKey, -tlsCA and -tlsServerName setting TLS
+// and -token and -tokens for authentication.
+func RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&Token, "token", "", "token sent to the server")
+	fs.StringVar(&tokensFile, "tokens", "", "file of accepted tokens as \"token role [sketch,...]\" lines, enables authentication on the server")
+	fs.StringVar(&TLS.Cert, "tlsCert", "", "PEM certificate of this process, enables TLS")
+	fs.StringVar(&TLS.Key, "tlsKey", "", "PEM private key of -tlsCert")
+	fs.StringVar(&TLS.CA, "tlsCA", "", "PEM CA certificates to trust, on a server this requires client certificates")
+	fs.StringVar(&TLS.ServerName, "tlsServerName", "", "name expected in the server certificate, defaults to the dialed host")
+}
+
+func (c TLSConfig) Enabled() bool {
+	return c.Cert != "" || c.Key != "" || c.CA != ""
+}
+
+func loadPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+func (c TLSConfig) loadCertificate() ([]tls.Certificate, error) {
+	if c.Cert == "" && c.Key == "" {
+		return nil, nil
+	}
+	if c.Cert == "" || c.Key == "" {
+		return nil, fmt.Errorf("a TLS certificate needs both a cert and a key file")
+	}
+	cert, err := tls.LoadX509KeyPair(c.Cert, c.Key)
+	if err != nil {
+		return nil, err
+	}
+	return []tls.Certificate{cert}, nil
+}
+
+// ServerCredentials are insecure unless TLS is configured.
+func (c TLSConfig) ServerCredentials() (credentials.TransportCredentials, error) {
+	cfg, err := c.ServerConfig()
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		return insecure.NewCredentials(), nil
+	}
+	return credentials.NewTLS(cfg), nil
+}
+
+// ServerConfig is the TLS configuration of a server, for gRPC and HTTP alike.
+// It is nil unless TLS is configured.
+func (c TLSConfig) ServerConfig() (*tls.Config, error) {
+	if !c.Enabled() {
+		return nil, nil
+	}
+	certs, err := c.loadCertificate()
+	if err != nil {
+		return nil, err
+	}
+	if certs == nil {
+		return nil, fmt.Errorf("a TLS server needs a certificate")
+	}
+	cfg := &tls.Config{Certificates: certs, MinVersion: tls.VersionTLS12}
+	if c.CA != "" {
+		if cfg.ClientCAs, err = loadPool(c.CA); err != nil {
+			return nil, err
+		}
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
+
+// ClientCredentials are insecure unless TLS is configured.
+func (c TLSConfig) ClientCredentials() (credentials.TransportCredentials, error) {
+	if !c.Enabled() {
+		return insecure.NewCredentials(), nil
+	}
+	certs, err := c.loadCertificate()
+	if err != nil {
+		return nil, err
+	}
+	cfg := &tls.Config{Certificates: certs, ServerName: c.ServerName, MinVersion: tls.VersionTLS12}
+	if c.CA != "" {
+		if cfg.RootCAs, err = loadPool(c.CA); err != nil {
+			return nil, err
+		}
+	}
+	return credentials.NewTLS(cfg), nil
+}
+
+// DialOptions returns the transport credentials of TLS and the Token for
+// grpc.NewClient.
+func DialOptions() ([]grpc.DialOption, error) {
+	creds, err := TLS.ClientCredentials()
+	if err != nil {
+		return nil, err
+	}
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	if Token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCredentials(Token)))
+	}
+	return opts, nil
+}
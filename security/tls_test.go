@@ -0,0 +1,165 @@
+package security_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/security"
+	"google.golang.org/grpc"
+)
+
+type pinger struct {
+	pb.UnimplementedSketcherServer
+}
+
+func (pinger) TestLatency(context.Context, *pb.EmptyMessage) (*pb.EmptyMessage, error) {
+	return &pb.EmptyMessage{}, nil
+}
+
+type certs struct {
+	dir string
+	ca  *x509.Certificate
+	key *ecdsa.PrivateKey
+}
+
+// newCA writes a self signed CA to dir/name.pem
+func newCA(t *testing.T, dir string, name string) *certs {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(der)
+	writePEM(t, filepath.Join(dir, name+".pem"), "CERTIFICATE", der)
+	return &certs{dir: dir, ca: ca, key: key}
+}
+
+// issue writes name.pem and name-key.pem signed by the CA
+func (c *certs) issue(t *testing.T, name string, usage x509.ExtKeyUsage) security.TLSConfig {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, c.ca, &key.PublicKey, c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, _ := x509.MarshalECPrivateKey(key)
+	cfg := security.TLSConfig{Cert: filepath.Join(c.dir, name+".pem"), Key: filepath.Join(c.dir, name+"-key.pem")}
+	writePEM(t, cfg.Cert, "CERTIFICATE", der)
+	writePEM(t, cfg.Key, "EC PRIVATE KEY", keyDer)
+	return cfg
+}
+
+func writePEM(t *testing.T, path string, typ string, der []byte) {
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func serve(t *testing.T, cfg security.TLSConfig) string {
+	creds, err := cfg.ServerCredentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer(grpc.Creds(creds))
+	pb.RegisterSketcherServer(s, pinger{})
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+func ping(t *testing.T, addr string, cfg security.TLSConfig) error {
+	creds, err := cfg.ClientCredentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = pb.NewSketcherClient(conn).TestLatency(ctx, &pb.EmptyMessage{})
+	return err
+}
+
+func TestTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newCA(t, dir, "ca")
+	other := newCA(t, dir, "other-ca")
+	serverCfg := ca.issue(t, "server", x509.ExtKeyUsageServerAuth)
+	clientCfg := ca.issue(t, "client", x509.ExtKeyUsageClientAuth)
+	strangerCfg := other.issue(t, "stranger", x509.ExtKeyUsageClientAuth)
+	caPath := filepath.Join(dir, "ca.pem")
+
+	addr := serve(t, serverCfg)
+	if err := ping(t, addr, security.TLSConfig{CA: caPath}); err != nil {
+		t.Fatalf("TLS: %v", err)
+	}
+	if err := ping(t, addr, security.TLSConfig{CA: filepath.Join(dir, "other-ca.pem")}); err == nil {
+		t.Fatal("a client trusting another CA connected")
+	}
+	if err := ping(t, addr, security.TLSConfig{}); err == nil {
+		t.Fatal("an insecure client connected to a TLS server")
+	}
+
+	serverCfg.CA = caPath
+	mutual := serve(t, serverCfg)
+	clientCfg.CA = caPath
+	if err := ping(t, mutual, clientCfg); err != nil {
+		t.Fatalf("mTLS: %v", err)
+	}
+	if err := ping(t, mutual, security.TLSConfig{CA: caPath}); err == nil {
+		t.Fatal("a client without certificate passed mTLS")
+	}
+	strangerCfg.CA = caPath
+	if err := ping(t, mutual, strangerCfg); err == nil {
+		t.Fatal("a client certificate of another CA passed mTLS")
+	}
+}
+
+func TestBadConfig(t *testing.T) {
+	if _, err := (security.TLSConfig{CA: "missing.pem"}).ServerCredentials(); err == nil {
+		t.Fatal("a server without certificate was accepted")
+	}
+	if _, err := (security.TLSConfig{Cert: "cert.pem"}).ClientCredentials(); err == nil {
+		t.Fatal("a certificate without key was accepted")
+	}
+	if _, err := (security.TLSConfig{CA: "missing.pem"}).ClientCredentials(); err == nil {
+		t.Fatal("a missing CA file was accepted")
+	}
+}
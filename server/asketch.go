@@ -3,128 +3,97 @@ package server
 import (
 	"context"
 	"fmt"
-	"sync"
+	"sort"
 
 	pb "github.com/bruhng/distributed-sketching/proto"
 	"github.com/bruhng/distributed-sketching/shared"
 	"github.com/bruhng/distributed-sketching/sketches/asketch"
+	"github.com/bruhng/distributed-sketching/sketches/convert"
 )
 
-var (
-	asketchStateMap sync.Map
-	asketchMutex    sync.Mutex
-)
-
-func getOrCreateASketchState[T shared.Number](field string) *asketch.ASketch[T] {
-	key := fmt.Sprintf("%s | %T", field, *new(T)) // "int" or "float64"
+// Every field (column or event name) gets its own ASketch per element type
+func getOrCreateASketchState[T shared.Number](s *Server, field string) *asketch.ASketch[T] {
+	key := newSketchKey[T](field)
 
-	if v, ok := asketchStateMap.Load(key); ok {
+	if v, ok := s.asketchStateMap.Load(key); ok {
 		return v.(*asketch.ASketch[T])
 	}
 	sk := asketch.NewASketch[T](shared.ASketchSeed, shared.ASketchWidth, shared.ASketchDepth, shared.ASketchSlots)
-	actual, _ := asketchStateMap.LoadOrStore(key, sk)
+	actual, _ := s.asketchStateMap.LoadOrStore(key, sk)
 	return actual.(*asketch.ASketch[T])
 }
 
-// Convert protobuf ASketch to internal ASketch
-func convertProtoASToAS[T shared.Number](protoData *pb.ASketch) *asketch.ASketch[T] {
-	var filter []asketch.FilterSlot[T]
-	var rows [][]int
-	var seeds []uint32
-
-	// Convert filter entries
-	for _, entry := range protoData.GetFilter() {
-		var item T
-		switch v := entry.GetItem().GetValue().(type) { // oneof
-		case *pb.NumericValue_IntVal:
-			item = T(v.IntVal)
-		case *pb.NumericValue_FloatVal:
-			item = T(v.FloatVal)
-		default:
-			continue
-		}
-
-		filter = append(filter, asketch.FilterSlot[T]{
-			Item: item,
-			Old:  int(entry.Old),
-			New:  int(entry.New),
-		})
-	}
-
-	// Convert CountMin data
-	if cm := protoData.GetCountMin(); cm != nil {
-		for _, row := range cm.GetRows() {
-			intRow := make([]int, 0, len(row.GetVal()))
-			for _, v := range row.GetVal() {
-				intRow = append(intRow, int(v))
-			}
-			rows = append(rows, intRow)
-		}
-		seeds = append(seeds, cm.GetSeeds()...)
-	}
-
-	return asketch.NewASketchFromState(filter, rows, seeds)
+// loadASketchState returns the ASketch of field for queries, without creating
+// one that would then be listed by ListFields
+func loadASketchState[T shared.Number](s *Server, field string) (*asketch.ASketch[T], error) {
+	return loadState[*asketch.ASketch[T], T](&s.asketchStateMap, "asketch", field)
 }
 
 // Merge the incoming ASketch into the server's ASketch state
 func (s *Server) MergeASketch(_ context.Context, in *pb.ASketch) (*pb.MergeReply, error) {
-	fld := ""
-	//fmt.Printf("[SERVER] MergeASketch type=%s filter=%d rows=%d\n", in.GetType(), len(in.GetFilter()), len(in.GetCountMin().GetRows()))
+	fld := in.GetName()
 	switch in.Type {
 	case "int":
-		asketchState := getOrCreateASketchState[int](fld)
-		sketch := convertProtoASToAS[int](in)
-		asketchMutex.Lock()
+		asketchState := getOrCreateASketchState[int](s, fld)
+		sketch := convert.FromProtoASketch[int](in)
+		s.asketchMutex.Lock()
 		asketchState.MergeSketch(sketch)
-		asketchMutex.Unlock()
+		s.asketchMutex.Unlock()
 	case "float64":
-		asketchState := getOrCreateASketchState[float64](fld)
-		sketch := convertProtoASToAS[float64](in)
-		asketchMutex.Lock()
+		asketchState := getOrCreateASketchState[float64](s, fld)
+		sketch := convert.FromProtoASketch[float64](in)
+		s.asketchMutex.Lock()
 		asketchState.MergeSketch(sketch)
-		asketchMutex.Unlock()
-
-		// if len(in.GetFilter()) > 0 {
-		// 	switch v := in.GetFilter()[0].GetItem().GetValue().(type) {
-		// 	case *pb.NumericValue_FloatVal:
-		// 		got := asketchState.Query(v.FloatVal)
-		// 		fmt.Printf("[SERVER][POST-MERGE] value=%.10g -> %d  sketch=%p\n", v.FloatVal, got, asketchState)
-		// 	}
-		// }
-
+		s.asketchMutex.Unlock()
 	default:
 		return nil, fmt.Errorf("%s is not supported, please submit a valid type", in.GetType())
 	}
 
+	s.recordMerge("asketch", fld, in.GetType())
 	return &pb.MergeReply{Status: 0}, nil
 }
 
-// Query the ASketch state for the given value
+// Query the field's ASketch state for the given value
 func (s *Server) QueryASketch(_ context.Context, in *pb.NumericValue) (*pb.CountQueryReply, error) {
+	fld := in.GetName()
 	switch v := in.GetValue().(type) {
 	case *pb.NumericValue_IntVal:
-		asketchState := getOrCreateASketchState[int]("")
+		asketchState, err := loadASketchState[int](s, fld)
+		if err != nil {
+			return nil, err
+		}
+		s.asketchMutex.Lock()
 		ret := asketchState.Query(int(v.IntVal))
-		//fmt.Printf("[SERVER][QUERY] type=int v=%d -> %d sketch=%p\n", v.IntVal, ret, asketchState)
+		s.asketchMutex.Unlock()
 		return &pb.CountQueryReply{Res: int64(ret)}, nil
 
 	case *pb.NumericValue_FloatVal:
-		asketchState := getOrCreateASketchState[float64]("")
+		asketchState, err := loadASketchState[float64](s, fld)
+		if err != nil {
+			return nil, err
+		}
+		s.asketchMutex.Lock()
 		ret := asketchState.Query(v.FloatVal)
-		//fmt.Printf("[SERVER][QUERY] type=float64 v=%.10g -> %d sketch=%p\n", v.FloatVal, ret, asketchState)
+		s.asketchMutex.Unlock()
 		return &pb.CountQueryReply{Res: int64(ret)}, nil
 
 	default:
 		return nil, fmt.Errorf("unsupported NumericValue variant")
 	}
 }
+
 func (s *Server) TopKASketch(_ context.Context, in *pb.TopKRequest) (*pb.TopKReply, error) {
-	fld := ""
+	fld := in.GetName()
 
 	switch in.GetType() {
 	case "int":
-		st := getOrCreateASketchState[int](fld)
+		st, err := loadASketchState[int](s, fld)
+		if err != nil {
+			return nil, err
+		}
+		s.asketchMutex.Lock()
 		slots := st.TopK(int(in.GetK()))
+		s.asketchMutex.Unlock()
 		out := &pb.TopKReply{Entries: make([]*pb.TopKEntry, len(slots))}
 		for i, sl := range slots {
 			out.Entries[i] = &pb.TopKEntry{
@@ -135,8 +104,13 @@ func (s *Server) TopKASketch(_ context.Context, in *pb.TopKRequest) (*pb.TopKRep
 		return out, nil
 
 	case "float64":
-		st := getOrCreateASketchState[float64](fld)
+		st, err := loadASketchState[float64](s, fld)
+		if err != nil {
+			return nil, err
+		}
+		s.asketchMutex.Lock()
 		slots := st.TopK(int(in.GetK()))
+		s.asketchMutex.Unlock()
 		out := &pb.TopKReply{Entries: make([]*pb.TopKEntry, len(slots))}
 		for i, sl := range slots {
 			out.Entries[i] = &pb.TopKEntry{
@@ -150,3 +124,22 @@ func (s *Server) TopKASketch(_ context.Context, in *pb.TopKRequest) (*pb.TopKRep
 		return nil, fmt.Errorf("%s is not supported, please submit a valid type", in.GetType())
 	}
 }
+
+// List every field that has an ASketch, optionally only those of one type
+func (s *Server) ListFields(_ context.Context, in *pb.ListFieldsRequest) (*pb.ListFieldsReply, error) {
+	out := &pb.ListFieldsReply{}
+	s.asketchStateMap.Range(func(k, _ any) bool {
+		key := k.(sketchKey)
+		if in.GetType() == "" || in.GetType() == key.typ {
+			out.Fields = append(out.Fields, &pb.FieldInfo{Field: key.name, Type: key.typ})
+		}
+		return true
+	})
+	sort.Slice(out.Fields, func(i, j int) bool {
+		if out.Fields[i].Field != out.Fields[j].Field {
+			return out.Fields[i].Field < out.Fields[j].Field
+		}
+		return out.Fields[i].Type < out.Fields[j].Type
+	})
+	return out, nil
+}
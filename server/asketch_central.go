@@ -29,25 +29,26 @@ func convertProtoBufToBuf[T shared.Number](protoData *pb.BufBatch) []T {
 	return localBuf
 }
 
-// Merge the incoming Buf into the server's ASketch state
+// Merge the incoming Buf into the ASketch state of its field
 func (s *Server) MergeBufIntoASketch(_ context.Context, in *pb.BufBatch) (*pb.MergeReply, error) {
 	//fmt.Printf("[SERVER] MergeASketch type=%s bufSize=%d\n", in.GetType(), len(in.Items))
 	switch in.Type {
 	case "int":
-		asketchState := getOrCreateASketchState[int]("")
+		asketchState := getOrCreateASketchState[int](s, in.GetName())
 		buf := convertProtoBufToBuf[int](in)
-		asketchMutex.Lock()
+		s.asketchMutex.Lock()
 		asketchState.MergeBuf(buf)
-		asketchMutex.Unlock()
+		s.asketchMutex.Unlock()
 	case "float64":
-		asketchState := getOrCreateASketchState[float64]("")
+		asketchState := getOrCreateASketchState[float64](s, in.GetName())
 		buf := convertProtoBufToBuf[float64](in)
-		asketchMutex.Lock()
+		s.asketchMutex.Lock()
 		asketchState.MergeBuf(buf)
-		asketchMutex.Unlock()
+		s.asketchMutex.Unlock()
 	default:
 		return nil, fmt.Errorf("%s is not supported, please submit a valid type", in.Type)
 	}
 
+	s.recordMerge("asketch", in.GetName(), in.Type)
 	return &pb.MergeReply{Status: 0}, nil
 }
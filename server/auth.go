@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"path"
+
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/security"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// methodRoles is the role each method needs, methods that are not listed only
+// need a known token.
+var methodRoles = map[string]security.Role{
+	"MergeKll":            security.Producer,
+	"MergeCount":          security.Producer,
+	"MergeASketch":        security.Producer,
+	"MergeBufIntoASketch": security.Producer,
+	"MergeHll":            security.Producer,
+	"BadKll":              security.Producer,
+	"BadCount":            security.Producer,
+	"QueryKll":            security.Consumer,
+	"ReverseQueryKll":     security.Consumer,
+	"PlotKll":             security.Consumer,
+	"QueryCount":          security.Consumer,
+	"QueryASketch":        security.Consumer,
+	"TopKASketch":         security.Consumer,
+	"ListFields":          security.Consumer,
+	"QueryHll":            security.Consumer,
+	"RestartServer":       security.Admin,
+	"DumpFilter":          security.Admin,
+	"ExportSketch":        security.Consumer,
+	"ImportSketch":        security.Admin,
+	"DescribeSketch":      security.Consumer,
+}
+
+// requestSketch is the name of the sketch a request is about, AnySketch if it
+// does not name one and EverySketch if it is about all of them. Fields are
+// listed across every sketch and the baselines are merged into the unnamed one.
+func requestSketch(req interface{}) string {
+	if r, ok := req.(interface{ GetAll() bool }); ok && r.GetAll() {
+		return security.EverySketch
+	}
+	switch r := req.(type) {
+	case *pb.ListFieldsRequest:
+		return security.EverySketch
+	case *pb.BadArray:
+		return security.UnnamedSketch
+	case interface{ GetName() string }:
+		return r.GetName()
+	}
+	return security.AnySketch
+}
+
+// AuthInterceptor checks the bearer token of every request against the tokens
+// of s, it lets everything through when s has none.
+func (s *Server) AuthInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	tokens := s.tokens
+	if tokens == nil {
+		return handler(ctx, req)
+	}
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			token = security.BearerToken(values[0])
+		}
+	}
+	var err error
+	if role, ok := methodRoles[path.Base(info.FullMethod)]; ok {
+		err = tokens.Authorize(token, role, requestSketch(req))
+	} else {
+		err = tokens.Authenticate(token)
+	}
+	if errors.Is(err, security.ErrUnauthenticated) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	return handler(ctx, req)
+}
@@ -4,7 +4,6 @@ import (
 	"cmp"
 	"context"
 	"fmt"
-	"sync"
 
 	pb "github.com/bruhng/distributed-sketching/proto"
 	"github.com/bruhng/distributed-sketching/shared"
@@ -12,75 +11,60 @@ import (
 	"github.com/bruhng/distributed-sketching/sketches/kll"
 )
 
-var (
-	badKllStateOnce   sync.Once
-	badKllStateMap    sync.Map
-	badKllMutex       sync.Mutex
-	badCountStateOnce sync.Once
-	badCountStateMap  sync.Map
-	badCountMutex     sync.Mutex
-)
-
-func getOrCreateBadKllState[T cmp.Ordered]() *kll.KLLSketch[T] {
+func getOrCreateBadKllState[T cmp.Ordered](s *Server) *kll.KLLSketch[T] {
 	key := fmt.Sprintf("%T", *new(T))
-	var sketch *kll.KLLSketch[T]
-	badKllStateOnce.Do(func() {
-		badKllStateMap.Store(key, kll.NewKLLSketch[T](200))
-	})
-	if val, ok := badKllStateMap.Load(key); ok {
-		sketch = val.(*kll.KLLSketch[T])
+	if val, ok := s.badKllStateMap.Load(key); ok {
+		return val.(*kll.KLLSketch[T])
 	}
-	return sketch
+	actual, _ := s.badKllStateMap.LoadOrStore(key, kll.NewKLLSketch[T](200))
+	return actual.(*kll.KLLSketch[T])
 }
 
 func (s *Server) BadKll(_ context.Context, in *pb.BadArray) (*pb.MergeReply, error) {
 	if in.Type == "int" {
-		sketch := getOrCreateBadKllState[int]()
-		badKllMutex.Lock()
+		sketch := getOrCreateBadKllState[int](s)
+		s.badKllMutex.Lock()
 		for _, val := range in.Arr.GetValues() {
 			sketch.Add(int(val.GetIntVal()))
 		}
-		badKllMutex.Unlock()
+		s.badKllMutex.Unlock()
 	} else if in.Type == "float64" {
-		sketch := getOrCreateBadKllState[float64]()
-		badKllMutex.Lock()
+		sketch := getOrCreateBadKllState[float64](s)
+		s.badKllMutex.Lock()
 		for _, val := range in.Arr.GetValues() {
 			sketch.Add(val.GetFloatVal())
 		}
-		badKllMutex.Unlock()
+		s.badKllMutex.Unlock()
 
 	} else {
 		return nil, fmt.Errorf("%s is not supported, please submit a valid type", in.Type)
 	}
 	return &pb.MergeReply{Status: 0}, nil
 }
-func getOrCreateBadCountState[T shared.Number]() *count.CountSketch[T] {
+func getOrCreateBadCountState[T shared.Number](s *Server) *count.CountSketch[T] {
 	key := fmt.Sprintf("%T", *new(T))
-	var sketch *count.CountSketch[T]
-	badCountStateOnce.Do(func() {
-		badCountStateMap.Store(key, count.NewCountSketch[T](157, 100, 10))
-	})
-	if val, ok := badCountStateMap.Load(key); ok {
-		sketch = val.(*count.CountSketch[T])
+	if val, ok := s.badCountStateMap.Load(key); ok {
+		return val.(*count.CountSketch[T])
 	}
-	return sketch
+	actual, _ := s.badCountStateMap.LoadOrStore(key, count.NewCountSketch[T](157, 100, 10))
+	return actual.(*count.CountSketch[T])
 }
 
 func (s *Server) BadCount(_ context.Context, in *pb.BadArray) (*pb.MergeReply, error) {
 	if in.Type == "int" {
-		sketch := getOrCreateBadCountState[int]()
-		badCountMutex.Lock()
+		sketch := getOrCreateBadCountState[int](s)
+		s.badCountMutex.Lock()
 		for _, val := range in.Arr.GetValues() {
 			sketch.Add(int(val.GetIntVal()))
 		}
-		badCountMutex.Unlock()
+		s.badCountMutex.Unlock()
 	} else if in.Type == "float64" {
-		sketch := getOrCreateBadCountState[float64]()
-		badCountMutex.Lock()
+		sketch := getOrCreateBadCountState[float64](s)
+		s.badCountMutex.Lock()
 		for _, val := range in.Arr.GetValues() {
 			sketch.Add(val.GetFloatVal())
 		}
-		badCountMutex.Unlock()
+		s.badCountMutex.Unlock()
 
 	} else {
 		return nil, fmt.Errorf("%s is not supported, please submit a valid type", in.Type)
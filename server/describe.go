@@ -0,0 +1,221 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+	"unsafe"
+
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/shared"
+	"github.com/bruhng/distributed-sketching/sketches/asketch"
+	"github.com/bruhng/distributed-sketching/sketches/count"
+	"github.com/bruhng/distributed-sketching/sketches/hll"
+	"github.com/bruhng/distributed-sketching/sketches/kll"
+)
+
+// mergeStats counts the merges one sketch received
+type mergeStats struct {
+	merges int64
+	last   time.Time
+}
+
+// statsKey identifies a sketch across kinds
+type statsKey struct {
+	kind string
+	key  sketchKey
+}
+
+// recordMerge notes a merge into the sketch of kind under name and typ
+func (s *Server) recordMerge(kind string, name string, typ string) {
+	s.statsMutex.Lock()
+	defer s.statsMutex.Unlock()
+	v, _ := s.mergeStatsMap.LoadOrStore(statsKey{kind, sketchKey{name: name, typ: typ}}, &mergeStats{})
+	stats := v.(*mergeStats)
+	stats.merges++
+	stats.last = time.Now()
+}
+
+// forgetMerges drops the merge counts of a sketch that was replaced
+func (s *Server) forgetMerges(kind string, key sketchKey) {
+	s.mergeStatsMap.Delete(statsKey{kind, key})
+}
+
+func sizeOf[T any]() int64 {
+	return int64(unsafe.Sizeof(*new(T)))
+}
+
+func describeKll[T shared.Number](sketch *kll.KLLSketch[T], info *pb.SketchInfo) {
+	info.Params = map[string]int64{"k": int64(sketch.K)}
+	info.N = sketch.N
+	for _, level := range sketch.Sketch {
+		info.LevelSizes = append(info.LevelSizes, int64(len(level)))
+		info.MemoryBytes += int64(cap(level)) * sizeOf[T]()
+	}
+}
+
+func describeCount[T shared.Number](sketch *count.CountSketch[T], info *pb.SketchInfo) {
+	info.Params = map[string]int64{"depth": int64(len(sketch.Sketch))}
+	if len(sketch.Sketch) > 0 {
+		info.Params["width"] = int64(len(sketch.Sketch[0]))
+	}
+	for _, row := range sketch.Sketch {
+		info.MemoryBytes += int64(len(row)) * sizeOf[int]()
+	}
+	info.MemoryBytes += int64(len(sketch.Seeds)) * sizeOf[uint32]()
+}
+
+func describeHll[T shared.Number](sketch *hll.HLLSketch[T], info *pb.SketchInfo) {
+	info.Params = map[string]int64{"registers": int64(len(sketch.C))}
+	info.MemoryBytes = int64(len(sketch.C)) * sizeOf[int]()
+}
+
+// describeASketch counts the items of an ASketch as the counts only its
+// filter holds plus the items in the first row of its Count-Min sketch.
+func describeASketch[T shared.Number](sketch *asketch.ASketch[T], info *pb.SketchInfo) {
+	filter, rows, seeds := sketch.Snapshot()
+	info.Params = map[string]int64{"slots": int64(len(filter)), "depth": int64(len(rows))}
+	if len(rows) > 0 {
+		info.Params["width"] = int64(len(rows[0]))
+		for _, c := range rows[0] {
+			info.N += int64(c)
+		}
+	}
+	for _, slot := range filter {
+		if slot.New >= 0 {
+			info.N += int64(slot.New - slot.Old)
+		}
+	}
+	info.MemoryBytes = int64(len(filter)) * sizeOf[asketch.FilterSlot[T]]()
+	for _, row := range rows {
+		info.MemoryBytes += int64(len(row)) * sizeOf[int]()
+	}
+	info.MemoryBytes += int64(len(seeds)) * sizeOf[uint32]()
+}
+
+// DescribeSketch reports the parameters, size and merges of every sketch kept
+// under the name of in, or under every name, optionally only those of one kind
+// or type.
+func (s *Server) DescribeSketch(_ context.Context, in *pb.DescribeRequest) (*pb.DescribeReply, error) {
+	switch in.GetKind() {
+	case "", "kll", "count", "hll", "asketch":
+	default:
+		return nil, fmt.Errorf("%s is not supported, please submit a valid kind", in.GetKind())
+	}
+	switch in.GetType() {
+	case "", "int", "float64":
+	default:
+		return nil, fmt.Errorf("%s is not supported, please submit a valid type", in.GetType())
+	}
+
+	out := &pb.DescribeReply{}
+	describe := func(kind string, m *sync.Map, mu *sync.Mutex, fill func(v any, info *pb.SketchInfo)) {
+		if in.GetKind() != "" && in.GetKind() != kind {
+			return
+		}
+		var infos []*pb.SketchInfo
+		mu.Lock()
+		m.Range(func(k, v any) bool {
+			key := k.(sketchKey)
+			if (!in.GetAll() && key.name != in.GetName()) || (in.GetType() != "" && in.GetType() != key.typ) {
+				return true
+			}
+			info := &pb.SketchInfo{Kind: kind, Type: key.typ, Name: key.name}
+			fill(v, info)
+			if stats, ok := s.mergeStatsMap.Load(statsKey{kind, key}); ok {
+				s.statsMutex.Lock()
+				info.Merges = stats.(*mergeStats).merges
+				info.LastMergeUnixNano = stats.(*mergeStats).last.UnixNano()
+				s.statsMutex.Unlock()
+			}
+			infos = append(infos, info)
+			return true
+		})
+		mu.Unlock()
+		sort.Slice(infos, func(i, j int) bool {
+			if infos[i].Name != infos[j].Name {
+				return infos[i].Name < infos[j].Name
+			}
+			return infos[i].Type < infos[j].Type
+		})
+		out.Sketches = append(out.Sketches, infos...)
+	}
+	describe("kll", &s.kllStateMap, &s.kllMutex, func(v any, info *pb.SketchInfo) {
+		switch sketch := v.(type) {
+		case *kll.KLLSketch[int]:
+			describeKll(sketch, info)
+		case *kll.KLLSketch[float64]:
+			describeKll(sketch, info)
+		}
+	})
+	describe("count", &s.countStateMap, &s.countMutex, func(v any, info *pb.SketchInfo) {
+		switch sketch := v.(type) {
+		case *count.CountSketch[int]:
+			describeCount(sketch, info)
+		case *count.CountSketch[float64]:
+			describeCount(sketch, info)
+		}
+	})
+	describe("hll", &s.hllStateMap, &s.hllMutex, func(v any, info *pb.SketchInfo) {
+		switch sketch := v.(type) {
+		case *hll.HLLSketch[int]:
+			describeHll(sketch, info)
+		case *hll.HLLSketch[float64]:
+			describeHll(sketch, info)
+		}
+	})
+	describe("asketch", &s.asketchStateMap, &s.asketchMutex, func(v any, info *pb.SketchInfo) {
+		switch sketch := v.(type) {
+		case *asketch.ASketch[int]:
+			describeASketch(sketch, info)
+		case *asketch.ASketch[float64]:
+			describeASketch(sketch, info)
+		}
+	})
+	return out, nil
+}
+
+// DumpFilter returns the filter of the ASketch of a field, the items it counts
+// exactly, from the most frequent.
+func (s *Server) DumpFilter(_ context.Context, in *pb.DumpFilterRequest) (*pb.DumpFilterReply, error) {
+	out := &pb.DumpFilterReply{}
+	switch in.GetType() {
+	case "int":
+		st, err := loadASketchState[int](s, in.GetName())
+		if err != nil {
+			return nil, err
+		}
+		s.asketchMutex.Lock()
+		slots := st.FilterSnapshot()
+		s.asketchMutex.Unlock()
+		sort.Slice(slots, func(i, j int) bool { return slots[i].New > slots[j].New })
+		for _, sl := range slots {
+			out.Entries = append(out.Entries, &pb.ASketchFilterEntry{
+				Item: &pb.NumericValue{Value: &pb.NumericValue_IntVal{IntVal: int64(sl.Item)}},
+				Old:  int64(sl.Old),
+				New:  int64(sl.New),
+			})
+		}
+	case "float64":
+		st, err := loadASketchState[float64](s, in.GetName())
+		if err != nil {
+			return nil, err
+		}
+		s.asketchMutex.Lock()
+		slots := st.FilterSnapshot()
+		s.asketchMutex.Unlock()
+		sort.Slice(slots, func(i, j int) bool { return slots[i].New > slots[j].New })
+		for _, sl := range slots {
+			out.Entries = append(out.Entries, &pb.ASketchFilterEntry{
+				Item: &pb.NumericValue{Value: &pb.NumericValue_FloatVal{FloatVal: sl.Item}},
+				Old:  int64(sl.Old),
+				New:  int64(sl.New),
+			})
+		}
+	default:
+		return nil, fmt.Errorf("%s is not supported, please submit a valid type", in.GetType())
+	}
+	return out, nil
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"cmp"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/bruhng/distributed-sketching/metrics"
+	"github.com/bruhng/distributed-sketching/shared"
+	"github.com/bruhng/distributed-sketching/sketches/asketch"
+	"github.com/bruhng/distributed-sketching/sketches/count"
+	"github.com/bruhng/distributed-sketching/sketches/kll"
+)
+
+// ExportConfig selects what ExportSketches publishes.
+type ExportConfig struct {
+	// Phis are the quantiles of every KLL sketch.
+	Phis []float64
+	// TopK is the number of heavy hitters of every ASketch and Count sketch.
+	TopK int
+	// CountItems are the candidates ranked for Count sketches, which can not
+	// list the items they have seen.
+	CountItems []float64
+}
+
+// ExportSketches publishes the contents of every named sketch of s under path
+// of ServeMetrics: KLL sketches as summaries and the top-k of ASketch and
+// Count sketches as gauges labelled with the item and its rank.
+func (s *Server) ExportSketches(path string, cfg ExportConfig) {
+	r := &metrics.Registry{}
+	r.NewSummaryFunc("sketch_kll_value", "Quantiles of the values in each KLL sketch.", []string{"name", "type"}, func(emit func(metrics.Quantiles, ...string)) {
+		s.kllMutex.Lock()
+		defer s.kllMutex.Unlock()
+		s.kllStateMap.Range(func(k, v any) bool {
+			key := k.(sketchKey)
+			switch sketch := v.(type) {
+			case *kll.KLLSketch[int]:
+				emit(kllQuantiles(sketch, cfg.Phis), key.name, key.typ)
+			case *kll.KLLSketch[float64]:
+				emit(kllQuantiles(sketch, cfg.Phis), key.name, key.typ)
+			}
+			return true
+		})
+	})
+	r.NewGaugeFunc("sketch_asketch_topk_count", "Estimated count of the most frequent items of each ASketch.", []string{"name", "type", "rank", "item"}, func(emit func(float64, ...string)) {
+		s.asketchMutex.Lock()
+		defer s.asketchMutex.Unlock()
+		s.asketchStateMap.Range(func(k, v any) bool {
+			key := k.(sketchKey)
+			switch sketch := v.(type) {
+			case *asketch.ASketch[int]:
+				emitASketchTopK(sketch, cfg.TopK, key, emit)
+			case *asketch.ASketch[float64]:
+				emitASketchTopK(sketch, cfg.TopK, key, emit)
+			}
+			return true
+		})
+	})
+	r.NewGaugeFunc("sketch_count_topk_count", "Estimated count of the most frequent candidate items of each Count sketch.", []string{"name", "type", "rank", "item"}, func(emit func(float64, ...string)) {
+		if len(cfg.CountItems) == 0 {
+			return
+		}
+		s.countMutex.Lock()
+		defer s.countMutex.Unlock()
+		s.countStateMap.Range(func(k, v any) bool {
+			key := k.(sketchKey)
+			switch sketch := v.(type) {
+			case *count.CountSketch[int]:
+				emitCountTopK(sketch, cfg.CountItems, cfg.TopK, key, emit)
+			case *count.CountSketch[float64]:
+				emitCountTopK(sketch, cfg.CountItems, cfg.TopK, key, emit)
+			}
+			return true
+		})
+	})
+	s.exports.Handle(path, r.Handler())
+}
+
+// kllQuantiles also estimates the sum from the weighted items of the sketch
+func kllQuantiles[T shared.Number](sketch *kll.KLLSketch[T], phis []float64) metrics.Quantiles {
+	q := metrics.Quantiles{Values: map[float64]float64{}, Count: float64(sketch.N)}
+	if sketch.N == 0 {
+		return q
+	}
+	for h, row := range sketch.Sketch {
+		for _, item := range row {
+			q.Sum += float64(item) * math.Pow(2, float64(h))
+		}
+	}
+	for _, phi := range phis {
+		q.Values[phi] = float64(sketch.QueryQuantile(phi))
+	}
+	return q
+}
+
+func emitASketchTopK[T shared.Number](sketch *asketch.ASketch[T], k int, key sketchKey, emit func(float64, ...string)) {
+	for i, slot := range sketch.TopK(k) {
+		emit(float64(slot.New), key.name, key.typ, strconv.Itoa(i+1), formatItem(slot.Item))
+	}
+}
+
+func emitCountTopK[T shared.Number](sketch *count.CountSketch[T], candidates []float64, k int, key sketchKey, emit func(float64, ...string)) {
+	type estimate struct {
+		item  T
+		count int
+	}
+	seen := map[T]bool{}
+	var estimates []estimate
+	for _, c := range candidates {
+		item := T(c)
+		if seen[item] {
+			continue
+		}
+		seen[item] = true
+		estimates = append(estimates, estimate{item, sketch.Query(item)})
+	}
+	sort.Slice(estimates, func(i, j int) bool {
+		if estimates[i].count != estimates[j].count {
+			return estimates[i].count > estimates[j].count
+		}
+		return cmp.Less(estimates[i].item, estimates[j].item)
+	})
+	for i, e := range estimates[:min(k, len(estimates))] {
+		emit(float64(e.count), key.name, key.typ, strconv.Itoa(i+1), formatItem(e.item))
+	}
+}
+
+func formatItem[T shared.Number](item T) string {
+	return strconv.FormatFloat(float64(item), 'g', -1, 64)
+}
@@ -0,0 +1,283 @@
+package server
+
+import (
+	"crypto/tls"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/security"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+//go:embed openapi.json
+var openAPI []byte
+
+type httpError struct {
+	status int
+	err    error
+}
+
+func badRequest(format string, args ...any) *httpError {
+	return &httpError{http.StatusBadRequest, fmt.Errorf(format, args...)}
+}
+
+// Gateway serves the queries of s as JSON over HTTP, see openapi.json for the
+// routes.
+func (s *Server) Gateway() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/openapi.json", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openAPI)
+	})
+	mux.HandleFunc("GET /v1/sketches/{name}/quantile", s.jsonHandler(s.httpQuantile))
+	mux.HandleFunc("GET /v1/sketches/{name}/rank", s.jsonHandler(s.httpRank))
+	mux.HandleFunc("GET /v1/sketches/{name}/topk", s.jsonHandler(s.httpTopK))
+	mux.HandleFunc("GET /v1/sketches/{name}/cardinality", s.jsonHandler(s.httpCardinality))
+	mux.HandleFunc("GET /v1/sketches/{name}/histogram", s.jsonHandler(s.httpHistogram))
+	mux.HandleFunc("GET /v1/fields", s.jsonHandler(s.httpFields))
+	return mux
+}
+
+// ServeGateway serves the gateway on addr until the process ends and returns
+// the address it listens on. It uses TLS when the server does.
+func (s *Server) ServeGateway(addr string) (net.Addr, error) {
+	return s.serveHTTP(addr, s.Gateway())
+}
+
+// serveHTTP serves h on addr until the process ends, over TLS when it is
+// configured.
+func (s *Server) serveHTTP(addr string, h http.Handler) (net.Addr, error) {
+	cfg, err := s.tls.ServerConfig()
+	if err != nil {
+		return nil, err
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if cfg != nil {
+		lis = tls.NewListener(lis, cfg)
+	}
+	go http.Serve(lis, h)
+	return lis.Addr(), nil
+}
+
+func (s *Server) jsonHandler(handle func(r *http.Request, name string, typ string) (any, *httpError)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		name := r.PathValue("name")
+		var reply any
+		herr := s.authorizeHTTP(r, name)
+		if name == security.UnnamedSketch {
+			name = ""
+		}
+		var typ string
+		if herr == nil {
+			typ, herr = queryType(r)
+		}
+		if herr == nil {
+			reply, herr = handle(r, name, typ)
+		}
+		if herr != nil {
+			w.WriteHeader(herr.status)
+			reply = map[string]string{"error": herr.err.Error()}
+		}
+		json.NewEncoder(w).Encode(reply)
+	}
+}
+
+// authorizeHTTP checks that the bearer token of r is a consumer token with
+// access to the named sketch, when s has tokens.
+func (s *Server) authorizeHTTP(r *http.Request, name string) *httpError {
+	if s.tokens == nil {
+		return nil
+	}
+	if name == "" {
+		name = security.EverySketch // only /v1/fields names no sketch, it lists all of them
+	}
+	err := s.tokens.Authorize(security.BearerToken(r.Header.Get("Authorization")), security.Consumer, name)
+	if errors.Is(err, security.ErrUnauthenticated) {
+		return &httpError{http.StatusUnauthorized, err}
+	}
+	if err != nil {
+		return &httpError{http.StatusForbidden, err}
+	}
+	return nil
+}
+
+// queryType reads the element type, float64 unless ?type= says otherwise
+func queryType(r *http.Request) (string, *httpError) {
+	switch typ := r.URL.Query().Get("type"); typ {
+	case "", "float", "float64":
+		return "float64", nil
+	case "int":
+		return "int", nil
+	default:
+		return "", badRequest("%s is not supported, please submit a valid type", typ)
+	}
+}
+
+func queryFloat(r *http.Request, param string) (float64, *httpError) {
+	raw := r.URL.Query().Get(param)
+	if raw == "" {
+		return 0, badRequest("missing query parameter %s", param)
+	}
+	f, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, badRequest("query parameter %s: %s is not a valid number", param, raw)
+	}
+	return f, nil
+}
+
+// numericValue builds the value of a query in the sketch's element type
+func numericValue(typ string, x float64) *pb.NumericValue {
+	if typ == "int" {
+		return &pb.NumericValue{Value: &pb.NumericValue_IntVal{IntVal: int64(x)}, Type: typ}
+	}
+	return &pb.NumericValue{Value: &pb.NumericValue_FloatVal{FloatVal: x}, Type: typ}
+}
+
+func numericJSON(v *pb.NumericValue) any {
+	if i, ok := v.GetValue().(*pb.NumericValue_IntVal); ok {
+		return i.IntVal
+	}
+	return v.GetFloatVal()
+}
+
+// internal reports a failed query, queries of unknown sketches are not found
+func internal(err error) *httpError {
+	if err == nil {
+		return nil
+	}
+	if status.Code(err) == codes.NotFound {
+		return &httpError{http.StatusNotFound, err}
+	}
+	return &httpError{http.StatusInternalServerError, err}
+}
+
+func (s *Server) httpQuantile(r *http.Request, name string, typ string) (any, *httpError) {
+	phi, herr := queryFloat(r, "phi")
+	if herr != nil {
+		return nil, herr
+	}
+	if phi < 0 || phi > 1 {
+		return nil, badRequest("phi has to be between 0 and 1")
+	}
+	v, err := s.ReverseQueryKll(r.Context(), &pb.ReverseQuery{Phi: phi, Type: typ, Name: name})
+	if err != nil {
+		return nil, internal(err)
+	}
+	return map[string]any{"name": name, "type": typ, "phi": phi, "value": numericJSON(v)}, nil
+}
+
+func (s *Server) httpRank(r *http.Request, name string, typ string) (any, *httpError) {
+	x, herr := queryFloat(r, "x")
+	if herr != nil {
+		return nil, herr
+	}
+	v := numericValue(typ, x)
+	v.Name = name
+	ret, err := s.QueryKll(r.Context(), v)
+	if err != nil {
+		return nil, internal(err)
+	}
+	return map[string]any{"name": name, "type": typ, "x": numericJSON(v), "rank": ret.Phi, "n": ret.N}, nil
+}
+
+func (s *Server) httpTopK(r *http.Request, name string, typ string) (any, *httpError) {
+	k := 10
+	if raw := r.URL.Query().Get("k"); raw != "" {
+		var err error
+		if k, err = strconv.Atoi(raw); err != nil || k < 1 {
+			return nil, badRequest("k has to be a positive int")
+		}
+	}
+	ret, err := s.TopKASketch(r.Context(), &pb.TopKRequest{K: uint32(k), Type: typ, Name: name})
+	if err != nil {
+		return nil, internal(err)
+	}
+	items := make([]map[string]any, len(ret.Entries))
+	for i, e := range ret.Entries {
+		items[i] = map[string]any{"item": numericJSON(e.Key), "count": e.EstFreq}
+	}
+	return map[string]any{"name": name, "type": typ, "items": items}, nil
+}
+
+func (s *Server) httpHistogram(r *http.Request, name string, typ string) (any, *httpError) {
+	req := &pb.PlotRequest{NumBins: 10, Type: typ, Name: name}
+	q := r.URL.Query()
+	if raw := q.Get("bins"); raw != "" {
+		var err error
+		if req.NumBins, err = strconv.ParseInt(raw, 10, 64); err != nil || req.NumBins < 1 {
+			return nil, badRequest("bins has to be a positive int")
+		}
+	}
+	switch q.Get("mode") {
+	case "", "equiwidth":
+	case "equidepth":
+		req.EquiDepth = true
+	default:
+		return nil, badRequest("mode has to be equiwidth or equidepth")
+	}
+	if q.Has("min") || q.Has("max") {
+		var herr *httpError
+		if req.RangeMin, herr = queryFloat(r, "min"); herr != nil {
+			return nil, herr
+		}
+		if req.RangeMax, herr = queryFloat(r, "max"); herr != nil {
+			return nil, herr
+		}
+		if req.RangeMin >= req.RangeMax {
+			return nil, badRequest("min has to be smaller than max")
+		}
+	}
+	if raw := q.Get("splits"); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			split, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return nil, badRequest("splits: %s is not a valid number", part)
+			}
+			req.Splits = append(req.Splits, split)
+		}
+	}
+	ret, err := s.PlotKll(r.Context(), req)
+	if status.Code(err) == codes.NotFound {
+		return nil, internal(err)
+	}
+	if err != nil {
+		// the type is already checked, what is left are invalid bins
+		return nil, badRequest("%v", err)
+	}
+	return map[string]any{"name": name, "type": typ, "edges": ret.Edges, "counts": ret.Pmf, "mass": ret.Mass}, nil
+}
+
+func (s *Server) httpCardinality(r *http.Request, name string, typ string) (any, *httpError) {
+	ret, err := s.QueryHll(r.Context(), &pb.CardinalityRequest{Type: typ, Name: name})
+	if err != nil {
+		return nil, internal(err)
+	}
+	return map[string]any{"name": name, "type": typ, "estimate": ret.Estimate}, nil
+}
+
+func (s *Server) httpFields(r *http.Request, _ string, typ string) (any, *httpError) {
+	if r.URL.Query().Get("type") == "" {
+		typ = "" // every type
+	}
+	ret, err := s.ListFields(r.Context(), &pb.ListFieldsRequest{Type: typ})
+	if err != nil {
+		return nil, internal(err)
+	}
+	fields := make([]map[string]string, len(ret.Fields))
+	for i, f := range ret.Fields {
+		fields[i] = map[string]string{"name": f.Field, "type": f.Type}
+	}
+	return map[string]any{"fields": fields}, nil
+}
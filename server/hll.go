@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/shared"
+	"github.com/bruhng/distributed-sketching/sketches/convert"
+	"github.com/bruhng/distributed-sketching/sketches/hll"
+)
+
+func getOrCreateHllState[T shared.Number](s *Server, name string) *hll.HLLSketch[T] {
+	key := newSketchKey[T](name)
+	if val, ok := s.hllStateMap.Load(key); ok {
+		return val.(*hll.HLLSketch[T])
+	}
+	actual, _ := s.hllStateMap.LoadOrStore(key, hll.NewHLLSketch[T](shared.HLLRegisters, shared.HLLSeed))
+	return actual.(*hll.HLLSketch[T])
+}
+
+func (s *Server) MergeHll(_ context.Context, in *pb.HLLSketch) (*pb.MergeReply, error) {
+	var err error
+	switch in.Type {
+	case "int":
+		hllState := getOrCreateHllState[int](s, in.GetName())
+		sketch := convert.FromProtoHll[int](in)
+		s.hllMutex.Lock()
+		err = hllState.Merge(*sketch)
+		s.hllMutex.Unlock()
+	case "float64":
+		hllState := getOrCreateHllState[float64](s, in.GetName())
+		sketch := convert.FromProtoHll[float64](in)
+		s.hllMutex.Lock()
+		err = hllState.Merge(*sketch)
+		s.hllMutex.Unlock()
+	default:
+		return nil, fmt.Errorf("%s is not supported, please submit a valid type", in.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.recordMerge("hll", in.GetName(), in.Type)
+	return &pb.MergeReply{Status: 0}, nil
+}
+
+func (s *Server) QueryHll(_ context.Context, in *pb.CardinalityRequest) (*pb.CardinalityReply, error) {
+	var est float64
+	switch in.Type {
+	case "int":
+		hllState, err := loadState[*hll.HLLSketch[int], int](&s.hllStateMap, "hll", in.GetName())
+		if err != nil {
+			return nil, err
+		}
+		s.hllMutex.Lock()
+		est = hllState.Query()
+		s.hllMutex.Unlock()
+	case "float64":
+		hllState, err := loadState[*hll.HLLSketch[float64], float64](&s.hllStateMap, "hll", in.GetName())
+		if err != nil {
+			return nil, err
+		}
+		s.hllMutex.Lock()
+		est = hllState.Query()
+		s.hllMutex.Unlock()
+	default:
+		return nil, fmt.Errorf("%s is not supported, please submit a valid type", in.Type)
+	}
+	return &pb.CardinalityReply{Estimate: est}, nil
+}
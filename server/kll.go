@@ -4,140 +4,214 @@ import (
 	"cmp"
 	"context"
 	"fmt"
-	"sync"
+	"math"
 
 	pb "github.com/bruhng/distributed-sketching/proto"
 	"github.com/bruhng/distributed-sketching/shared"
+	"github.com/bruhng/distributed-sketching/sketches/convert"
 	"github.com/bruhng/distributed-sketching/sketches/kll"
 )
 
-var (
-	kllStateOnce sync.Once
-	kllStateMap  sync.Map
-	KllMutex     sync.Mutex
-)
-
-func getOrCreateKllState[T cmp.Ordered]() *kll.KLLSketch[T] {
-	key := fmt.Sprintf("%T", *new(T))
-	var sketch *kll.KLLSketch[T]
-	kllStateOnce.Do(func() {
-		kllStateMap.Store(key, kll.NewKLLSketch[T](200))
-	})
-	if val, ok := kllStateMap.Load(key); ok {
-		sketch = val.(*kll.KLLSketch[T])
+func getOrCreateKllState[T cmp.Ordered](s *Server, name string) *kll.KLLSketch[T] {
+	key := newSketchKey[T](name)
+	if val, ok := s.kllStateMap.Load(key); ok {
+		return val.(*kll.KLLSketch[T])
 	}
-	return sketch
+	actual, _ := s.kllStateMap.LoadOrStore(key, kll.NewKLLSketch[T](200))
+	return actual.(*kll.KLLSketch[T])
 }
 
-func convertProtoKLLToKLL[T shared.Number](protoData *pb.KLLSketch) *kll.KLLSketch[T] {
-	var data [][]T
-
-	for _, protoRow := range protoData.Rows {
-		var row []T
-
-		for _, protoValue := range protoRow.Values {
-			if intVal, ok := protoValue.Value.(*pb.NumericValue_IntVal); ok {
-				row = append(row, T(intVal.IntVal))
-			}
-			if floatVal, ok := protoValue.Value.(*pb.NumericValue_FloatVal); ok {
-				row = append(row, T(any(floatVal.FloatVal).(T)))
-			}
-		}
-
-		data = append(data, row)
-	}
-
-	return kll.NewKLLFromData[T](data, protoData.GetN(), 200)
+// loadKllState returns the KLL sketch stored under name for queries, without
+// creating one
+func loadKllState[T cmp.Ordered](s *Server, name string) (*kll.KLLSketch[T], error) {
+	return loadState[*kll.KLLSketch[T], T](&s.kllStateMap, "kll", name)
 }
 
 func (s *Server) MergeKll(_ context.Context, in *pb.KLLSketch) (*pb.MergeReply, error) {
 	if in.Type == "int" {
-		kllState := getOrCreateKllState[int]()
-		sketch := convertProtoKLLToKLL[int](in)
-		KllMutex.Lock()
+		kllState := getOrCreateKllState[int](s, in.GetName())
+		sketch := convert.FromProtoKLL[int](in)
+		s.kllMutex.Lock()
 		kllState.Merge(*sketch)
-		KllMutex.Unlock()
+		s.kllMutex.Unlock()
 	} else if in.Type == "float64" {
-		kllState := getOrCreateKllState[float64]()
-		sketch := convertProtoKLLToKLL[float64](in)
-		KllMutex.Lock()
+		kllState := getOrCreateKllState[float64](s, in.GetName())
+		sketch := convert.FromProtoKLL[float64](in)
+		s.kllMutex.Lock()
 		kllState.Merge(*sketch)
-		KllMutex.Unlock()
+		s.kllMutex.Unlock()
 	} else {
 		return nil, fmt.Errorf("%s is not supported, please submit a valid type", in.Type)
 	}
 
+	s.recordMerge("kll", in.GetName(), in.Type)
 	return &pb.MergeReply{Status: 0}, nil
 }
 
 func (s *Server) QueryKll(_ context.Context, in *pb.NumericValue) (*pb.QueryReturn, error) {
-	if in.Type == "int" {
-		kllState := getOrCreateKllState[int]()
-		val := in.GetIntVal()
-		ret := kllState.Query(int(val))
+	switch in.Type {
+	case "int":
+		kllState, err := loadKllState[int](s, in.GetName())
+		if err != nil {
+			return nil, err
+		}
+		s.kllMutex.Lock()
+		defer s.kllMutex.Unlock()
+		ret := kllState.Query(int(in.GetIntVal()))
 		return &pb.QueryReturn{N: int64(kllState.N), Phi: int64(ret)}, nil
-	} else if in.Type == "float64" {
-		kllState := getOrCreateKllState[float64]()
-		val := in.GetFloatVal()
-		ret := kllState.Query(float64(val))
+	case "float64":
+		kllState, err := loadKllState[float64](s, in.GetName())
+		if err != nil {
+			return nil, err
+		}
+		s.kllMutex.Lock()
+		defer s.kllMutex.Unlock()
+		ret := kllState.Query(in.GetFloatVal())
 		return &pb.QueryReturn{N: int64(kllState.N), Phi: int64(ret)}, nil
-	} else {
+	default:
 		return nil, fmt.Errorf("%s is not supported, please submit a valid type", in.Type)
 	}
 }
 
+// ReverseQueryKll holds the lock while querying, QueryQuantile sorts the rows
+// of the sketch in place.
 func (s *Server) ReverseQueryKll(_ context.Context, in *pb.ReverseQuery) (*pb.NumericValue, error) {
 	phi := in.Phi
-	if in.Type == "int" {
-		kllState := getOrCreateKllState[int]()
+	switch in.Type {
+	case "int":
+		kllState, err := loadKllState[int](s, in.GetName())
+		if err != nil {
+			return nil, err
+		}
+		s.kllMutex.Lock()
+		defer s.kllMutex.Unlock()
 		ret := kllState.QueryQuantile(float64(phi))
 		return &pb.NumericValue{Value: &pb.NumericValue_IntVal{IntVal: int64(ret)}}, nil
-	} else if in.Type == "float64" {
-		kllState := getOrCreateKllState[float64]()
+	case "float64":
+		kllState, err := loadKllState[float64](s, in.GetName())
+		if err != nil {
+			return nil, err
+		}
+		s.kllMutex.Lock()
+		defer s.kllMutex.Unlock()
 		ret := kllState.QueryQuantile(float64(phi))
 		return &pb.NumericValue{Value: &pb.NumericValue_FloatVal{FloatVal: float64(ret)}}, nil
-	} else {
+	default:
 		return nil, fmt.Errorf("%s is not supported, please submit a valid type", in.Type)
 	}
 }
 
 func (s *Server) PlotKll(_ context.Context, in *pb.PlotRequest) (*pb.PlotKllReply, error) {
-	if in.Type == "int" {
-		kllState := getOrCreateKllState[int]()
-		numBins := int(in.GetNumBins())
-		xmin := kllState.QueryQuantile(0.0)
-		xmax := kllState.QueryQuantile(1.0)
-		step := float64(xmax-xmin) / float64(numBins)
-
-		splits := make([]int, numBins+1)
-		for i := 0; i <= numBins; i++ {
-			splits[i] = xmin + int(step*float64(i))
+	switch in.Type {
+	case "int":
+		kllState, err := loadKllState[int](s, in.GetName())
+		if err != nil {
+			return nil, err
 		}
-
-		pmf := make([]float64, numBins)
-		for i := 0; i < numBins; i++ {
-			pmf[i] = float64(kllState.Query(splits[i+1]) - kllState.Query(splits[i]))
+		s.kllMutex.Lock()
+		defer s.kllMutex.Unlock()
+		return plotKll(kllState, in)
+	case "float64":
+		kllState, err := loadKllState[float64](s, in.GetName())
+		if err != nil {
+			return nil, err
 		}
-		return &pb.PlotKllReply{Step: float64(step), Pmf: pmf}, nil
-	} else if in.Type == "float64" {
-		kllState := getOrCreateKllState[float64]()
-		numBins := int(in.GetNumBins())
-		xmin := kllState.QueryQuantile(0.0)
-		xmax := kllState.QueryQuantile(1.0)
-		step := float64(xmax-xmin) / float64(numBins)
+		s.kllMutex.Lock()
+		defer s.kllMutex.Unlock()
+		return plotKll(kllState, in)
+	default:
+		return nil, fmt.Errorf("%s is not supported, please submit a valid type", in.Type)
+	}
+}
 
-		splits := make([]float64, numBins+1)
+// maxBins bounds the bins of a histogram, each one is a pair of queries
+const maxBins = 10000
+
+// plotKll bins the items of sketch like numpy and gonum do: bin i holds the
+// items in [edges[i], edges[i+1]), the last bin also holds its upper edge.
+func plotKll[T shared.Number](sketch *kll.KLLSketch[T], in *pb.PlotRequest) (*pb.PlotKllReply, error) {
+	out := &pb.PlotKllReply{}
+	numBins := int(in.GetNumBins())
+	switch {
+	case len(in.GetSplits()) > 0:
+		if in.GetEquiDepth() {
+			return nil, fmt.Errorf("splits and equiDepth can not be combined")
+		}
+		if len(in.GetSplits()) < 2 {
+			return nil, fmt.Errorf("at least 2 splits are needed for a bin")
+		}
+		if len(in.GetSplits()) > maxBins+1 {
+			return nil, fmt.Errorf("at most %d splits are allowed, got %d", maxBins+1, len(in.GetSplits()))
+		}
+		for _, split := range in.GetSplits() {
+			if math.IsNaN(split) || math.IsInf(split, 0) {
+				return nil, fmt.Errorf("splits must be finite, got %v", split)
+			}
+		}
+		for i := 1; i < len(in.GetSplits()); i++ {
+			if in.GetSplits()[i] <= in.GetSplits()[i-1] {
+				return nil, fmt.Errorf("splits must be increasing, %v follows %v", in.GetSplits()[i], in.GetSplits()[i-1])
+			}
+		}
+		out.Edges = in.GetSplits()
+	case numBins < 1:
+		return nil, fmt.Errorf("numBins must be positive, got %d", numBins)
+	case in.GetNumBins() > maxBins:
+		return nil, fmt.Errorf("numBins must be at most %d, got %d", maxBins, in.GetNumBins())
+	case in.GetEquiDepth():
+		// ties can make quantiles equal, those bins are merged
 		for i := 0; i <= numBins; i++ {
-			splits[i] = xmin + step*float64(i)
+			edge := float64(sketch.QueryQuantile(float64(i) / float64(numBins)))
+			if len(out.Edges) == 0 || edge > out.Edges[len(out.Edges)-1] {
+				out.Edges = append(out.Edges, edge)
+			}
 		}
+		if len(out.Edges) == 1 {
+			out.Edges = append(out.Edges, out.Edges[0])
+		}
+	default:
+		lo, hi := in.GetRangeMin(), in.GetRangeMax()
+		if math.IsNaN(lo) || math.IsInf(lo, 0) || math.IsNaN(hi) || math.IsInf(hi, 0) {
+			return nil, fmt.Errorf("rangeMin and rangeMax must be finite, got %v and %v", lo, hi)
+		}
+		if lo >= hi {
+			lo, hi = float64(sketch.QueryQuantile(0)), float64(sketch.QueryQuantile(1))
+		}
+		out.Step = (hi - lo) / float64(numBins)
+		out.Edges = make([]float64, numBins+1)
+		for i := range numBins {
+			out.Edges[i] = lo + out.Step*float64(i)
+		}
+		out.Edges[numBins] = hi
+	}
 
-		pmf := make([]float64, numBins)
-		for i := 0; i < numBins; i++ {
-			pmf[i] = float64(kllState.Query(splits[i+1]) - kllState.Query(splits[i]))
+	bins := len(out.Edges) - 1
+	out.Pmf = make([]float64, bins)
+	out.Mass = make([]float64, bins)
+	for i := range bins {
+		upper := countBelow(sketch, out.Edges[i+1], i == bins-1)
+		out.Pmf[i] = float64(upper - countBelow(sketch, out.Edges[i], false))
+		if sketch.N > 0 {
+			out.Mass[i] = out.Pmf[i] / float64(sketch.N)
 		}
-		return &pb.PlotKllReply{Step: float64(step), Pmf: pmf}, nil
+	}
+	return out, nil
+}
 
-	} else {
-		return nil, fmt.Errorf("%s is not supported, please submit a valid type", in.Type)
+// countBelow counts the items below x, or up to x when inclusive. Integer
+// sketches compare against the integers next to a fractional x, so edges
+// are never truncated.
+func countBelow[T shared.Number](sketch *kll.KLLSketch[T], x float64, inclusive bool) int {
+	half := 0.5
+	isInt := T(half) == 0
+	if inclusive {
+		if isInt {
+			x = math.Floor(x)
+		}
+		return sketch.Query(T(x))
+	}
+	if isInt {
+		x = math.Ceil(x)
 	}
+	return sketch.QueryLess(T(x))
 }
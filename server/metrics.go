@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bruhng/distributed-sketching/metrics"
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/security"
+	"github.com/bruhng/distributed-sketching/sketches/asketch"
+	"github.com/bruhng/distributed-sketching/sketches/hll"
+	"github.com/bruhng/distributed-sketching/sketches/kll"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// mergeMetrics are recorded by MetricsInterceptor to the registry of the server
+type mergeMetrics struct {
+	total    *metrics.Value
+	errors   *metrics.Value
+	duration *metrics.Histogram
+	bytes    *metrics.Value
+}
+
+func newMergeMetrics(r *metrics.Registry) *mergeMetrics {
+	return &mergeMetrics{
+		total:    r.NewCounter("sketch_server_merges_total", "Merge requests handled, by sketch kind.", "kind"),
+		errors:   r.NewCounter("sketch_server_merge_errors_total", "Merge requests that failed, by sketch kind.", "kind"),
+		duration: r.NewHistogram("sketch_server_merge_duration_seconds", "Time spent merging a sketch into the state.", metrics.DefaultBuckets, "kind"),
+		bytes:    r.NewCounter("sketch_server_received_bytes_total", "Encoded size of the merged sketches.", "kind"),
+	}
+}
+
+// RegisterMetrics adds the gauges describing the sketches of s to r, the merge
+// metrics are recorded to the registry given to NewServer.
+func (s *Server) RegisterMetrics(r *metrics.Registry) {
+	r.NewGaugeFunc("sketch_server_sketch_n", "Items summarized by each named KLL sketch and ASketch, distinct items estimated by each HyperLogLog.", []string{"kind", "name", "type"}, s.collectN)
+	r.NewGaugeFunc("sketch_server_sketch_merges", "Merges received by each named sketch of every kind.", []string{"kind", "name", "type"}, s.collectMerges)
+}
+
+// collectN reports the items of every sketch that knows them. Count sketches
+// hold signed counters that do not add up to their items and are left out.
+func (s *Server) collectN(emit func(float64, ...string)) {
+	collect := func(kind string, m *sync.Map, mu *sync.Mutex, n func(v any) float64) {
+		mu.Lock()
+		defer mu.Unlock()
+		m.Range(func(k, v any) bool {
+			key := k.(sketchKey)
+			emit(n(v), kind, key.name, key.typ)
+			return true
+		})
+	}
+	collect("kll", &s.kllStateMap, &s.kllMutex, func(v any) float64 {
+		switch sketch := v.(type) {
+		case *kll.KLLSketch[int]:
+			return float64(sketch.N)
+		case *kll.KLLSketch[float64]:
+			return float64(sketch.N)
+		}
+		return 0
+	})
+	collect("hll", &s.hllStateMap, &s.hllMutex, func(v any) float64 {
+		switch sketch := v.(type) {
+		case *hll.HLLSketch[int]:
+			return sketch.Query()
+		case *hll.HLLSketch[float64]:
+			return sketch.Query()
+		}
+		return 0
+	})
+	collect("asketch", &s.asketchStateMap, &s.asketchMutex, func(v any) float64 {
+		info := &pb.SketchInfo{}
+		switch sketch := v.(type) {
+		case *asketch.ASketch[int]:
+			describeASketch(sketch, info)
+		case *asketch.ASketch[float64]:
+			describeASketch(sketch, info)
+		}
+		return float64(info.N)
+	})
+}
+
+func (s *Server) collectMerges(emit func(float64, ...string)) {
+	s.statsMutex.Lock()
+	defer s.statsMutex.Unlock()
+	s.mergeStatsMap.Range(func(k, v any) bool {
+		key := k.(statsKey)
+		emit(float64(v.(*mergeStats).merges), key.kind, key.key.name, key.key.typ)
+		return true
+	})
+}
+
+// MetricsInterceptor records every Merge* request, labelled by the kind of
+// sketch in the method name, e.g. "kll" for MergeKll.
+func (s *Server) MetricsInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	kind, ok := strings.CutPrefix(path.Base(info.FullMethod), "Merge")
+	if !ok || s.merges == nil {
+		return handler(ctx, req)
+	}
+	kind = strings.ToLower(kind)
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	s.merges.duration.Observe(time.Since(start).Seconds(), kind)
+	s.merges.total.Inc(kind)
+	if err != nil {
+		s.merges.errors.Inc(kind)
+	}
+	if msg, ok := req.(proto.Message); ok {
+		s.merges.bytes.Add(float64(proto.Size(msg)), kind)
+	}
+	return resp, err
+}
+
+// ServeMetrics serves the registry of s under /metrics and the endpoints of
+// ExportSketches on addr like ServeGateway serves the gateway. The metrics and
+// exported sketches describe every sketch, so when s has tokens they need a
+// consumer token with access to all of them.
+func (s *Server) ServeMetrics(addr string) (net.Addr, error) {
+	registry := s.registry
+	if registry == nil {
+		registry = &metrics.Registry{}
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", registry.Handler())
+	mux.Handle("/", &s.exports)
+	return s.serveHTTP(addr, s.requireEverySketch(mux))
+}
+
+func (s *Server) requireEverySketch(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if herr := s.authorizeHTTP(r, security.EverySketch); herr != nil {
+			http.Error(w, herr.err.Error(), herr.status)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
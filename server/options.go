@@ -0,0 +1,27 @@
+package server
+
+import (
+	"github.com/bruhng/distributed-sketching/metrics"
+	"github.com/bruhng/distributed-sketching/security"
+)
+
+// Option configures a server built by NewServer.
+type Option func(*Server)
+
+// WithTokens makes the server authorize every gRPC and HTTP request with
+// tokens, a nil Tokens lets everything through.
+func WithTokens(tokens security.Tokens) Option {
+	return func(s *Server) { s.tokens = tokens }
+}
+
+// WithTLS serves Init, ServeGateway and ServeMetrics over TLS when cfg is
+// enabled.
+func WithTLS(cfg security.TLSConfig) Option {
+	return func(s *Server) { s.tls = cfg }
+}
+
+// WithMetrics records the merge metrics to r and serves r under /metrics of
+// ServeMetrics, instead of a registry of the server's own.
+func WithMetrics(r *metrics.Registry) Option {
+	return func(s *Server) { s.registry = r }
+}
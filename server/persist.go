@@ -0,0 +1,264 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/sketches/asketch"
+	"github.com/bruhng/distributed-sketching/sketches/convert"
+	"github.com/bruhng/distributed-sketching/sketches/count"
+	"github.com/bruhng/distributed-sketching/sketches/hll"
+	"github.com/bruhng/distributed-sketching/sketches/kll"
+	"google.golang.org/protobuf/proto"
+)
+
+// snapshotState converts the sketches keep accepts to their wire format, a nil
+// keep takes every sketch.
+func (s *Server) snapshotState(keep func(kind string, key sketchKey) bool) *pb.ServerState {
+	state := &pb.ServerState{}
+	if keep == nil {
+		keep = func(string, sketchKey) bool { return true }
+	}
+
+	s.kllMutex.Lock()
+	s.kllStateMap.Range(func(k, v any) bool {
+		if !keep("kll", k.(sketchKey)) {
+			return true
+		}
+		var protoSketch *pb.KLLSketch
+		switch sketch := v.(type) {
+		case *kll.KLLSketch[int]:
+			protoSketch = convert.ToProtoKLL(sketch)
+		case *kll.KLLSketch[float64]:
+			protoSketch = convert.ToProtoKLL(sketch)
+		}
+		protoSketch.Name = k.(sketchKey).name
+		state.Kll = append(state.Kll, protoSketch)
+		return true
+	})
+	s.kllMutex.Unlock()
+
+	s.countMutex.Lock()
+	s.countStateMap.Range(func(k, v any) bool {
+		if !keep("count", k.(sketchKey)) {
+			return true
+		}
+		var protoSketch *pb.CountSketch
+		switch sketch := v.(type) {
+		case *count.CountSketch[int]:
+			protoSketch = convert.ToProtoCount(sketch)
+		case *count.CountSketch[float64]:
+			protoSketch = convert.ToProtoCount(sketch)
+		}
+		protoSketch.Name = k.(sketchKey).name
+		state.Count = append(state.Count, protoSketch)
+		return true
+	})
+	s.countMutex.Unlock()
+
+	s.hllMutex.Lock()
+	s.hllStateMap.Range(func(k, v any) bool {
+		if !keep("hll", k.(sketchKey)) {
+			return true
+		}
+		switch sketch := v.(type) {
+		case *hll.HLLSketch[int]:
+			state.Hll = append(state.Hll, convert.ToProtoHll(sketch, k.(sketchKey).name))
+		case *hll.HLLSketch[float64]:
+			state.Hll = append(state.Hll, convert.ToProtoHll(sketch, k.(sketchKey).name))
+		}
+		return true
+	})
+	s.hllMutex.Unlock()
+
+	s.asketchMutex.Lock()
+	s.asketchStateMap.Range(func(k, v any) bool {
+		if !keep("asketch", k.(sketchKey)) {
+			return true
+		}
+		switch sketch := v.(type) {
+		case *asketch.ASketch[int]:
+			state.Asketch = append(state.Asketch, convert.ToProtoASketch(sketch, k.(sketchKey).name))
+		case *asketch.ASketch[float64]:
+			state.Asketch = append(state.Asketch, convert.ToProtoASketch(sketch, k.(sketchKey).name))
+		}
+		return true
+	})
+	s.asketchMutex.Unlock()
+
+	return state
+}
+
+// saveState writes the state to path, replacing the previous file only once
+// the new one is complete.
+func (s *Server) saveState(path string) error {
+	data, err := proto.Marshal(s.snapshotState(nil))
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// loadState merges a saved state into the current one. A missing file is not
+// an error, the server then simply starts empty.
+func (s *Server) loadState(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	state := &pb.ServerState{}
+	if err := proto.Unmarshal(data, state); err != nil {
+		return fmt.Errorf("could not read state %s: %w", path, err)
+	}
+
+	_, err = s.importState(state, false)
+	return err
+}
+
+// importState merges every sketch of state into the server and returns how
+// many it merged. The sketches are merged into an empty server first, so a
+// state that does not merge changes nothing. With replace the sketches of the
+// server with the same kind, name and type are dropped once state merged.
+func (s *Server) importState(state *pb.ServerState, replace bool) (int, error) {
+	if _, err := NewServer().mergeState(state); err != nil {
+		return 0, err
+	}
+	if replace {
+		s.dropSketches(state)
+	}
+	return s.mergeState(state)
+}
+
+// mergeState merges the sketches of state one at a time and returns how many
+// it merged before an error
+func (s *Server) mergeState(state *pb.ServerState) (int, error) {
+	ctx := context.Background()
+	n := 0
+	for _, sketch := range state.Kll {
+		if _, err := s.MergeKll(ctx, sketch); err != nil {
+			return n, err
+		}
+		n++
+	}
+	for _, sketch := range state.Count {
+		if _, err := s.MergeCount(ctx, sketch); err != nil {
+			return n, err
+		}
+		n++
+	}
+	for _, sketch := range state.Hll {
+		if _, err := s.MergeHll(ctx, sketch); err != nil {
+			return n, err
+		}
+		n++
+	}
+	for _, sketch := range state.Asketch {
+		if _, err := s.MergeASketch(ctx, sketch); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// dropSketches deletes the sketches of the server that state has a sketch of
+// the same kind, name and type for
+func (s *Server) dropSketches(state *pb.ServerState) {
+	s.kllMutex.Lock()
+	for _, sketch := range state.Kll {
+		key := sketchKey{name: sketch.GetName(), typ: sketch.GetType()}
+		s.kllStateMap.Delete(key)
+		s.forgetMerges("kll", key)
+	}
+	s.kllMutex.Unlock()
+	s.countMutex.Lock()
+	for _, sketch := range state.Count {
+		key := sketchKey{name: sketch.GetName(), typ: sketch.GetType()}
+		s.countStateMap.Delete(key)
+		s.forgetMerges("count", key)
+	}
+	s.countMutex.Unlock()
+	s.hllMutex.Lock()
+	for _, sketch := range state.Hll {
+		key := sketchKey{name: sketch.GetName(), typ: sketch.GetType()}
+		s.hllStateMap.Delete(key)
+		s.forgetMerges("hll", key)
+	}
+	s.hllMutex.Unlock()
+	s.asketchMutex.Lock()
+	for _, sketch := range state.Asketch {
+		key := sketchKey{name: sketch.GetName(), typ: sketch.GetType()}
+		s.asketchStateMap.Delete(key)
+		s.forgetMerges("asketch", key)
+	}
+	s.asketchMutex.Unlock()
+}
+
+// ExportSketch returns the sketches in selects in the format of the state file,
+// which ImportSketch and loadState read.
+func (s *Server) ExportSketch(_ context.Context, in *pb.ExportRequest) (*pb.ServerState, error) {
+	switch in.GetKind() {
+	case "", "kll", "count", "hll", "asketch":
+	default:
+		return nil, fmt.Errorf("%s is not supported, please submit a valid kind", in.GetKind())
+	}
+	switch in.GetType() {
+	case "", "int", "float64":
+	default:
+		return nil, fmt.Errorf("%s is not supported, please submit a valid type", in.GetType())
+	}
+	return s.snapshotState(func(kind string, key sketchKey) bool {
+		return (in.GetKind() == "" || in.GetKind() == kind) &&
+			(in.GetType() == "" || in.GetType() == key.typ) &&
+			(in.GetAll() || in.GetName() == key.name)
+	}), nil
+}
+
+// ImportSketch merges the exported sketches of in into the server, or replaces
+// the server's sketches of the same kind, name and type with them.
+func (s *Server) ImportSketch(_ context.Context, in *pb.ImportRequest) (*pb.ImportReply, error) {
+	var replace bool
+	switch in.GetMode() {
+	case "", "merge":
+	case "replace":
+		replace = true
+	default:
+		return nil, fmt.Errorf("%s is not supported, please submit a valid mode", in.GetMode())
+	}
+	state := in.GetSketches()
+	if state == nil {
+		state = &pb.ServerState{}
+	}
+	if in.Name != nil {
+		state = proto.Clone(state).(*pb.ServerState)
+		for _, sketch := range state.Kll {
+			sketch.Name = in.GetName()
+		}
+		for _, sketch := range state.Count {
+			sketch.Name = in.GetName()
+		}
+		for _, sketch := range state.Hll {
+			sketch.Name = in.GetName()
+		}
+		for _, sketch := range state.Asketch {
+			sketch.Name = in.GetName()
+		}
+	}
+	n, err := s.importState(state, replace)
+	if err != nil && n > 0 {
+		return nil, fmt.Errorf("imported %d of the sketches: %w", n, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ImportReply{Imported: int64(n)}, nil
+}
@@ -5,84 +5,156 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
 	"sync"
+	"syscall"
 	"time"
 
+	"github.com/bruhng/distributed-sketching/metrics"
 	pb "github.com/bruhng/distributed-sketching/proto"
-	"github.com/bruhng/distributed-sketching/shared"
-	"github.com/bruhng/distributed-sketching/sketches/asketch"
-	"github.com/bruhng/distributed-sketching/sketches/count"
-	"github.com/bruhng/distributed-sketching/sketches/kll"
+	"github.com/bruhng/distributed-sketching/security"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
+// Server keeps the sketches merged into it. Every server has its own sketches,
+// the zero value is an empty server ready to use that authorizes nothing and
+// records no metrics, NewServer configures one with options.
 type Server struct {
 	pb.UnimplementedSketcherServer
+
+	// one map per kind from sketchKey to the sketch, each guarded by its mutex
+	kllStateMap     sync.Map
+	kllMutex        sync.Mutex
+	countStateMap   sync.Map
+	countMutex      sync.Mutex
+	hllStateMap     sync.Map
+	hllMutex        sync.Mutex
+	asketchStateMap sync.Map
+	asketchMutex    sync.Mutex
+
+	// the centralized baselines, one sketch per type name
+	badKllStateMap   sync.Map
+	badKllMutex      sync.Mutex
+	badCountStateMap sync.Map
+	badCountMutex    sync.Mutex
+
+	mergeStatsMap sync.Map
+	statsMutex    sync.Mutex
+
+	// set by the options of NewServer
+	tokens   security.Tokens
+	tls      security.TLSConfig
+	registry *metrics.Registry
+	merges   *mergeMetrics
+	exports  http.ServeMux // the endpoints added by ExportSketches
+
+	// set by Init and replaced by RestartServer
+	grpcServer *grpc.Server
+	listener   net.Listener
+	savedPort  string
+
+	restartWg      sync.WaitGroup
+	restartWaiting bool
+	restartMu      sync.Mutex
+	restarts       int
 }
 
-func (s *Server) TestLatency(_ context.Context, in *pb.EmptyMessage) (*pb.EmptyMessage, error) {
-	return &pb.EmptyMessage{}, nil
+// NewServer returns an empty server configured by opts. Without WithMetrics it
+// records its merge metrics to a registry of its own.
+func NewServer(opts ...Option) *Server {
+	s := &Server{}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.registry == nil {
+		s.registry = &metrics.Registry{}
+	}
+	s.merges = newMergeMetrics(s.registry)
+	return s
 }
 
-var grpcServer *grpc.Server
-var listener net.Listener
-var savedPort string
+// sketchKey identifies one independent sketch of a kind on the server. Clients
+// that do not name their sketch share the default one with an empty name.
+type sketchKey struct {
+	name string
+	typ  string // "int" or "float64"
+}
 
-var wg sync.WaitGroup
-var waiting bool = false
-var mu sync.Mutex
-var restarts int = 0
+func newSketchKey[T any](name string) sketchKey {
+	return sketchKey{name: name, typ: fmt.Sprintf("%T", *new(T))}
+}
+
+// loadState returns the sketch of kind stored under name in m without creating
+// it, queries of sketches nothing was merged into fail with NotFound.
+func loadState[S any, T any](m *sync.Map, kind string, name string) (S, error) {
+	key := newSketchKey[T](name)
+	if v, ok := m.Load(key); ok {
+		return v.(S), nil
+	}
+	var none S
+	return none, status.Errorf(codes.NotFound, "there is no %s sketch %q of type %s", kind, name, key.typ)
+}
+
+func (s *Server) TestLatency(_ context.Context, in *pb.EmptyMessage) (*pb.EmptyMessage, error) {
+	return &pb.EmptyMessage{}, nil
+}
 
+// RestartServer resets the server once NumMsg clients asked for it. A server
+// started by Init also stops and listens on its port again.
 func (s *Server) RestartServer(ctx context.Context, in *pb.RestartMessage) (*pb.EmptyMessage, error) {
-	mu.Lock()
-	if !waiting {
-		waiting = true
-		wg.Add(int(in.NumMsg))
-		mu.Unlock()
-		wg.Done()
-		wg.Wait()
-		waiting = false
+	s.restartMu.Lock()
+	if !s.restartWaiting {
+		s.restartWaiting = true
+		s.restartWg.Add(int(in.NumMsg))
+		s.restartMu.Unlock()
+		s.restartWg.Done()
+		s.restartWg.Wait()
+		s.restartWaiting = false
 		go func() {
-			fmt.Println("Restarting...", restarts)
-			restarts++
+			fmt.Println("Restarting...", s.restarts)
+			s.restarts++
 			time.Sleep(1 * time.Second)
-			restartServer()
+			s.restartServer()
 		}()
 		return &pb.EmptyMessage{}, nil
 	}
-	mu.Unlock()
-	wg.Done()
-	wg.Wait()
+	s.restartMu.Unlock()
+	s.restartWg.Done()
+	s.restartWg.Wait()
 	return &pb.EmptyMessage{}, nil
 
 }
 
-func restartServer() {
+func (s *Server) restartServer() {
 
-	if grpcServer != nil {
-		grpcServer.Stop()
+	if s.grpcServer != nil {
+		s.grpcServer.Stop()
 	}
 
-	if listener != nil {
-		listener.Close()
+	if s.listener != nil {
+		s.listener.Close()
 	}
 
-	resetState()
-	startServer()
+	s.resetState()
+	if s.savedPort != "" {
+		s.startServer()
+	}
 
 }
 
-func resetState() {
-	kllStateMap.Store("int", kll.NewKLLSketch[int](200))
-	kllStateMap.Store("float64", kll.NewKLLSketch[float64](200))
-	badCountStateMap.Store("int", count.NewCountSketch[int](157, 100, 10))
-	badCountStateMap.Store("float64", count.NewCountSketch[float64](157, 100, 10))
-	badKllStateMap.Store("int", kll.NewKLLSketch[int](200))
-	badKllStateMap.Store("float64", kll.NewKLLSketch[float64](200))
-	asketchStateMap.Store("int", asketch.NewASketch[int](shared.ASketchSeed, shared.ASketchWidth, shared.ASketchDepth, shared.ASketchSlots))
-	asketchStateMap.Store("float64", asketch.NewASketch[float64](shared.ASketchSeed, shared.ASketchWidth, shared.ASketchDepth, shared.ASketchSlots))
+func (s *Server) resetState() {
+	// named sketches are created lazily on their first merge
+	s.kllStateMap.Clear()
+	s.countStateMap.Clear()
+	s.hllStateMap.Clear()
+	s.badCountStateMap.Clear()
+	s.badKllStateMap.Clear()
+	s.asketchStateMap.Clear()
+	s.mergeStatsMap.Clear()
 }
 
 func PanicRecoveryInterceptor(
@@ -107,31 +179,69 @@ func PanicRecoveryInterceptor(
 	return handler(ctx, req)
 }
 
-func Init(port string) {
-	savedPort = port
-	startServer()
-	for {
+// Init serves s on port until SIGINT or SIGTERM. It then stops accepting
+// requests, lets running merges finish and writes the state to statePath,
+// which is also read on start. An empty statePath disables persistence.
+func (s *Server) Init(port string, statePath string) {
+	if statePath != "" {
+		if err := s.loadState(statePath); err != nil {
+			log.Fatalf("Failed to load state: %v", err)
+		}
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	s.savedPort = port
+	s.listen()
+	go s.serve()
+	<-ctx.Done()
+	stop()
+
+	log.Println("Shutting down, waiting for running requests")
+	s.grpcServer.GracefulStop()
+	if statePath != "" {
+		if err := s.saveState(statePath); err != nil {
+			log.Fatalf("Failed to save state: %v", err)
+		}
+		log.Printf("State saved to %s", statePath)
 	}
-
 }
 
-func startServer() {
+func (s *Server) startServer() {
+	s.listen()
+	s.serve()
+}
 
+func (s *Server) listen() {
 	var err error
-	// if listener == nil {
-	listener, err = net.Listen("tcp", ":"+savedPort)
+	s.listener, err = net.Listen("tcp", ":"+s.savedPort)
 	if err != nil {
 		panic(fmt.Sprint("listen error: ", err))
 
 	}
 
-	grpcServer = grpc.NewServer(
-		grpc.UnaryInterceptor(PanicRecoveryInterceptor),
+	creds, err := s.tls.ServerCredentials()
+	if err != nil {
+		panic(fmt.Sprint("TLS configuration error: ", err))
+	}
+	s.grpcServer = s.NewGRPCServer(grpc.Creds(creds))
+}
+
+// NewGRPCServer returns a gRPC server running s behind the interceptors of
+// Init, for serving on a listener of one's own.
+func (s *Server) NewGRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(PanicRecoveryInterceptor, s.AuthInterceptor, s.MetricsInterceptor),
 		grpc.MaxConcurrentStreams(100_000),
-	)
-	pb.RegisterSketcherServer(grpcServer, &Server{})
-	if err := grpcServer.Serve(listener); err != nil {
+	}, opts...)
+	g := grpc.NewServer(opts...)
+	pb.RegisterSketcherServer(g, s)
+	return g
+}
+
+func (s *Server) serve() {
+	log.Printf("Server listening at %v", s.listener.Addr())
+	if err := s.grpcServer.Serve(s.listener); err != nil {
 		log.Fatalf("Failed to serve: %v", err)
 	}
-	log.Printf("Server listening at %v", listener.Addr())
 }
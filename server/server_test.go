@@ -2,226 +2,145 @@ package server_test
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	crand "crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
-	"log"
+	"io"
+	"math"
+	"math/big"
 	"math/rand"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/bruhng/distributed-sketching/metrics"
 	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/security"
 	"github.com/bruhng/distributed-sketching/server"
+	"github.com/bruhng/distributed-sketching/server/servertest"
+	"github.com/bruhng/distributed-sketching/shared"
+	"github.com/bruhng/distributed-sketching/sketches/convert"
 	"github.com/bruhng/distributed-sketching/stream"
 
 	"github.com/bruhng/distributed-sketching/client"
+	"github.com/bruhng/distributed-sketching/sketches/asketch"
 	"github.com/bruhng/distributed-sketching/sketches/count"
+	"github.com/bruhng/distributed-sketching/sketches/hll"
 	"github.com/bruhng/distributed-sketching/sketches/kll"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/connectivity"
-	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 var DATA_SET_PATH = "../data/PVS 1/dataset_gps.csv"
 var HEADER_NAME = "speed_meters_per_second"
-var PORT = "8080"
-var SERVER_ADR = "127.0.0.1"
 var NUM_CLIENTS = 10
 var NUM_STREAM_RUNS = 10
-var STREAM_DELAY = 0
-
-const bufSize = 1024 * 1024 * 100
-
-var lis *bufconn.Listener
-
-var clientAmounts []int = []int{512, 256, 128, 64, 32}
-
-var streamRates []int = []int{0, 10, 100, 1000}
-
 var samples int = 1000
 
-func BenchmarkThroughputKll(b *testing.B) {
-	go server.Init(PORT)
-	time.Sleep(500 * time.Millisecond)
-	var wg sync.WaitGroup
-	var fg sync.WaitGroup
-	var mu sync.Mutex
-	cond := sync.NewCond(&mu)
-	for clientAmount := 1; clientAmount <= 10000; clientAmount *= 10 {
-		for mergeRate := 1; mergeRate <= 10000; mergeRate *= 10 {
-			for _, streamRate := range streamRates {
-				b.Run(fmt.Sprintf("Clients: %d,MergeRate: %d,StreamRate: %d", clientAmount, mergeRate, streamRate), func(pb *testing.B) {
-					pb.StopTimer()
-					for range clientAmount {
-						wg.Add(1)
-						fg.Add(1)
-						go func() {
-							wg.Done()
-							cond.L.Lock()
-
-							cond.Wait()
-							cond.L.Unlock()
-
-							client.Init[float64](PORT, SERVER_ADR, "kll", DATA_SET_PATH, HEADER_NAME, 10, streamRate, mergeRate)
-							fg.Done()
-						}()
-					}
-					wg.Wait()
-					pb.ResetTimer()
-					pb.StartTimer()
-					cond.Broadcast()
-					fg.Wait()
-					pb.StopTimer()
-					client.RestartServer(SERVER_ADR, PORT, 1)
-					time.Sleep(time.Second)
-				})
-			}
-		}
-	}
-}
-
-func BenchmarkThroughputCount(b *testing.B) {
-	go server.Init(PORT)
-	time.Sleep(500 * time.Millisecond)
-	var wg sync.WaitGroup
-	var fg sync.WaitGroup
-	var mu sync.Mutex
-	cond := sync.NewCond(&mu)
-	for clientAmount := 1; clientAmount <= 10000; clientAmount *= 10 {
-		for mergeRate := 1; mergeRate <= 10000; mergeRate *= 10 {
-			for _, streamRate := range streamRates {
-				b.Run(fmt.Sprintf("Clients: %d,MergeRate: %d,StreamRate: %d", clientAmount, mergeRate, streamRate), func(pb *testing.B) {
-					for range clientAmount {
-						wg.Add(1)
-						fg.Add(1)
-						go func() {
-							wg.Done()
-							cond.L.Lock()
-
-							cond.Wait()
-							cond.L.Unlock()
-
-							client.Init[float64](PORT, SERVER_ADR, "count", DATA_SET_PATH, HEADER_NAME, 10, streamRate, mergeRate)
-							fg.Done()
-						}()
-					}
-					wg.Wait()
-					pb.ResetTimer()
-					pb.StartTimer()
-					cond.Broadcast()
-					fg.Wait()
-					pb.StopTimer()
-					client.RestartServer(SERVER_ADR, PORT, 1)
-					time.Sleep(time.Second)
-				})
-			}
-		}
-	}
-}
-
 func TestServerLatencyKll(t *testing.T) {
+	skipWithoutDataSet(t)
 	var reconAttempt *int = new(int)
 	*reconAttempt = 0
-	go server.Init(PORT)
-	time.Sleep(500 * time.Millisecond)
-	c, conn, err := startRealConnection(SERVER_ADR + ":" + PORT)
-	if err != nil {
-		panic("Could not start benchmark")
-	}
 
 	for mergeRate := 1000; mergeRate <= 1000000; mergeRate *= 2 {
+		srv := servertest.NewServer(t)
+		c, conn, err := srv.Dial("")
+		if err != nil {
+			t.Fatal(err)
+		}
 		dataStream := *stream.NewStreamFromCsv[float64](DATA_SET_PATH, HEADER_NAME, 0, 10*mergeRate)
 		sketch := client.GetKll(200, mergeRate, dataStream)
 
 		fmt.Println("Mergerate:", mergeRate)
 		for range 1000 {
-			client.MakeRequest(sketch, SERVER_ADR+":"+PORT, c.MergeKll, conn, &c, startRealConnection, reconAttempt)
+			client.MakeRequest(sketch, "bufnet", c.MergeKll, conn, &c, srv.Dial, reconAttempt)
 		}
-
-		client.RestartServer(SERVER_ADR, PORT, 1)
-		time.Sleep(1 * time.Second)
+		conn.Close()
 	}
 }
 func TestServerLatencyCount(t *testing.T) {
+	skipWithoutDataSet(t)
 	var reconAttempt *int = new(int)
 	*reconAttempt = 0
-	go server.Init(PORT)
-	time.Sleep(500 * time.Millisecond)
-	c, conn, err := startRealConnection(SERVER_ADR + ":" + PORT)
-	if err != nil {
-		panic("Could not start benchmark")
-	}
 
 	for mergeRate := 1000; mergeRate <= 1000000; mergeRate *= 2 {
+		srv := servertest.NewServer(t)
+		c, conn, err := srv.Dial("")
+		if err != nil {
+			t.Fatal(err)
+		}
 		dataStream := *stream.NewStreamFromCsv[float64](DATA_SET_PATH, HEADER_NAME, 0, 10*mergeRate)
 		sketch := client.GetCount(mergeRate, dataStream)
 
 		fmt.Println("Mergerate:", mergeRate)
 		for range 1000 {
-			client.MakeRequest(sketch, SERVER_ADR+":"+PORT, c.MergeCount, conn, &c, startRealConnection, reconAttempt)
+			client.MakeRequest(sketch, "bufnet", c.MergeCount, conn, &c, srv.Dial, reconAttempt)
 		}
-
-		client.RestartServer(SERVER_ADR, PORT, 1)
-		time.Sleep(1 * time.Second)
+		conn.Close()
 	}
 }
 func TestServerLatencyCenteralizedKll(t *testing.T) {
+	skipWithoutDataSet(t)
 	var reconAttempt *int = new(int)
 	*reconAttempt = 0
-	go server.Init(PORT)
-	time.Sleep(500 * time.Millisecond)
-	c, conn, err := startRealConnection(SERVER_ADR + ":" + PORT)
-	if err != nil {
-		panic("Could not start benchmark")
-	}
 
 	for mergeRate := 1000; mergeRate <= 1000000; mergeRate *= 2 {
+		srv := servertest.NewServer(t)
+		c, conn, err := srv.Dial("")
+		if err != nil {
+			t.Fatal(err)
+		}
 		dataStream := *stream.NewStreamFromCsv[float64](DATA_SET_PATH, HEADER_NAME, 0, 10*mergeRate)
 		sketch := client.GetBad(mergeRate, dataStream)
 
 		fmt.Println("Mergerate:", mergeRate)
 		for range 1000 {
-			client.MakeRequest(sketch, SERVER_ADR+":"+PORT, c.BadKll, conn, &c, startRealConnection, reconAttempt)
+			client.MakeRequest(sketch, "bufnet", c.BadKll, conn, &c, srv.Dial, reconAttempt)
 		}
-
-		client.RestartServer(SERVER_ADR, PORT, 1)
-		time.Sleep(1 * time.Second)
+		conn.Close()
 	}
 }
 func TestServerLatencyCenteralizedCount(t *testing.T) {
+	skipWithoutDataSet(t)
 	var reconAttempt *int = new(int)
 	*reconAttempt = 0
-	go server.Init(PORT)
-	time.Sleep(500 * time.Millisecond)
-	c, conn, err := startRealConnection(SERVER_ADR + ":" + PORT)
-	if err != nil {
-		panic("Could not start benchmark")
-	}
 
 	for mergeRate := 1000; mergeRate <= 1000000; mergeRate *= 2 {
+		srv := servertest.NewServer(t)
+		c, conn, err := srv.Dial("")
+		if err != nil {
+			t.Fatal(err)
+		}
 		dataStream := *stream.NewStreamFromCsv[float64](DATA_SET_PATH, HEADER_NAME, 0, 10*mergeRate)
 		sketch := client.GetBad(mergeRate, dataStream)
 
 		fmt.Println("Mergerate:", mergeRate)
 		for range 1000 {
-			client.MakeRequest(sketch, SERVER_ADR+":"+PORT, c.BadCount, conn, &c, startRealConnection, reconAttempt)
+			client.MakeRequest(sketch, "bufnet", c.BadCount, conn, &c, srv.Dial, reconAttempt)
 		}
-
-		client.RestartServer(SERVER_ADR, PORT, 1)
-		time.Sleep(1 * time.Second)
+		conn.Close()
 	}
 }
-
 func BenchmarkPinger(b *testing.B) {
 	samples = 1000
-	conn, err := grpc.NewClient(SERVER_ADR+":"+PORT, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		fmt.Println(err)
-		panic("Could not connect to server")
-	}
-	defer conn.Close()
-	c := pb.NewSketcherClient(conn)
+	c := servertest.NewServer(b).Client(b)
 	var latencies = make([]int, samples)
 	for i := range samples {
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
@@ -237,86 +156,935 @@ func BenchmarkPinger(b *testing.B) {
 		diff := int(after.Sub(prev).Nanoseconds())
 		latencies[i] = diff
 	}
-	client.RestartServer(SERVER_ADR, PORT, 1)
 	fmt.Println(latencies)
 }
 
-func startRealConnection(adr string) (pb.SketcherClient, *grpc.ClientConn, error) {
+// skipWithoutDataSet skips tests replaying the default data set, which is not
+// part of the repository
+func skipWithoutDataSet(t testing.TB) {
+	if _, err := os.Stat(DATA_SET_PATH); err != nil {
+		t.Skipf("%s is not available", DATA_SET_PATH)
+	}
+}
+
+func BenchmarkKllMergeInt(b *testing.B) {
+	b.StopTimer()
+	ctx := context.Background()
+	server := servertest.NewServer(b).Client(b)
+	sketch := kll.NewKLLSketch[int](200)
+	server.MergeKll(ctx, convert.ToProtoKLL(sketch))
+
+	for range 100 {
+		sketch.Add(rand.Intn(20))
+	}
+	b.StartTimer()
+
+	for range b.N {
+		server.MergeKll(ctx, convert.ToProtoKLL(sketch))
+	}
+}
+
+func BenchmarkCountMergeInt(b *testing.B) {
+	b.StopTimer()
+	ctx := context.Background()
+	server := servertest.NewServer(b).Client(b)
+	sketch := count.NewCountSketch[int](111, 50, 5)
+	server.MergeCount(ctx, convert.ToProtoCount(sketch))
+	for range 100 {
+		sketch.Add(rand.Intn(100))
+	}
+	b.StartTimer()
+
+	for range b.N {
+		server.MergeCount(ctx, convert.ToProtoCount(sketch))
+	}
+}
+
+func TestASketchPerField(t *testing.T) {
+	ctx := context.Background()
+	c := servertest.NewServer(t).Client(t)
+
+	speeds := asketch.NewASketch[int](shared.ASketchSeed, shared.ASketchWidth, shared.ASketchDepth, shared.ASketchSlots)
+	events := asketch.NewASketch[int](shared.ASketchSeed, shared.ASketchWidth, shared.ASketchDepth, shared.ASketchSlots)
+	speeds.AddBy(7, 100)
+	events.AddBy(42, 50)
+	if _, err := c.MergeASketch(ctx, convert.ToProtoASketch(speeds, "test_speed")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.MergeASketch(ctx, convert.ToProtoASketch(events, "test_event")); err != nil {
+		t.Fatal(err)
+	}
+
+	for field, want := range map[string]int64{"test_speed": 7, "test_event": 42} {
+		res, err := c.TopKASketch(ctx, &pb.TopKRequest{K: 1, Type: "int", Name: field})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Entries) != 1 || res.Entries[0].Key.GetIntVal() != want {
+			t.Fatalf("top-1 of %s = %v, want %d", field, res.Entries, want)
+		}
+	}
+	res, err := c.QueryASketch(ctx, &pb.NumericValue{Value: &pb.NumericValue_IntVal{IntVal: 7}, Name: "test_event"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Res != 0 {
+		t.Fatalf("test_event counted %d of the value merged into test_speed", res.Res)
+	}
 
-	conn, err := grpc.NewClient(adr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	fields, err := c.ListFields(ctx, &pb.ListFieldsRequest{Type: "int"})
 	if err != nil {
-		return nil, nil, err
-	}
-	c := pb.NewSketcherClient(conn)
-	conn.Connect()
-	// wait for the connection to be established
-	for {
-		state := conn.GetState()
-		if state == connectivity.Idle || state == connectivity.Connecting {
-			continue
-		} else if state == connectivity.Ready {
-			break
-		} else {
-			return c, conn, fmt.Errorf("Could not establish connection to server")
+		t.Fatal(err)
+	}
+	found := map[string]bool{}
+	for _, f := range fields.Fields {
+		found[f.Field] = true
+	}
+	if !found["test_speed"] || !found["test_event"] {
+		t.Fatalf("ListFields = %v, want test_speed and test_event", fields.Fields)
+	}
+
+	// reads of unknown fields do not create them
+	unknown := &pb.NumericValue{Value: &pb.NumericValue_IntVal{IntVal: 7}, Name: "test_unknown"}
+	if _, err := c.QueryASketch(ctx, unknown); status.Code(err) != codes.NotFound {
+		t.Errorf("query of an unknown field: %v, want NotFound", err)
+	}
+	if _, err := c.TopKASketch(ctx, &pb.TopKRequest{K: 1, Type: "int", Name: "test_unknown"}); status.Code(err) != codes.NotFound {
+		t.Errorf("top-k of an unknown field: %v, want NotFound", err)
+	}
+	if _, err := c.DumpFilter(ctx, &pb.DumpFilterRequest{Type: "int", Name: "test_unknown"}); status.Code(err) != codes.NotFound {
+		t.Errorf("filter of an unknown field: %v, want NotFound", err)
+	}
+	if fields, _ = c.ListFields(ctx, &pb.ListFieldsRequest{}); len(fields.Fields) != 2 {
+		t.Errorf("ListFields = %v after queries of unknown fields", fields.Fields)
+	}
+}
+
+func TestNamedSketches(t *testing.T) {
+	ctx := context.Background()
+	c := servertest.NewServer(t).Client(t)
+
+	speeds := kll.NewKLLSketch[float64](200)
+	vehicles := hll.NewHLLSketch[int](shared.HLLRegisters, shared.HLLSeed)
+	for i := range 1000 {
+		speeds.Add(float64(i))
+		vehicles.Add(i % 100)
+	}
+	protoKll := convert.ToProtoKLL(speeds)
+	protoKll.Name = "test_speed"
+	if _, err := c.MergeKll(ctx, protoKll); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.MergeHll(ctx, convert.ToProtoHll(vehicles, "test_vehicles")); err != nil {
+		t.Fatal(err)
+	}
+
+	rank, err := c.QueryKll(ctx, &pb.NumericValue{Value: &pb.NumericValue_FloatVal{FloatVal: 499}, Type: "float64", Name: "test_speed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rank.N != 1000 {
+		t.Fatalf("test_speed has N = %d, want 1000", rank.N)
+	}
+	card, err := c.QueryHll(ctx, &pb.CardinalityRequest{Type: "int", Name: "test_vehicles"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if card.Estimate < 90 || card.Estimate > 110 {
+		t.Fatalf("test_vehicles cardinality = %.1f, want about 100", card.Estimate)
+	}
+	// queries of sketches nothing was merged into neither answer nor create them
+	for query, call := range map[string]func() error{
+		"QueryHll": func() error {
+			_, err := c.QueryHll(ctx, &pb.CardinalityRequest{Type: "int", Name: "test_other"})
+			return err
+		},
+		"QueryKll": func() error {
+			_, err := c.QueryKll(ctx, &pb.NumericValue{Value: &pb.NumericValue_IntVal{IntVal: 1}, Type: "int", Name: "test_other"})
+			return err
+		},
+		"ReverseQueryKll": func() error {
+			_, err := c.ReverseQueryKll(ctx, &pb.ReverseQuery{Phi: 0.5, Type: "int", Name: "test_other"})
+			return err
+		},
+		"PlotKll": func() error {
+			_, err := c.PlotKll(ctx, &pb.PlotRequest{NumBins: 4, Type: "int", Name: "test_other"})
+			return err
+		},
+		"QueryCount": func() error {
+			_, err := c.QueryCount(ctx, &pb.NumericValue{Value: &pb.NumericValue_IntVal{IntVal: 1}, Type: "int", Name: "test_other"})
+			return err
+		},
+	} {
+		if err := call(); status.Code(err) != codes.NotFound {
+			t.Errorf("%s of an unmerged sketch: got %v, want NotFound", query, err)
+		}
+	}
+	if info, err := c.DescribeSketch(ctx, &pb.DescribeRequest{Name: "test_other"}); err != nil || len(info.Sketches) != 0 {
+		t.Fatalf("queries created sketches %v, %v", info, err)
+	}
+}
+
+func TestCountQueryByName(t *testing.T) {
+	ctx := context.Background()
+	c := servertest.NewServer(t).Client(t)
+
+	speeds := count.NewCountSketch[float64](157, 100, 10)
+	lanes := count.NewCountSketch[int](157, 100, 10)
+	for i := range 1000 {
+		speeds.Add(float64(i % 4))
+		lanes.Add(i % 2)
+	}
+	protoSpeeds := convert.ToProtoCount(speeds)
+	protoSpeeds.Name = "test_count"
+	protoLanes := convert.ToProtoCount(lanes)
+	protoLanes.Name = "test_count"
+	for _, sketch := range []*pb.CountSketch{protoSpeeds, protoLanes} {
+		if _, err := c.MergeCount(ctx, sketch); err != nil {
+			t.Fatal(err)
 		}
 	}
-	return c, conn, nil
+
+	// float64 queries read the float64 count sketch of the name
+	res, err := c.QueryCount(ctx, &pb.NumericValue{Value: &pb.NumericValue_FloatVal{FloatVal: 2}, Type: "float64", Name: "test_count"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Res != 250 {
+		t.Errorf("count of 2.0 is %d, want 250", res.Res)
+	}
+	res, err = c.QueryCount(ctx, &pb.NumericValue{Value: &pb.NumericValue_IntVal{IntVal: 1}, Type: "int", Name: "test_count"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Res != 500 {
+		t.Errorf("count of 1 is %d, want 500", res.Res)
+	}
+}
+
+// shutdown sends SIGTERM to the test process once Init had time to listen for it
+func shutdown(t *testing.T, done chan struct{}) {
+	guard := make(chan os.Signal, 1)
+	signal.Notify(guard, syscall.SIGTERM)
+	defer signal.Stop(guard)
+	time.Sleep(200 * time.Millisecond)
+	syscall.Kill(os.Getpid(), syscall.SIGTERM)
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("server did not shut down")
+	}
 }
 
-func init() {
-	lis = bufconn.Listen(bufSize)
-	s := grpc.NewServer()
-	pb.RegisterSketcherServer(s, &server.Server{})
+func TestStatePersistedOnShutdown(t *testing.T) {
+	ctx := context.Background()
+	srv := servertest.NewServer(t)
+	c := srv.Client(t)
+
+	sketch := kll.NewKLLSketch[int](200)
+	for i := range 500 {
+		sketch.Add(i)
+	}
+	protoKll := convert.ToProtoKLL(sketch)
+	protoKll.Name = "test_persisted"
+	if _, err := c.MergeKll(ctx, protoKll); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "state.pb")
+	done := make(chan struct{})
 	go func() {
-		if err := s.Serve(lis); err != nil {
-			log.Fatalf("Server exited with error: %v", err)
+		srv.Init("0", path)
+		close(done)
+	}()
+	shutdown(t, done)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := &pb.ServerState{}
+	if err := proto.Unmarshal(data, state); err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, saved := range state.Kll {
+		if saved.Name == "test_persisted" && saved.Type == "int" {
+			found = saved.N == 500
 		}
+	}
+	if !found {
+		t.Fatal("test_persisted was not saved with N = 500")
+	}
+
+	// loading merges the saved sketch into the running state again
+	done = make(chan struct{})
+	go func() {
+		srv.Init("0", path)
+		close(done)
 	}()
+	shutdown(t, done)
+	rank, err := c.QueryKll(ctx, &pb.NumericValue{Value: &pb.NumericValue_IntVal{IntVal: 250}, Type: "int", Name: "test_persisted"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rank.N != 1000 {
+		t.Fatalf("after loading N = %d, want 1000", rank.N)
+	}
 }
 
-func bufDialer(context.Context, string) (net.Conn, error) {
-	return lis.Dial()
+func TestMergeMetrics(t *testing.T) {
+	ctx := context.Background()
+	r := &metrics.Registry{}
+	srv := servertest.Serve(t, server.NewServer(server.WithMetrics(r)))
+	c := srv.Client(t)
+
+	sketch := kll.NewKLLSketch[float64](200)
+	for i := range 300 {
+		sketch.Add(float64(i))
+	}
+	protoKll := convert.ToProtoKLL(sketch)
+	protoKll.Name = "test_metrics"
+	for range 2 {
+		if _, err := c.MergeKll(ctx, protoKll); err != nil {
+			t.Fatal(err)
+		}
+	}
+	distinct := hll.NewHLLSketch[int](shared.HLLRegisters, shared.HLLSeed)
+	events := asketch.NewASketch[int](shared.ASketchSeed, shared.ASketchWidth, shared.ASketchDepth, shared.ASketchSlots)
+	counts := count.NewCountSketch[float64](157, 100, 10)
+	for i := range 500 {
+		distinct.Add(i % 100)
+		events.Add(i % 40)
+		counts.Add(float64(i % 7))
+	}
+	if _, err := c.MergeHll(ctx, convert.ToProtoHll(distinct, "test_metrics")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.MergeASketch(ctx, convert.ToProtoASketch(events, "test_metrics")); err != nil {
+		t.Fatal(err)
+	}
+	protoCount := convert.ToProtoCount(counts)
+	protoCount.Name = "test_metrics"
+	if _, err := c.MergeCount(ctx, protoCount); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.MergeKll(ctx, &pb.KLLSketch{Type: "string"}); err == nil {
+		t.Fatal("merging an unsupported type did not fail")
+	}
+
+	srv.RegisterMetrics(r)
+	addr, err := srv.ServeMetrics("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get("http://" + addr.String() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	for _, want := range []string{
+		`sketch_server_merge_errors_total{kind="kll"} 1`,
+		`sketch_server_merge_duration_seconds_count{kind="kll"}`,
+		`sketch_server_received_bytes_total{kind="kll"}`,
+		`sketch_server_sketch_n{kind="kll",name="test_metrics",type="float64"} 600`,
+		`sketch_server_sketch_n{kind="asketch",name="test_metrics",type="int"} 500`,
+		`sketch_server_sketch_merges{kind="kll",name="test_metrics",type="float64"} 2`,
+		`sketch_server_sketch_merges{kind="count",name="test_metrics",type="float64"} 1`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics do not contain %s", want)
+		}
+	}
+	_, estimate, _ := strings.Cut(string(body), `sketch_server_sketch_n{kind="hll",name="test_metrics",type="int"} `)
+	estimate, _, _ = strings.Cut(estimate, "\n")
+	if n, err := strconv.ParseFloat(estimate, 64); err != nil || n < 95 || n > 105 {
+		t.Errorf("distinct items of the hll gauge = %q, want about 100", estimate)
+	}
 }
 
-func BenchmarkKllMergeInt(b *testing.B) {
-	b.StopTimer()
+func TestExportSketches(t *testing.T) {
 	ctx := context.Background()
-	conn, err := grpc.NewClient("bufnet", grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(1024*1024)), grpc.WithContextDialer(bufDialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	srv := servertest.NewServer(t)
+	c := srv.Client(t)
+
+	speeds := kll.NewKLLSketch[float64](200)
+	events := asketch.NewASketch[int](shared.ASketchSeed, shared.ASketchWidth, shared.ASketchDepth, shared.ASketchSlots)
+	counts := count.NewCountSketch[int](157, 100, 10)
+	for i := range 1000 {
+		speeds.Add(float64(i))
+		events.AddBy(i%50, 1+i%50)
+		counts.Add(i % 10)
+	}
+	counts.Add(3)
+	protoKll := convert.ToProtoKLL(speeds)
+	protoKll.Name = "test_export"
+	protoCount := convert.ToProtoCount(counts)
+	protoCount.Name = "test_export"
+	if _, err := c.MergeKll(ctx, protoKll); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.MergeCount(ctx, protoCount); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.MergeASketch(ctx, convert.ToProtoASketch(events, "test_export")); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.ExportSketches("/test-sketches", server.ExportConfig{Phis: []float64{0, 1}, TopK: 2, CountItems: []float64{1, 3, 5}})
+	addr, err := srv.ServeMetrics("127.0.0.1:0")
 	if err != nil {
-		b.Fatalf("Failed to dial bufnet: %v", err)
+		t.Fatal(err)
+	}
+	resp, err := http.Get("http://" + addr.String() + "/test-sketches")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	for _, want := range []string{
+		`# TYPE sketch_kll_value summary`,
+		`sketch_kll_value{name="test_export",type="float64",quantile="1"} 9`,
+		`sketch_kll_value_count{name="test_export",type="float64"} 1000`,
+		`sketch_asketch_topk_count{name="test_export",type="int",rank="1",item="49"}`,
+		`sketch_asketch_topk_count{name="test_export",type="int",rank="2",item="48"}`,
+		`sketch_count_topk_count{name="test_export",type="int",rank="1",item="3"} 101`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("exported sketches do not contain %s", want)
+		}
+	}
+	// compaction may drop the smallest items, the minimum is only close to 0
+	minLine := `sketch_kll_value{name="test_export",type="float64",quantile="0"} `
+	if i := strings.Index(string(body), minLine); i < 0 {
+		t.Errorf("exported sketches do not contain %s", minLine)
+	} else if min, err := strconv.ParseFloat(strings.Fields(string(body)[i+len(minLine):])[0], 64); err != nil || min > 50 {
+		t.Errorf("exported minimum %v, %v, want about 0", min, err)
+	}
+	if strings.Contains(string(body), `rank="3"`) {
+		t.Error("more than the top 2 items were exported")
 	}
-	defer conn.Close()
-	server := pb.NewSketcherClient(conn)
+}
+
+func getJSON(t *testing.T, url string, wantStatus int) map[string]any {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("GET %s: status %d, want %d", url, resp.StatusCode, wantStatus)
+	}
+	var out map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	return out
+}
+
+func TestGateway(t *testing.T) {
+	ctx := context.Background()
+	srv := servertest.NewServer(t)
+	c := srv.Client(t)
+
+	speeds := kll.NewKLLSketch[float64](200)
+	vehicles := hll.NewHLLSketch[int](shared.HLLRegisters, shared.HLLSeed)
+	events := asketch.NewASketch[int](shared.ASketchSeed, shared.ASketchWidth, shared.ASketchDepth, shared.ASketchSlots)
+	for i := range 100 {
+		speeds.Add(float64(i))
+		vehicles.Add(i % 20)
+		events.AddBy(i%5, i%5+1)
+	}
+	protoKll := convert.ToProtoKLL(speeds)
+	protoKll.Name = "test_gateway"
+	if _, err := c.MergeKll(ctx, protoKll); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.MergeHll(ctx, convert.ToProtoHll(vehicles, "test_gateway")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.MergeASketch(ctx, convert.ToProtoASketch(events, "test_gateway")); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(srv.Gateway())
+	defer ts.Close()
+	base := ts.URL + "/v1/sketches/test_gateway"
+
+	if q := getJSON(t, base+"/quantile?phi=0.5", http.StatusOK); q["value"].(float64) != 49 {
+		t.Errorf("median = %v, want 49", q["value"])
+	}
+	if r := getJSON(t, base+"/rank?x=9", http.StatusOK); r["rank"].(float64) != 10 || r["n"].(float64) != 100 {
+		t.Errorf("rank = %v", r)
+	}
+	topk := getJSON(t, base+"/topk?k=2&type=int", http.StatusOK)["items"].([]any)
+	if len(topk) != 2 || topk[0].(map[string]any)["item"].(float64) != 4 {
+		t.Errorf("topk = %v", topk)
+	}
+	if card := getJSON(t, base+"/cardinality?type=int", http.StatusOK)["estimate"].(float64); card < 18 || card > 22 {
+		t.Errorf("cardinality = %v, want about 20", card)
+	}
+	fields := getJSON(t, ts.URL+"/v1/fields?type=int", http.StatusOK)["fields"].([]any)
+	found := false
+	for _, f := range fields {
+		found = found || f.(map[string]any)["name"] == "test_gateway"
+	}
+	if !found {
+		t.Errorf("fields = %v, missing test_gateway", fields)
+	}
+
+	hist := getJSON(t, base+"/histogram?splits=0,50,99", http.StatusOK)
+	if fmt.Sprint(hist["counts"], hist["mass"]) != "[50 50] [0.5 0.5]" {
+		t.Errorf("histogram = %v", hist)
+	}
+	if counts := getJSON(t, base+"/histogram?bins=4&mode=equidepth", http.StatusOK)["counts"].([]any); len(counts) != 4 {
+		t.Errorf("equi depth histogram counts = %v", counts)
+	}
+
+	getJSON(t, base+"/quantile?phi=2", http.StatusBadRequest)
+	getJSON(t, base+"/histogram?splits=5,1", http.StatusBadRequest)
+	getJSON(t, base+"/histogram?bins=1000000000", http.StatusBadRequest)
+	getJSON(t, base+"/histogram?splits=0,NaN", http.StatusBadRequest)
+	getJSON(t, base+"/histogram?splits=0,Inf", http.StatusBadRequest)
+	getJSON(t, base+"/histogram?mode=pie", http.StatusBadRequest)
+	getJSON(t, base+"/histogram?min=3", http.StatusBadRequest)
+	getJSON(t, base+"/rank", http.StatusBadRequest)
+	getJSON(t, base+"/topk?type=string", http.StatusBadRequest)
+	for _, route := range []string{"topk?type=int", "quantile?phi=0.5", "rank?x=1", "cardinality", "histogram"} {
+		getJSON(t, ts.URL+"/v1/sketches/test_unknown/"+route, http.StatusNotFound)
+	}
+
+	resp, err := http.Get(ts.URL + "/v1/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var doc struct {
+		Paths map[string]any `json:"paths"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil || len(doc.Paths) != 6 {
+		t.Fatalf("openapi.json has paths %v, err %v", doc.Paths, err)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	tokens, err := security.ParseTokens(strings.NewReader(`
+# token role sketches
+p1 producer speeds
+c1 consumer speeds,_
+c2 consumer *
+a1 admin
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := servertest.Serve(t, server.NewServer(server.WithTokens(tokens)))
+	dial := func(token string) pb.SketcherClient {
+		security.Token = token
+		defer func() { security.Token = "" }()
+		opts, err := security.DialOptions()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return srv.Client(t, opts...)
+	}
+	ctx := context.Background()
+	producer, consumer, admin, anonymous := dial("p1"), dial("c1"), dial("a1"), dial("")
+	everything := dial("c2")
+
+	sketch := kll.NewKLLSketch[float64](200)
+	sketch.Add(1)
+	speeds := convert.ToProtoKLL(sketch)
+	speeds.Name = "speeds"
+	other := convert.ToProtoKLL(sketch)
+	other.Name = "other"
+	query := &pb.NumericValue{Value: &pb.NumericValue_FloatVal{FloatVal: 1}, Name: "speeds", Type: "float64"}
+	baseline := &pb.BadArray{Arr: &pb.NumericRow{Values: []*pb.NumericValue{{Value: &pb.NumericValue_FloatVal{FloatVal: 1}}}}, Type: "float64"}
+
+	for _, tc := range []struct {
+		name string
+		call func() error
+		want codes.Code
+	}{
+		{"producer merges", func() error { _, err := producer.MergeKll(ctx, speeds); return err }, codes.OK},
+		{"producer merges other sketch", func() error { _, err := producer.MergeKll(ctx, other); return err }, codes.PermissionDenied},
+		{"producer queries", func() error { _, err := producer.QueryKll(ctx, query); return err }, codes.PermissionDenied},
+		{"consumer queries", func() error { _, err := consumer.QueryKll(ctx, query); return err }, codes.OK},
+		{"consumer merges", func() error { _, err := consumer.MergeKll(ctx, speeds); return err }, codes.PermissionDenied},
+		{"consumer lists fields", func() error { _, err := consumer.ListFields(ctx, &pb.ListFieldsRequest{}); return err }, codes.PermissionDenied},
+		{"consumer of every sketch lists fields", func() error { _, err := everything.ListFields(ctx, &pb.ListFieldsRequest{}); return err }, codes.OK},
+		{"producer merges into the baseline", func() error { _, err := producer.BadKll(ctx, baseline); return err }, codes.PermissionDenied},
+		{"admin merges into the baseline", func() error { _, err := admin.BadKll(ctx, baseline); return err }, codes.OK},
+		{"consumer describes every name", func() error { _, err := consumer.DescribeSketch(ctx, &pb.DescribeRequest{All: true}); return err }, codes.PermissionDenied},
+		{"consumer restarts", func() error { _, err := consumer.RestartServer(ctx, &pb.RestartMessage{}); return err }, codes.PermissionDenied},
+		{"admin merges other sketch", func() error { _, err := admin.MergeKll(ctx, other); return err }, codes.OK},
+		{"anonymous pings", func() error { _, err := anonymous.TestLatency(ctx, &pb.EmptyMessage{}); return err }, codes.Unauthenticated},
+		{"producer pings", func() error { _, err := producer.TestLatency(ctx, &pb.EmptyMessage{}); return err }, codes.OK},
+	} {
+		if got := status.Code(tc.call()); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+
+	ts := httptest.NewServer(srv.Gateway())
+	defer ts.Close()
+	get := func(url string, token string) int {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := get(ts.URL+"/v1/sketches/speeds/rank?x=1", ""); code != http.StatusUnauthorized {
+		t.Errorf("anonymous rank: status %d", code)
+	}
+	if code := get(ts.URL+"/v1/sketches/speeds/rank?x=1", "c1"); code != http.StatusOK {
+		t.Errorf("consumer rank: status %d", code)
+	}
+	if code := get(ts.URL+"/v1/sketches/other/rank?x=1", "c1"); code != http.StatusForbidden {
+		t.Errorf("consumer rank of other: status %d", code)
+	}
+	if code := get(ts.URL+"/v1/fields", "c1"); code != http.StatusForbidden {
+		t.Errorf("consumer fields: status %d", code)
+	}
+	if code := get(ts.URL+"/v1/fields", "c2"); code != http.StatusOK {
+		t.Errorf("consumer of every sketch fields: status %d", code)
+	}
+
+	// the metrics describe every sketch, a consumer of some of them may not read them
+	addr, err := srv.ServeMetrics("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for token, want := range map[string]int{"": http.StatusUnauthorized, "c1": http.StatusForbidden, "c2": http.StatusOK, "p1": http.StatusForbidden, "a1": http.StatusOK} {
+		if code := get("http://"+addr.String()+"/metrics", token); code != want {
+			t.Errorf("metrics with token %q: status %d, want %d", token, code, want)
+		}
+	}
+}
+
+// selfSigned writes a certificate for 127.0.0.1 and its key to dir
+func selfSigned(t *testing.T, dir string) (security.TLSConfig, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), crand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "server"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(crand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, _ := x509.MarshalECPrivateKey(key)
+	cfg := security.TLSConfig{Cert: filepath.Join(dir, "server.pem"), Key: filepath.Join(dir, "server-key.pem")}
+	os.WriteFile(cfg.Cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(cfg.Key, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	cert, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return cfg, pool
+}
+
+func TestGatewayTLS(t *testing.T) {
+	cfg, pool := selfSigned(t, t.TempDir())
+	srv := servertest.Serve(t, server.NewServer(server.WithTLS(cfg)))
+	addr, err := srv.ServeGateway("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := http.Get("http://" + addr.String() + "/v1/openapi.json"); err == nil {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			t.Fatal("the gateway answered plain HTTP with TLS configured")
+		}
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	resp, err := client.Get("https://" + addr.String() + "/v1/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("openapi.json over https: status %d", resp.StatusCode)
+	}
+}
+
+// TestKllQueriesDuringMerges is meant for go test -race, quantile queries sort
+// the rows of the sketch that merges append to.
+func TestKllQueriesDuringMerges(t *testing.T) {
+	ctx := context.Background()
+	srv := servertest.NewServer(t)
+	c := srv.Client(t)
+
+	sketch := kll.NewKLLSketch[float64](200)
+	for i := range 1000 {
+		sketch.Add(float64(i))
+	}
+	protoKll := convert.ToProtoKLL(sketch)
+	protoKll.Name = "test_concurrent"
+	if _, err := c.MergeKll(ctx, protoKll); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for range 20 {
+			if _, err := c.MergeKll(ctx, protoKll); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 20 {
+			if _, err := c.ReverseQueryKll(ctx, &pb.ReverseQuery{Phi: 0.5, Type: "float64", Name: "test_concurrent"}); err != nil {
+				t.Error(err)
+			}
+			if _, err := c.QueryKll(ctx, &pb.NumericValue{Value: &pb.NumericValue_FloatVal{FloatVal: 500}, Type: "float64", Name: "test_concurrent"}); err != nil {
+				t.Error(err)
+			}
+		}
+	}()
+	wg.Wait()
+}
+
+func TestPlotKllEdges(t *testing.T) {
+	ctx := context.Background()
+	c := servertest.NewServer(t).Client(t)
+
+	sketch := kll.NewKLLSketch[float64](200)
+	for i := range 100 {
+		sketch.Add(float64(i + 100))
+	}
+	protoKll := convert.ToProtoKLL(sketch)
+	protoKll.Name = "test_edges"
+	if _, err := c.MergeKll(ctx, protoKll); err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.PlotKll(ctx, &pb.PlotRequest{NumBins: 4, Type: "float64", Name: "test_edges"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Edges) != 5 || res.Edges[0] != 100 || res.Edges[4] != 199 {
+		t.Fatalf("edges = %v, want 5 edges from 100 to 199", res.Edges)
+	}
+	for i := range res.Pmf {
+		if res.Edges[i+1]-res.Edges[i] != res.Step {
+			t.Errorf("bin %d is %v wide, want %v", i, res.Edges[i+1]-res.Edges[i], res.Step)
+		}
+	}
+}
+
+func TestPlotKllBins(t *testing.T) {
+	ctx := context.Background()
+	c := servertest.NewServer(t).Client(t)
+
 	sketch := kll.NewKLLSketch[int](200)
-	server.MergeKll(ctx, client.ConvertToProtoKLL(sketch))
+	for i := range 100 {
+		sketch.Add(i)
+	}
+	protoKll := convert.ToProtoKLL(sketch)
+	protoKll.Name = "test_bins"
+	if _, err := c.MergeKll(ctx, protoKll); err != nil {
+		t.Fatal(err)
+	}
 
-	for range 100 {
-		sketch.Add(rand.Intn(20))
+	for _, tc := range []struct {
+		name  string
+		req   *pb.PlotRequest
+		edges []float64
+		pmf   []float64
+	}{
+		{"equal width", &pb.PlotRequest{NumBins: 4}, []float64{0, 24.75, 49.5, 74.25, 99}, []float64{25, 25, 25, 25}},
+		{"splits", &pb.PlotRequest{Splits: []float64{10, 20, 50}}, []float64{10, 20, 50}, []float64{10, 31}},
+		{"range", &pb.PlotRequest{NumBins: 2, RangeMin: 0, RangeMax: 10}, []float64{0, 5, 10}, []float64{5, 6}},
+		{"equi depth", &pb.PlotRequest{NumBins: 4, EquiDepth: true}, []float64{0, 24, 49, 74, 99}, []float64{24, 25, 25, 26}},
+	} {
+		tc.req.Type, tc.req.Name = "int", "test_bins"
+		res, err := c.PlotKll(ctx, tc.req)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(res.Edges, tc.edges) || !reflect.DeepEqual(res.Pmf, tc.pmf) {
+			t.Errorf("%s: edges %v pmf %v, want %v %v", tc.name, res.Edges, res.Pmf, tc.edges, tc.pmf)
+		}
+		for i, count := range res.Pmf {
+			if res.Mass[i] != count/100 {
+				t.Errorf("%s: mass of bin %d is %v, want %v", tc.name, i, res.Mass[i], count/100)
+			}
+		}
 	}
-	b.StartTimer()
 
-	for range b.N {
-		server.MergeKll(ctx, client.ConvertToProtoKLL(sketch))
+	for _, req := range []*pb.PlotRequest{
+		{NumBins: 0},
+		{Splits: []float64{1}},
+		{Splits: []float64{5, 1}},
+		{Splits: []float64{1, 5}, EquiDepth: true},
+		{NumBins: 1 << 40},
+		{Splits: make([]float64, 20000)},
+		{Splits: []float64{1, math.NaN()}},
+		{Splits: []float64{1, math.Inf(1)}},
+		{NumBins: 2, RangeMin: math.Inf(-1), RangeMax: 5},
+	} {
+		req.Type, req.Name = "int", "test_bins"
+		if _, err := c.PlotKll(ctx, req); err == nil {
+			t.Errorf("PlotKll(%v) succeeded", req)
+		}
 	}
 }
 
-func BenchmarkCountMergeInt(b *testing.B) {
-	b.StopTimer()
+func TestExportImportSketch(t *testing.T) {
 	ctx := context.Background()
-	conn, err := grpc.NewClient("bufnet", grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(1024*1024)), grpc.WithContextDialer(bufDialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	c := servertest.NewServer(t).Client(t)
+
+	sketch := kll.NewKLLSketch[int](200)
+	for i := range 100 {
+		sketch.Add(i)
+	}
+	protoKll := convert.ToProtoKLL(sketch)
+	protoKll.Name = "test_export"
+	if _, err := c.MergeKll(ctx, protoKll); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := c.ExportSketch(ctx, &pb.ExportRequest{Name: "test_export", Kind: "kll", Type: "int"})
 	if err != nil {
-		b.Fatalf("Failed to dial bufnet: %v", err)
+		t.Fatal(err)
 	}
-	defer conn.Close()
-	server := pb.NewSketcherClient(conn)
-	sketch := count.NewCountSketch[int](111, 50, 5)
-	server.MergeCount(ctx, client.ConvertToProtoCount(sketch))
-	for range 100 {
-		sketch.Add(rand.Intn(100))
+	if len(state.Kll) != 1 || state.Kll[0].N != 100 || len(state.Count)+len(state.Hll)+len(state.Asketch) != 0 {
+		t.Fatalf("exported %v, want one KLL sketch of 100 items", state)
 	}
-	b.StartTimer()
 
-	for range b.N {
-		server.MergeCount(ctx, client.ConvertToProtoCount(sketch))
+	rank := func(name string) int64 {
+		res, err := c.QueryKll(ctx, &pb.NumericValue{Value: &pb.NumericValue_IntVal{IntVal: 99}, Type: "int", Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.N
+	}
+	name := "test_import"
+	res, err := c.ImportSketch(ctx, &pb.ImportRequest{Sketches: state, Name: &name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Imported != 1 || rank("test_import") != 100 {
+		t.Fatalf("imported %d sketches of %d items, want 1 of 100", res.Imported, rank("test_import"))
+	}
+	if _, err := c.ImportSketch(ctx, &pb.ImportRequest{Sketches: state, Name: &name, Mode: "merge"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := rank("test_import"); n != 200 {
+		t.Errorf("merged sketch has %d items, want 200", n)
+	}
+	if _, err := c.ImportSketch(ctx, &pb.ImportRequest{Sketches: state, Name: &name, Mode: "replace"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := rank("test_import"); n != 100 {
+		t.Errorf("replaced sketch has %d items, want 100", n)
+	}
+	if n := rank("test_export"); n != 100 {
+		t.Errorf("exported sketch has %d items after the imports, want 100", n)
+	}
+
+	broken := proto.Clone(state).(*pb.ServerState)
+	broken.Kll = append([]*pb.KLLSketch{{Type: "string"}}, broken.Kll...)
+	if _, err := c.ImportSketch(ctx, &pb.ImportRequest{Sketches: broken, Name: &name, Mode: "replace"}); err == nil {
+		t.Error("expected an error for a sketch of an unknown type")
+	}
+	if n := rank("test_import"); n != 100 {
+		t.Errorf("sketch has %d items after a failed replace, want the 100 it had", n)
+	}
+
+	if _, err := c.ExportSketch(ctx, &pb.ExportRequest{Kind: "tdigest"}); err == nil {
+		t.Error("expected an error for an unknown kind")
+	}
+	if _, err := c.ImportSketch(ctx, &pb.ImportRequest{Sketches: state, Mode: "append"}); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
+
+func TestDescribeSketch(t *testing.T) {
+	ctx := context.Background()
+	c := servertest.NewServer(t).Client(t)
+
+	sketch := kll.NewKLLSketch[int](200)
+	for i := range 1000 {
+		sketch.Add(i)
+	}
+	protoKll := convert.ToProtoKLL(sketch)
+	protoKll.Name = "test_describe"
+	for range 2 {
+		if _, err := c.MergeKll(ctx, protoKll); err != nil {
+			t.Fatal(err)
+		}
+	}
+	events := asketch.NewASketch[int](shared.ASketchSeed, shared.ASketchWidth, shared.ASketchDepth, shared.ASketchSlots)
+	for i := range 100 {
+		events.AddBy(i%3, i+1)
+	}
+	if _, err := c.MergeASketch(ctx, convert.ToProtoASketch(events, "test_describe")); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := c.DescribeSketch(ctx, &pb.DescribeRequest{Name: "test_describe"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Sketches) != 2 {
+		t.Fatalf("described %v, want a KLL sketch and an ASketch", res.Sketches)
+	}
+	k, a := res.Sketches[0], res.Sketches[1]
+	if k.Kind != "kll" || k.Type != "int" || k.N != 2000 || k.Params["k"] != 200 || k.Merges != 2 || k.LastMergeUnixNano == 0 {
+		t.Errorf("kll = %v", k)
+	}
+	total := int64(0)
+	for _, size := range k.LevelSizes {
+		total += size
+	}
+	if len(k.LevelSizes) < 2 || total == 0 || k.MemoryBytes < total*8 {
+		t.Errorf("kll levels %v of %d bytes", k.LevelSizes, k.MemoryBytes)
+	}
+	if a.Kind != "asketch" || a.N != 5050 || a.Params["slots"] != int64(shared.ASketchSlots) || a.Merges != 1 {
+		t.Errorf("asketch = %v", a)
+	}
+
+	filter, err := c.DumpFilter(ctx, &pb.DumpFilterRequest{Type: "int", Name: "test_describe"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filter.Entries) != 3 || filter.Entries[0].New < filter.Entries[2].New {
+		t.Errorf("filter = %v, want 3 entries from the most frequent", filter.Entries)
+	}
+
+	if _, err := c.MergeKll(ctx, convert.ToProtoKLL(sketch)); err != nil {
+		t.Fatal(err)
+	}
+	every, err := c.DescribeSketch(ctx, &pb.DescribeRequest{All: true, Kind: "kll"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, info := range every.Sketches {
+		names = append(names, info.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"", "test_describe"}) {
+		t.Errorf("described the kll sketches of %q, want the unnamed one and test_describe", names)
+	}
+
+	if _, err := c.DescribeSketch(ctx, &pb.DescribeRequest{Kind: "tdigest"}); err == nil {
+		t.Error("expected an error for an unknown kind")
 	}
 }
@@ -0,0 +1,73 @@
+// Package servertest runs servers over an in memory connection for end to end
+// tests, so every test gets a server of its own and no port is bound.
+package servertest
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const bufSize = 1024 * 1024 * 100
+
+// Server is an empty server.Server served on a bufconn listener behind the
+// interceptors of Server.Init.
+type Server struct {
+	*server.Server
+	Listener *bufconn.Listener
+}
+
+// NewServer starts a server that is stopped when the test ends. opts are
+// passed to the gRPC server, e.g. TLS credentials.
+func NewServer(t testing.TB, opts ...grpc.ServerOption) *Server {
+	t.Helper()
+	return Serve(t, server.NewServer(), opts...)
+}
+
+// Serve is NewServer for a server built with options of its own, e.g. tokens.
+func Serve(t testing.TB, srv *server.Server, opts ...grpc.ServerOption) *Server {
+	t.Helper()
+	s := &Server{Server: srv, Listener: bufconn.Listen(bufSize)}
+	g := s.NewGRPCServer(opts...)
+	go g.Serve(s.Listener)
+	t.Cleanup(g.Stop)
+	return s
+}
+
+// DialOptions connect to the server without TLS, options given later, e.g.
+// from security.DialOptions, take precedence.
+func (s *Server) DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return s.Listener.DialContext(ctx)
+		}),
+	}
+}
+
+// Dial connects to the server whatever the address, it starts connections the
+// way the clients of the client package expect.
+func (s *Server) Dial(string) (pb.SketcherClient, *grpc.ClientConn, error) {
+	conn, err := grpc.NewClient("passthrough:///bufnet", s.DialOptions()...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pb.NewSketcherClient(conn), conn, nil
+}
+
+// Client returns a client of the server that is closed when the test ends.
+func (s *Server) Client(t testing.TB, opts ...grpc.DialOption) pb.SketcherClient {
+	t.Helper()
+	conn, err := grpc.NewClient("passthrough:///bufnet", append(s.DialOptions(), opts...)...)
+	if err != nil {
+		t.Fatalf("could not dial the test server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewSketcherClient(conn)
+}
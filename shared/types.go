@@ -14,6 +14,12 @@ const (
 	ASketchSlots int    = 32
 )
 
+// HLL constants
+const (
+	HLLSeed      int64  = 157
+	HLLRegisters uint64 = 1024
+)
+
 // Primitive buf constants
 const (
 	BufSize int = 1000 //number of elements in the buf
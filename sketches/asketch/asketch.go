@@ -1,6 +1,7 @@
 package asketch
 
 import (
+	"container/heap"
 	"sort"
 
 	"github.com/bruhng/distributed-sketching/shared"
@@ -13,8 +14,39 @@ type aCount[T shared.Number] struct {
 	new int
 }
 
+// filterHeap is a min-heap on new that keeps index in sync with the heap
+// positions so an item can be found and fixed in place without scanning.
+type filterHeap[T shared.Number] struct {
+	slots []aCount[T]
+	index map[T]int
+}
+
+func (h *filterHeap[T]) Len() int           { return len(h.slots) }
+func (h *filterHeap[T]) Less(i, j int) bool { return h.slots[i].new < h.slots[j].new }
+
+func (h *filterHeap[T]) Swap(i, j int) {
+	h.slots[i], h.slots[j] = h.slots[j], h.slots[i]
+	h.index[h.slots[i].it] = i
+	h.index[h.slots[j].it] = j
+}
+
+func (h *filterHeap[T]) Push(x any) {
+	slot := x.(aCount[T])
+	h.index[slot.it] = len(h.slots)
+	h.slots = append(h.slots, slot)
+}
+
+func (h *filterHeap[T]) Pop() any {
+	last := len(h.slots) - 1
+	slot := h.slots[last]
+	h.slots = h.slots[:last]
+	delete(h.index, slot.it)
+	return slot
+}
+
 type ASketch[T shared.Number] struct {
-	filter []aCount[T]
+	filter *filterHeap[T]
+	m      int
 	cms    *countmin.CountMin[T]
 }
 
@@ -24,83 +56,63 @@ type FilterSlot[T shared.Number] struct {
 	New  int
 }
 
+func newFilterHeap[T shared.Number](m int) *filterHeap[T] {
+	return &filterHeap[T]{
+		slots: make([]aCount[T], 0, m),
+		index: make(map[T]int, m),
+	}
+}
+
 func NewASketch[T shared.Number](seed int64, width uint64, depth int, m int) *ASketch[T] {
 	if m <= 0 {
 		panic("ASketch requires m > 0")
 	}
-	f := make([]aCount[T], m)
-	for i := range f {
-		f[i].new = -1
-	}
 	return &ASketch[T]{
-		filter: f,
+		filter: newFilterHeap[T](m),
+		m:      m,
 		cms:    countmin.NewCountMin[T](seed, width, depth),
 	}
 }
 
-// check if x exist in filter, if in, return index, or return -1 means not find
+// check if x exist in filter, if in, return its heap position, or return -1 means not find
 func (a *ASketch[T]) getIndex(x T) int {
-	for i := range a.filter {
-		if a.filter[i].new >= 0 && a.filter[i].it == x {
-			return i
-		}
-	}
-	return -1
-}
-
-// find the first empty slot in filter(used for add function)
-func (a *ASketch[T]) firstEmpty() int {
-	for i := range a.filter {
-		if a.filter[i].new < 0 {
-			return i
-		}
+	if i, ok := a.filter.index[x]; ok {
+		return i
 	}
 	return -1
 }
 
-// When filter is full, to find the minimal value slot
-func (a *ASketch[T]) argMinNew() int {
-	minIdx := -1
-	minVal := 0
-	for i := range a.filter {
-		if a.filter[i].new < 0 {
-			continue
-		}
-		if minIdx == -1 || minVal > a.filter[i].new {
-			minIdx = i
-			minVal = a.filter[i].new
-		}
-	}
-	return minIdx
-}
-
 func (a *ASketch[T]) AddBy(x T, u int) {
 	if u <= 0 {
 		return
 	}
 
 	if index := a.getIndex(x); index >= 0 {
-		a.filter[index].new += u
+		a.filter.slots[index].new += u
+		heap.Fix(a.filter, index)
 		return
 	}
 
-	if index := a.firstEmpty(); index >= 0 {
-		a.filter[index] = aCount[T]{it: x, old: 0, new: u}
+	if a.filter.Len() < a.m {
+		heap.Push(a.filter, aCount[T]{it: x, old: 0, new: u})
 		return
 	}
 
 	a.cms.AddBy(x, u)
 	est := a.cms.Query(x)
 
-	minIdx := a.argMinNew()
-	minSlot := a.filter[minIdx]
+	// the root of the heap is always the slot with the smallest new count
+	minSlot := a.filter.slots[0]
 
 	if est > minSlot.new {
 		delta := minSlot.new - minSlot.old
 		if delta > 0 {
 			a.cms.AddBy(minSlot.it, delta)
 		}
-		a.filter[minIdx] = aCount[T]{it: x, old: est, new: est}
+		delete(a.filter.index, minSlot.it)
+		a.filter.slots[0] = aCount[T]{it: x, old: est, new: est}
+		a.filter.index[x] = 0
+		heap.Fix(a.filter, 0)
 	}
 }
 
@@ -110,7 +122,7 @@ func (a *ASketch[T]) Add(k T) {
 
 func (a *ASketch[T]) Query(x T) int {
 	if index := a.getIndex(x); index >= 0 {
-		return a.filter[index].new
+		return a.filter.slots[index].new
 	}
 	return a.cms.Query(x)
 }
@@ -125,44 +137,110 @@ func (a *ASketch[T]) MergeBuf(otherBuf []T) {
 
 }
 
+// MergeSketch folds other into a. Both filters are unioned and every item is
+// given the combined estimate a.Query(x) + other.Query(x). The part of an
+// item's count that lives only in a filter (new - old) is its delta; the m
+// largest candidates stay in the filter with old = est - delta, while the
+// deltas of the rest are pushed into the merged CMS so no count is lost or
+// counted twice.
 func (a *ASketch[T]) MergeSketch(other *ASketch[T]) {
 	if other == nil {
 		return
 	}
+	type candidate struct {
+		it    T
+		est   int
+		delta int
+	}
+	cands := make([]candidate, 0, a.filter.Len()+other.filter.Len())
+	for _, slot := range a.filter.slots {
+		c := candidate{it: slot.it, est: slot.new, delta: slot.new - slot.old}
+		if i := other.getIndex(slot.it); i >= 0 {
+			o := other.filter.slots[i]
+			c.est += o.new
+			c.delta += o.new - o.old
+		} else {
+			c.est += other.cms.Query(slot.it)
+		}
+		cands = append(cands, c)
+	}
+	for _, slot := range other.filter.slots {
+		if a.getIndex(slot.it) >= 0 {
+			continue
+		}
+		cands = append(cands, candidate{
+			it:    slot.it,
+			est:   slot.new + a.cms.Query(slot.it),
+			delta: slot.new - slot.old,
+		})
+	}
+
 	a.cms.Merge(*other.cms)
-	for _, slot := range other.filter {
-		if slot.new < 0 {
+
+	sort.Slice(cands, func(i, j int) bool {
+		if cands[i].est != cands[j].est {
+			return cands[i].est > cands[j].est
+		}
+		return cands[i].it < cands[j].it
+	})
+	f := newFilterHeap[T](a.m)
+	for i, c := range cands {
+		if i >= a.m {
+			if c.delta > 0 {
+				a.cms.AddBy(c.it, c.delta)
+			}
 			continue
 		}
-		a.AddBy(slot.it, slot.new)
+		f.index[c.it] = len(f.slots)
+		f.slots = append(f.slots, aCount[T]{it: c.it, old: c.est - c.delta, new: c.est})
 	}
+	heap.Init(f)
+	a.filter = f
 }
 
+// NewASketchFromState rebuilds a sketch from a snapshot. The filter size m is
+// len(filter); slots with New < 0 are empty.
 func NewASketchFromState[T shared.Number](filter []FilterSlot[T], rows [][]int, seeds []uint32) *ASketch[T] {
-	f := make([]aCount[T], len(filter))
-	for i, slot := range filter {
-		f[i] = aCount[T]{
+	m := max(len(filter), 1)
+	f := newFilterHeap[T](m)
+	for _, slot := range filter {
+		if slot.New < 0 {
+			continue
+		}
+		if i, ok := f.index[slot.Item]; ok {
+			f.slots[i].old += slot.Old
+			f.slots[i].new += slot.New
+			continue
+		}
+		f.index[slot.Item] = len(f.slots)
+		f.slots = append(f.slots, aCount[T]{
 			it:  slot.Item,
 			old: slot.Old,
 			new: slot.New,
-		}
+		})
 	}
+	heap.Init(f)
 	cm := countmin.NewCountMinFromData[T](rows, seeds)
 	return &ASketch[T]{
 		filter: f,
+		m:      m,
 		cms:    cm,
 	}
 }
 
+// Snapshot returns all m filter slots, padding unused ones with New = -1.
 func (a *ASketch[T]) Snapshot() ([]FilterSlot[T], [][]int, []uint32) {
-	filterCopy := make([]FilterSlot[T], len(a.filter))
-	for i, slot := range a.filter {
+	filterCopy := make([]FilterSlot[T], a.m)
+	for i, slot := range a.filter.slots {
 		filterCopy[i] = FilterSlot[T]{
 			Item: slot.it,
 			Old:  slot.old,
 			New:  slot.new,
 		}
 	}
+	for i := a.filter.Len(); i < a.m; i++ {
+		filterCopy[i].New = -1
+	}
 	rowsCopy := make([][]int, len(a.cms.Sketch))
 	for i := range a.cms.Sketch {
 		rowsCopy[i] = append([]int(nil), a.cms.Sketch[i]...)
@@ -172,11 +250,9 @@ func (a *ASketch[T]) Snapshot() ([]FilterSlot[T], [][]int, []uint32) {
 }
 
 func (a *ASketch[T]) FilterSnapshot() []FilterSlot[T] {
-	out := make([]FilterSlot[T], 0, len(a.filter))
-	for _, s := range a.filter {
-		if s.new >= 0 {
-			out = append(out, FilterSlot[T]{Item: s.it, Old: s.old, New: s.new})
-		}
+	out := make([]FilterSlot[T], 0, a.filter.Len())
+	for _, s := range a.filter.slots {
+		out = append(out, FilterSlot[T]{Item: s.it, Old: s.old, New: s.new})
 	}
 	return out
 }
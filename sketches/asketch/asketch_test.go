@@ -0,0 +1,162 @@
+package asketch_test
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/bruhng/distributed-sketching/shared"
+	"github.com/bruhng/distributed-sketching/sketches/asketch"
+)
+
+func TestASketchExactWhileFilterFits(t *testing.T) {
+	sketch := asketch.NewASketch[int](shared.ASketchSeed, shared.ASketchWidth, shared.ASketchDepth, 64)
+	truth := map[int]int{}
+	r := rand.New(rand.NewSource(1))
+	for range 10000 {
+		x := r.Intn(64)
+		sketch.Add(x)
+		truth[x]++
+	}
+	for x, c := range truth {
+		if got := sketch.Query(x); got != c {
+			t.Fatalf("Query(%d) = %d, want %d", x, got, c)
+		}
+	}
+}
+
+func TestASketchKeepsHeavyHitters(t *testing.T) {
+	sketch := asketch.NewASketch[int](shared.ASketchSeed, shared.ASketchWidth, shared.ASketchDepth, 8)
+	r := rand.New(rand.NewSource(2))
+	for range 20000 {
+		sketch.Add(1000 + r.Intn(5000))
+	}
+	for i := range 4 {
+		sketch.AddBy(i, 1000*(i+1))
+	}
+
+	top := sketch.TopK(4)
+	if len(top) != 4 {
+		t.Fatalf("TopK(4) returned %d slots", len(top))
+	}
+	for i, slot := range top {
+		if want := 3 - i; slot.Item != want {
+			t.Fatalf("TopK[%d] = %d, want %d", i, slot.Item, want)
+		}
+	}
+}
+
+func TestASketchSnapshotRoundTrip(t *testing.T) {
+	sketch := asketch.NewASketch[int](shared.ASketchSeed, shared.ASketchWidth, shared.ASketchDepth, 16)
+	for i := range 10 {
+		sketch.AddBy(i, i+1)
+	}
+	filter, rows, seeds := sketch.Snapshot()
+	if len(filter) != 16 {
+		t.Fatalf("snapshot has %d slots, want 16", len(filter))
+	}
+	restored := asketch.NewASketchFromState(filter, rows, seeds)
+	for i := range 10 {
+		if got, want := restored.Query(i), sketch.Query(i); got != want {
+			t.Fatalf("Query(%d) = %d after restore, want %d", i, got, want)
+		}
+	}
+}
+
+func zipfStream(seed int64, n int) []int {
+	r := rand.New(rand.NewSource(seed))
+	z := rand.NewZipf(r, 1.2, 1, 20000)
+	out := make([]int, n)
+	for i := range out {
+		out[i] = int(z.Uint64())
+	}
+	return out
+}
+
+func newTestSketch(m int) *asketch.ASketch[int] {
+	return asketch.NewASketch[int](shared.ASketchSeed, shared.ASketchWidth, shared.ASketchDepth, m)
+}
+
+// checkWithinCMSError verifies that every item of truth is estimated by got
+// in [truth, truth + eps*N] and agrees with ref, a single sketch over the
+// same stream, within the same bound.
+func checkWithinCMSError(t *testing.T, got, ref *asketch.ASketch[int], truth map[int]int, n int) {
+	t.Helper()
+	bound := int(math.Ceil(math.E / float64(shared.ASketchWidth) * float64(n)))
+	for x, c := range truth {
+		est := got.Query(x)
+		if est < c || est > c+bound {
+			t.Fatalf("Query(%d) = %d, want within [%d, %d]", x, est, c, c+bound)
+		}
+		if diff := est - ref.Query(x); diff > bound || -diff > bound {
+			t.Fatalf("Query(%d) = %d differs from single sketch %d by more than %d", x, est, ref.Query(x), bound)
+		}
+	}
+}
+
+func TestASketchMergeMatchesSingleSketch(t *testing.T) {
+	for _, m := range []int{8, 32, 256} {
+		for seed := int64(0); seed < 5; seed++ {
+			t.Run(fmt.Sprintf("Slots: %d,Seed: %d", m, seed), func(t *testing.T) {
+				left := zipfStream(2*seed, 20000)
+				right := zipfStream(2*seed+1, 20000)
+				a, b, single := newTestSketch(m), newTestSketch(m), newTestSketch(m)
+				truth := map[int]int{}
+				for _, x := range left {
+					a.Add(x)
+					single.Add(x)
+					truth[x]++
+				}
+				for _, x := range right {
+					b.Add(x)
+					single.Add(x)
+					truth[x]++
+				}
+
+				a.MergeSketch(b)
+				checkWithinCMSError(t, a, single, truth, len(left)+len(right))
+			})
+		}
+	}
+}
+
+func TestASketchMergeManyIntoEmpty(t *testing.T) {
+	const m = 32
+	merged, single := newTestSketch(m), newTestSketch(m)
+	truth := map[int]int{}
+	n := 0
+	for seed := int64(0); seed < 8; seed++ {
+		part := newTestSketch(m)
+		for _, x := range zipfStream(100+seed, 5000) {
+			part.Add(x)
+			single.Add(x)
+			truth[x]++
+			n++
+		}
+		// go through the wire representation like the server does
+		filter, rows, seeds := part.Snapshot()
+		merged.MergeSketch(asketch.NewASketchFromState(filter, rows, seeds))
+	}
+	checkWithinCMSError(t, merged, single, truth, n)
+
+	top := merged.TopK(5)
+	want := single.TopK(5)
+	for i := range want {
+		if top[i].Item != want[i].Item {
+			t.Fatalf("TopK[%d] = %d, single sketch has %d", i, top[i].Item, want[i].Item)
+		}
+	}
+}
+
+func BenchmarkASketchAdd(b *testing.B) {
+	for _, slots := range []int{32, 1024, 8192} {
+		b.Run(fmt.Sprintf("Slots: %d", slots), func(b *testing.B) {
+			sketch := asketch.NewASketch[int](shared.ASketchSeed, shared.ASketchWidth, shared.ASketchDepth, slots)
+			r := rand.New(rand.NewSource(3))
+			for b.Loop() {
+				sketch.Add(r.Intn(4 * slots))
+			}
+		})
+	}
+}
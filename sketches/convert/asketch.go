@@ -0,0 +1,102 @@
+package convert
+
+import (
+	"fmt"
+	"reflect"
+
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/shared"
+	"github.com/bruhng/distributed-sketching/sketches/asketch"
+)
+
+func ToProtoASketch[T shared.Number](sketch *asketch.ASketch[T], fieldName string) *pb.ASketch {
+	t := fmt.Sprintf("%T", *new(T))
+
+	// Get snapshot of the sketch
+	filter, rows, seeds := sketch.Snapshot()
+
+	protoASketch := &pb.ASketch{
+		Type: t,
+		Name: fieldName,
+	}
+	// Convert filter entries
+	for _, slot := range filter {
+		var protoValue *pb.NumericValue
+
+		if reflect.ValueOf(slot.Item).Kind() == reflect.Int {
+			protoValue = &pb.NumericValue{
+				Value: &pb.NumericValue_IntVal{IntVal: int64(slot.Item)},
+				Type:  "int",
+			}
+		} else {
+			protoValue = &pb.NumericValue{
+				Value: &pb.NumericValue_FloatVal{FloatVal: float64(slot.Item)},
+				Type:  "float64",
+			}
+		}
+
+		protoEntry := &pb.ASketchFilterEntry{
+			Item: protoValue,
+			Old:  int64(slot.Old),
+			New:  int64(slot.New),
+		}
+
+		protoASketch.Filter = append(protoASketch.Filter, protoEntry)
+	}
+
+	// Convert CountMin data
+	protoCountMin := &pb.CountMin{}
+
+	for _, row := range rows {
+		protoRow := &pb.IntRow{}
+		for _, val := range row {
+			protoRow.Val = append(protoRow.Val, int64(val))
+		}
+		protoCountMin.Rows = append(protoCountMin.Rows, protoRow)
+	}
+
+	protoCountMin.Seeds = append(protoCountMin.Seeds, seeds...)
+	protoASketch.CountMin = protoCountMin
+
+	return protoASketch
+}
+
+// FromProtoASketch converts a protobuf ASketch to an internal ASketch
+func FromProtoASketch[T shared.Number](protoData *pb.ASketch) *asketch.ASketch[T] {
+	var filter []asketch.FilterSlot[T]
+	var rows [][]int
+	var seeds []uint32
+
+	// Convert filter entries
+	for _, entry := range protoData.GetFilter() {
+		var item T
+		switch v := entry.GetItem().GetValue().(type) { // oneof
+		case *pb.NumericValue_IntVal:
+			item = T(v.IntVal)
+		case *pb.NumericValue_FloatVal:
+			item = T(v.FloatVal)
+		default:
+			continue
+		}
+
+		filter = append(filter, asketch.FilterSlot[T]{
+			Item: item,
+			Old:  int(entry.Old),
+			New:  int(entry.New),
+		})
+	}
+
+	// Convert CountMin data
+	if cm := protoData.GetCountMin(); cm != nil {
+		for _, row := range cm.GetRows() {
+			intRow := make([]int, 0, len(row.GetVal()))
+			for _, v := range row.GetVal() {
+				intRow = append(intRow, int(v))
+			}
+			rows = append(rows, intRow)
+		}
+		seeds = append(seeds, cm.GetSeeds()...)
+	}
+
+	return asketch.NewASketchFromState(filter, rows, seeds)
+}
@@ -0,0 +1,49 @@
+// Package convert translates between the sketches and their protobuf messages,
+// shared by the client, the server and the sketch files.
+package convert
+
+import (
+	"fmt"
+	"reflect"
+
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/shared"
+)
+
+func ToProtoArr[T shared.Number](arr []T) *pb.BadArray {
+	t := fmt.Sprintf("%T", arr)[2:]
+	protoRow := pb.NumericRow{}
+
+	for _, val := range arr {
+		if reflect.ValueOf(val).Kind() == reflect.Int {
+			protoRow.Values = append(protoRow.Values, &pb.NumericValue{
+				Value: &pb.NumericValue_IntVal{IntVal: int64(val)}, // Wrap value properly
+			})
+
+		} else {
+			protoRow.Values = append(protoRow.Values, &pb.NumericValue{
+				Value: &pb.NumericValue_FloatVal{FloatVal: float64(val)}, // Wrap value properly
+			})
+
+		}
+	}
+	return &pb.BadArray{Arr: &protoRow, Type: t}
+}
+
+func ToProtoBuf[T shared.Number](buf []T) *pb.BufBatch {
+	t := fmt.Sprintf("%T", *new(T))
+	protoBuf := &pb.BufBatch{Type: t}
+	switch t {
+	case "int":
+		for _, item := range buf {
+			protoBuf.Items = append(protoBuf.Items, &pb.NumericValue{Value: &pb.NumericValue_IntVal{IntVal: int64(item)}})
+		}
+	case "float64":
+		for _, item := range buf {
+			protoBuf.Items = append(protoBuf.Items, &pb.NumericValue{Value: &pb.NumericValue_FloatVal{FloatVal: float64(item)}})
+		}
+	default:
+		panic("Type not supported")
+	}
+	return protoBuf
+}
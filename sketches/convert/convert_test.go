@@ -0,0 +1,81 @@
+package convert_test
+
+import (
+	"testing"
+
+	"github.com/bruhng/distributed-sketching/shared"
+	"github.com/bruhng/distributed-sketching/sketches/asketch"
+	"github.com/bruhng/distributed-sketching/sketches/convert"
+	"github.com/bruhng/distributed-sketching/sketches/count"
+	"github.com/bruhng/distributed-sketching/sketches/hll"
+	"github.com/bruhng/distributed-sketching/sketches/kll"
+)
+
+func TestKLLRoundTrip(t *testing.T) {
+	sketch := kll.NewKLLSketch[int](200)
+	for i := range 10_000 {
+		sketch.Add(i)
+	}
+	back := convert.FromProtoKLL[int](convert.ToProtoKLL(sketch))
+	if back.N != sketch.N {
+		t.Fatalf("N = %d, want %d", back.N, sketch.N)
+	}
+	for _, x := range []int{0, 2500, 5000, 9999} {
+		if got, want := back.Query(x), sketch.Query(x); got != want {
+			t.Errorf("rank of %d = %d, want %d", x, got, want)
+		}
+	}
+}
+
+func TestCountRoundTrip(t *testing.T) {
+	sketch := count.NewCountSketch[float64](157, 100, 10)
+	for i := range 1000 {
+		sketch.Add(float64(i % 7))
+	}
+	back := convert.FromProtoCount[float64](convert.ToProtoCount(sketch))
+	for x := range 7 {
+		if got, want := back.Query(float64(x)), sketch.Query(float64(x)); got != want {
+			t.Errorf("count of %d = %d, want %d", x, got, want)
+		}
+	}
+}
+
+func TestCountType(t *testing.T) {
+	if got := convert.ToProtoCount(count.NewCountSketch[int](157, 100, 10)).Type; got != "int" {
+		t.Errorf("int sketch has type %q, want int", got)
+	}
+	if got := convert.ToProtoCount(count.NewCountSketch[float64](157, 100, 10)).Type; got != "float64" {
+		t.Errorf("float64 sketch has type %q, want float64", got)
+	}
+}
+
+func TestHLLRoundTrip(t *testing.T) {
+	sketch := hll.NewHLLSketch[int](shared.HLLRegisters, shared.HLLSeed)
+	for i := range 5000 {
+		sketch.Add(i)
+	}
+	p := convert.ToProtoHll(sketch, "vehicles")
+	if p.Name != "vehicles" {
+		t.Fatalf("name = %q", p.Name)
+	}
+	if got, want := convert.FromProtoHll[int](p).Query(), sketch.Query(); got != want {
+		t.Fatalf("estimate = %v, want %v", got, want)
+	}
+}
+
+func TestASketchRoundTrip(t *testing.T) {
+	sketch := asketch.NewASketch[int](shared.ASketchSeed, shared.ASketchWidth, shared.ASketchDepth, shared.ASketchSlots)
+	for i := range 2000 {
+		sketch.Add(i % 50)
+	}
+	p := convert.ToProtoASketch(sketch, "events")
+	if p.Name != "events" {
+		t.Fatalf("name = %q", p.Name)
+	}
+	back := convert.FromProtoASketch[int](p)
+	for x := range 50 {
+		if got, want := back.Query(x), sketch.Query(x); got != want {
+			t.Errorf("count of %d = %d, want %d", x, got, want)
+		}
+	}
+}
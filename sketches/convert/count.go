@@ -0,0 +1,45 @@
+package convert
+
+import (
+	"fmt"
+
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/shared"
+	"github.com/bruhng/distributed-sketching/sketches/count"
+)
+
+func ToProtoCount[T shared.Number](sketch *count.CountSketch[T]) *pb.CountSketch {
+	protoArray := &pb.CountSketch{Type: fmt.Sprintf("%T", *new(T))}
+	data := sketch.Sketch
+	seeds := sketch.Seeds
+
+	for _, row := range data {
+		protoRow := &pb.IntRow{} // Create a new row
+
+		for _, val := range row {
+			protoRow.Val = append(protoRow.Val, int64(val))
+		}
+		protoArray.Rows = append(protoArray.Rows, protoRow)
+	}
+
+	protoArray.Seeds = append(protoArray.Seeds, seeds...)
+	return protoArray
+}
+
+func FromProtoCount[T shared.Number](protoData *pb.CountSketch) *count.CountSketch[T] {
+	var data [][]int
+	var seeds []uint32
+
+	for _, protoRow := range protoData.Rows {
+		var row []int
+
+		for _, protoValue := range protoRow.Val {
+			row = append(row, int(protoValue))
+		}
+
+		data = append(data, row)
+	}
+	seeds = append(seeds, protoData.Seeds...)
+
+	return count.NewCountFromData[T](data, seeds)
+}
@@ -0,0 +1,31 @@
+package convert
+
+import (
+	"fmt"
+
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/shared"
+	"github.com/bruhng/distributed-sketching/sketches/hll"
+)
+
+func ToProtoHll[T shared.Number](sketch *hll.HLLSketch[T], name string) *pb.HLLSketch {
+	h, g := sketch.Hashes()
+	protoSketch := &pb.HLLSketch{
+		Type: fmt.Sprintf("%T", *new(T)),
+		Name: name,
+		H:    h,
+		G:    g,
+	}
+	for _, r := range sketch.C {
+		protoSketch.Registers = append(protoSketch.Registers, int64(r))
+	}
+	return protoSketch
+}
+
+func FromProtoHll[T shared.Number](protoData *pb.HLLSketch) *hll.HLLSketch[T] {
+	registers := make([]int, len(protoData.Registers))
+	for i, r := range protoData.Registers {
+		registers[i] = int(r)
+	}
+	return hll.NewHLLFromData[T](registers, protoData.GetH(), protoData.GetG())
+}
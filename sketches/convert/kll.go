@@ -0,0 +1,58 @@
+package convert
+
+import (
+	"fmt"
+	"reflect"
+
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/shared"
+	"github.com/bruhng/distributed-sketching/sketches/kll"
+)
+
+func ToProtoKLL[T shared.Number](sketch *kll.KLLSketch[T]) *pb.KLLSketch {
+	t := fmt.Sprintf("%T", sketch.Sketch)[4:]
+	orderedArray := &pb.KLLSketch{N: int64(sketch.N), Type: t}
+	data := sketch.Sketch
+
+	for _, row := range data {
+		protoRow := &pb.NumericRow{} // Create a new row
+
+		for _, val := range row {
+			if reflect.ValueOf(val).Kind() == reflect.Int {
+				protoRow.Values = append(protoRow.Values, &pb.NumericValue{
+					Value: &pb.NumericValue_IntVal{IntVal: int64(val)}, // Wrap value properly
+				})
+
+			} else {
+				protoRow.Values = append(protoRow.Values, &pb.NumericValue{
+					Value: &pb.NumericValue_FloatVal{FloatVal: float64(val)}, // Wrap value properly
+				})
+
+			}
+		}
+		orderedArray.Rows = append(orderedArray.Rows, protoRow)
+	}
+
+	return orderedArray
+}
+
+func FromProtoKLL[T shared.Number](protoData *pb.KLLSketch) *kll.KLLSketch[T] {
+	var data [][]T
+
+	for _, protoRow := range protoData.Rows {
+		var row []T
+
+		for _, protoValue := range protoRow.Values {
+			if intVal, ok := protoValue.Value.(*pb.NumericValue_IntVal); ok {
+				row = append(row, T(intVal.IntVal))
+			}
+			if floatVal, ok := protoValue.Value.(*pb.NumericValue_FloatVal); ok {
+				row = append(row, T(any(floatVal.FloatVal).(T)))
+			}
+		}
+
+		data = append(data, row)
+	}
+
+	return kll.NewKLLFromData[T](data, protoData.GetN(), 200)
+}
@@ -30,6 +30,16 @@ func NewHLLSketch[T shared.Number](m uint64, seed int64) *HLLSketch[T] {
 	return &HLLSketch[T]{C: arr, m: m, h: h, g: g}
 }
 
+// build from the existing registers and hash seeds
+func NewHLLFromData[T shared.Number](c []int, h uint32, g uint32) *HLLSketch[T] {
+	return &HLLSketch[T]{C: c, m: uint64(len(c)), h: h, g: g}
+}
+
+// Hashes returns the seeds of the bucket hash h and the value hash g.
+func (hll HLLSketch[T]) Hashes() (uint32, uint32) {
+	return hll.h, hll.g
+}
+
 func hashWithSeed(data []byte, seed uint32) uint64 {
 	hash := murmur3.New64WithSeed(seed)
 	hash.Write(data)
@@ -53,20 +63,20 @@ func (hll HLLSketch[T]) Add(x T) {
 	m := hll.m
 
 	chx := C[hx%m]
-	zgx := bits.LeadingZeros(uint(gx))
+	// position of the first 1 bit
+	zgx := bits.LeadingZeros64(gx) + 1
 
 	hll.C[hx%m] = max(chx, zgx)
 }
 
 func (hll HLLSketch[T]) Merge(hll2 HLLSketch[T]) error {
-	newC := make([]int, hll.m)
 	if hll.g != hll2.g || hll.h != hll2.h || hll.m != hll2.m {
 		return errors.New("Missmatched parameters")
 	}
+	// C is shared with the caller's sketch so it is updated in place
 	for i, x := range hll.C {
-		newC[i] = max(x, hll2.C[i])
+		hll.C[i] = max(x, hll2.C[i])
 	}
-	hll.C = newC
 	return nil
 }
 
@@ -74,9 +84,18 @@ func (hll HLLSketch[T]) Query() float64 {
 	x := 0.0
 	c := hll.C
 	m := float64(hll.m)
-	am := 0.7213 / (1 + 1.079*m)
+	am := 0.7213 / (1 + 1.079/m)
+	zeros := 0
 	for _, cj := range c {
 		x += math.Pow(2.0, -float64(cj))
+		if cj == 0 {
+			zeros++
+		}
+	}
+	est := am * math.Pow(m, 2) / x
+	// small range correction, fall back to linear counting
+	if est <= 2.5*m && zeros > 0 {
+		return m * math.Log(m/float64(zeros))
 	}
-	return am * math.Pow(m, 2) / x
+	return est
 }
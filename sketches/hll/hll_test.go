@@ -0,0 +1,74 @@
+package hll_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bruhng/distributed-sketching/shared"
+	"github.com/bruhng/distributed-sketching/sketches/hll"
+)
+
+// stdErr is the relative standard error of a HyperLogLog with m registers
+func stdErr(m uint64) float64 {
+	return 1.04 / math.Sqrt(float64(m))
+}
+
+func TestHLLEstimatorAccuracy(t *testing.T) {
+	// 100 and 1000 are in the small range, corrected by linear counting
+	for _, n := range []int{100, 1000, 10_000, 100_000, 500_000} {
+		sketch := hll.NewHLLSketch[int](shared.HLLRegisters, shared.HLLSeed)
+		for i := range n {
+			sketch.Add(i)
+			sketch.Add(i) // duplicates do not count
+		}
+		est := sketch.Query()
+		if rel := math.Abs(est-float64(n)) / float64(n); rel > 3*stdErr(shared.HLLRegisters) {
+			t.Errorf("estimate of %d distinct items is %.0f, %.1f%% off", n, est, 100*rel)
+		}
+	}
+}
+
+func TestHLLEmptyAndSingleItem(t *testing.T) {
+	sketch := hll.NewHLLSketch[float64](shared.HLLRegisters, shared.HLLSeed)
+	if est := sketch.Query(); est != 0 {
+		t.Fatalf("estimate of an empty sketch is %v", est)
+	}
+	// registers hold the position of the first 1 bit, so any item sets one
+	sketch.Add(3.5)
+	set := 0
+	for _, c := range sketch.C {
+		if c > 0 {
+			set++
+		}
+	}
+	if set != 1 {
+		t.Fatalf("%d registers set by one item", set)
+	}
+	if est := sketch.Query(); math.Abs(est-1) > 0.01 {
+		t.Fatalf("estimate of one item is %v", est)
+	}
+}
+
+func TestHLLMergeInPlace(t *testing.T) {
+	a := hll.NewHLLSketch[int](shared.HLLRegisters, shared.HLLSeed)
+	b := hll.NewHLLSketch[int](shared.HLLRegisters, shared.HLLSeed)
+	for i := range 20_000 {
+		a.Add(i)
+		b.Add(10_000 + i)
+	}
+	before := b.Query()
+	if err := a.Merge(*b); err != nil {
+		t.Fatal(err)
+	}
+	if est := a.Query(); math.Abs(est-30_000)/30_000 > 3*stdErr(shared.HLLRegisters) {
+		t.Errorf("estimate of the union of 30000 items is %.0f", est)
+	}
+	if b.Query() != before {
+		t.Error("merging changed the merged sketch")
+	}
+
+	other := hll.NewHLLSketch[int](shared.HLLRegisters, shared.HLLSeed+1)
+	if err := a.Merge(*other); err == nil {
+		t.Error("sketches with different seeds were merged")
+	}
+}
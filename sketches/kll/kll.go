@@ -86,6 +86,19 @@ func (kll *KLLSketch[T]) Merge(sketch KLLSketch[T]) {
 	compress(kll)
 }
 
+// QueryLess is like Query but only counts the items smaller than val.
+func (kll *KLLSketch[T]) QueryLess(val T) int {
+	sum := 0
+	for h, row := range kll.Sketch {
+		for _, elem := range row {
+			if elem < val {
+				sum += int(math.Pow(2.0, float64(h)))
+			}
+		}
+	}
+	return sum
+}
+
 func (kll *KLLSketch[T]) Query(val T) int {
 	sum := 0
 	for h, row := range kll.Sketch {
@@ -99,7 +112,8 @@ func (kll *KLLSketch[T]) Query(val T) int {
 }
 
 func (kll *KLLSketch[T]) QueryQuantile(phi float64) T {
-	q := int(phi * float64(kll.N))
+	// at least one item is taken so phi 0 is the minimum
+	q := max(1, int(phi*float64(kll.N)))
 
 	quantileSum := 0
 	sketch := make([][]T, len(kll.Sketch))
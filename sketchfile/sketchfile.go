@@ -0,0 +1,533 @@
+// Package sketchfile reads, writes and combines sketch files without a server.
+// A sketch file holds a ServerState, the format of the server's -state file
+// and of sketchctl save, either as binary protobuf or as protobuf JSON.
+package sketchfile
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/shared"
+	"github.com/bruhng/distributed-sketching/sketches/convert"
+	"github.com/bruhng/distributed-sketching/sketches/count"
+	"github.com/bruhng/distributed-sketching/sketches/kll"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Formats are the encodings of a sketch file
+var Formats = []string{"pb", "json"}
+
+// FormatOf is the format a file is written in by default, json for .json
+// files and pb otherwise.
+func FormatOf(path string) string {
+	if filepath.Ext(path) == ".json" {
+		return "json"
+	}
+	return "pb"
+}
+
+// Read reads a sketch file in either format.
+func Read(path string) (*pb.ServerState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	state := &pb.ServerState{}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		err = protojson.Unmarshal(data, state)
+	} else {
+		err = proto.Unmarshal(data, state)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read sketches %s: %w", path, err)
+	}
+	return state, nil
+}
+
+// Write writes state to path in format, or in FormatOf(path) if format is
+// empty.
+func Write(path string, state *pb.ServerState, format string) error {
+	if format == "" {
+		format = FormatOf(path)
+	}
+	var data []byte
+	var err error
+	switch format {
+	case "pb":
+		data, err = proto.Marshal(state)
+	case "json":
+		data, err = protojson.MarshalOptions{Multiline: true}.Marshal(state)
+		data = append(data, '\n')
+	default:
+		return fmt.Errorf("%s is not supported, please submit a valid format", format)
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Rename gives every sketch of state the name name.
+func Rename(state *pb.ServerState, name string) {
+	for _, s := range state.Kll {
+		s.Name = name
+	}
+	for _, s := range state.Count {
+		s.Name = name
+	}
+	for _, s := range state.Hll {
+		s.Name = name
+	}
+	for _, s := range state.Asketch {
+		s.Name = name
+	}
+}
+
+// Sketch is one sketch of a file. Exactly one of its fields is set.
+type Sketch struct {
+	Kll     *pb.KLLSketch
+	Count   *pb.CountSketch
+	Hll     *pb.HLLSketch
+	Asketch *pb.ASketch
+}
+
+// Kind is kll, count, hll or asketch
+func (s Sketch) Kind() string {
+	switch {
+	case s.Kll != nil:
+		return "kll"
+	case s.Count != nil:
+		return "count"
+	case s.Hll != nil:
+		return "hll"
+	}
+	return "asketch"
+}
+
+func (s Sketch) Name() string {
+	switch {
+	case s.Kll != nil:
+		return s.Kll.Name
+	case s.Count != nil:
+		return s.Count.Name
+	case s.Hll != nil:
+		return s.Hll.Name
+	}
+	return s.Asketch.Name
+}
+
+func (s Sketch) Type() string {
+	switch {
+	case s.Kll != nil:
+		return s.Kll.Type
+	case s.Count != nil:
+		return s.Count.Type
+	case s.Hll != nil:
+		return s.Hll.Type
+	}
+	return s.Asketch.Type
+}
+
+// Sketches lists the sketches of state by kind, in the order of the file.
+func Sketches(state *pb.ServerState) []Sketch {
+	var out []Sketch
+	for _, s := range state.Kll {
+		out = append(out, Sketch{Kll: s})
+	}
+	for _, s := range state.Count {
+		out = append(out, Sketch{Count: s})
+	}
+	for _, s := range state.Hll {
+		out = append(out, Sketch{Hll: s})
+	}
+	for _, s := range state.Asketch {
+		out = append(out, Sketch{Asketch: s})
+	}
+	return out
+}
+
+// Select returns the only sketch of state that has the given kind, name and
+// type, an empty kind or type matches any.
+func Select(state *pb.ServerState, kind string, name string, typ string) (Sketch, error) {
+	var found []Sketch
+	for _, s := range Sketches(state) {
+		if (kind == "" || kind == s.Kind()) && name == s.Name() && (typ == "" || typ == s.Type()) {
+			found = append(found, s)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Sketch{}, fmt.Errorf("no sketch named %q matches", name)
+	case 1:
+		return found[0], nil
+	}
+	return Sketch{}, fmt.Errorf("%d sketches named %q match, choose one by kind and type", len(found), name)
+}
+
+// Info summarizes a sketch
+type Info struct {
+	Kind, Name, Type string
+	// N is the number of items, 0 for count and hll sketches which do not
+	// keep it
+	N      int64
+	Params map[string]int64
+}
+
+// Describe summarizes every sketch of state.
+func Describe(state *pb.ServerState) []Info {
+	var out []Info
+	for _, s := range Sketches(state) {
+		info := Info{Kind: s.Kind(), Name: s.Name(), Type: s.Type(), Params: map[string]int64{}}
+		switch {
+		case s.Kll != nil:
+			info.N = s.Kll.N
+			info.Params["levels"] = int64(len(s.Kll.Rows))
+		case s.Count != nil:
+			info.Params["depth"] = int64(len(s.Count.Rows))
+			if len(s.Count.Rows) > 0 {
+				info.Params["width"] = int64(len(s.Count.Rows[0].Val))
+			}
+		case s.Hll != nil:
+			info.Params["registers"] = int64(len(s.Hll.Registers))
+		default:
+			info.Params["slots"] = int64(len(s.Asketch.Filter))
+			rows := s.Asketch.GetCountMin().GetRows()
+			info.Params["depth"] = int64(len(rows))
+			if len(rows) > 0 {
+				info.Params["width"] = int64(len(rows[0].Val))
+				for _, c := range rows[0].Val {
+					info.N += c
+				}
+			}
+			for _, slot := range s.Asketch.Filter {
+				if slot.New >= 0 {
+					info.N += slot.New - slot.Old
+				}
+			}
+		}
+		out = append(out, info)
+	}
+	return out
+}
+
+type mergeKey struct {
+	name string
+	typ  string
+}
+
+// Merge merges the sketches of every state that have the same kind, name and
+// type, like a server receiving them would.
+func Merge(states ...*pb.ServerState) (*pb.ServerState, error) {
+	out := &pb.ServerState{}
+	var err error
+	var all pb.ServerState
+	for _, state := range states {
+		all.Kll = append(all.Kll, state.Kll...)
+		all.Count = append(all.Count, state.Count...)
+		all.Hll = append(all.Hll, state.Hll...)
+		all.Asketch = append(all.Asketch, state.Asketch...)
+	}
+	out.Kll, err = mergeKind(all.Kll, (*pb.KLLSketch).GetName, (*pb.KLLSketch).GetType, mergeKll[int], mergeKll[float64])
+	if err != nil {
+		return nil, err
+	}
+	out.Count, err = mergeKind(all.Count, (*pb.CountSketch).GetName, (*pb.CountSketch).GetType, mergeCount[int], mergeCount[float64])
+	if err != nil {
+		return nil, err
+	}
+	out.Hll, err = mergeKind(all.Hll, (*pb.HLLSketch).GetName, (*pb.HLLSketch).GetType, mergeHll[int], mergeHll[float64])
+	if err != nil {
+		return nil, err
+	}
+	out.Asketch, err = mergeKind(all.Asketch, (*pb.ASketch).GetName, (*pb.ASketch).GetType, mergeASketch[int], mergeASketch[float64])
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// mergeKind groups sketches by name and type and merges each group with
+// mergeInt or mergeFloat.
+func mergeKind[S any](sketches []S, name func(S) string, typ func(S) string, mergeInt func([]S) (S, error), mergeFloat func([]S) (S, error)) ([]S, error) {
+	groups := map[mergeKey][]S{}
+	var keys []mergeKey
+	for _, s := range sketches {
+		key := mergeKey{name(s), typ(s)}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], s)
+	}
+	var out []S
+	for _, key := range keys {
+		var merged S
+		var err error
+		switch key.typ {
+		case "int":
+			merged, err = mergeInt(groups[key])
+		case "float64":
+			merged, err = mergeFloat(groups[key])
+		default:
+			return nil, fmt.Errorf("%s is not supported, please submit a valid type", key.typ)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not merge %s: %w", key.name, err)
+		}
+		out = append(out, merged)
+	}
+	return out, nil
+}
+
+func mergeKll[T shared.Number](sketches []*pb.KLLSketch) (*pb.KLLSketch, error) {
+	merged := convert.FromProtoKLL[T](sketches[0])
+	for _, s := range sketches[1:] {
+		merged.Merge(*convert.FromProtoKLL[T](s))
+	}
+	out := convert.ToProtoKLL(merged)
+	out.Name = sketches[0].Name
+	return out, nil
+}
+
+func mergeCount[T shared.Number](sketches []*pb.CountSketch) (*pb.CountSketch, error) {
+	merged := convert.FromProtoCount[T](sketches[0])
+	for _, s := range sketches[1:] {
+		other := convert.FromProtoCount[T](s)
+		if err := sameCounters(merged.Sketch, merged.Seeds, other); err != nil {
+			return nil, err
+		}
+		merged.Merge(*other)
+	}
+	out := convert.ToProtoCount(merged)
+	out.Name = sketches[0].Name
+	return out, nil
+}
+
+func mergeHll[T shared.Number](sketches []*pb.HLLSketch) (*pb.HLLSketch, error) {
+	merged := convert.FromProtoHll[T](sketches[0])
+	for _, s := range sketches[1:] {
+		if err := merged.Merge(*convert.FromProtoHll[T](s)); err != nil {
+			return nil, err
+		}
+	}
+	return convert.ToProtoHll(merged, sketches[0].Name), nil
+}
+
+func mergeASketch[T shared.Number](sketches []*pb.ASketch) (*pb.ASketch, error) {
+	merged := convert.FromProtoASketch[T](sketches[0])
+	_, rows, seeds := merged.Snapshot()
+	for _, s := range sketches[1:] {
+		other := convert.FromProtoASketch[T](s)
+		_, otherRows, otherSeeds := other.Snapshot()
+		if err := sameCounters(rows, seeds, count.NewCountFromData[T](otherRows, otherSeeds)); err != nil {
+			return nil, err
+		}
+		merged.MergeSketch(other)
+	}
+	return convert.ToProtoASketch(merged, sketches[0].Name), nil
+}
+
+// sameCounters checks that a Count sketch can be merged with or compared to
+// the counters rows hashed with seeds.
+func sameCounters[T shared.Number](rows [][]int, seeds []uint32, other *count.CountSketch[T]) error {
+	if len(rows) != len(other.Sketch) || (len(rows) > 0 && len(rows[0]) != len(other.Sketch[0])) {
+		return fmt.Errorf("sketches of %dx%d and %dx%d counters differ in size", len(rows), width(rows), len(other.Sketch), width(other.Sketch))
+	}
+	if !slices.Equal(seeds, other.Seeds) {
+		return fmt.Errorf("sketches hash with different seeds")
+	}
+	return nil
+}
+
+func width(rows [][]int) int {
+	if len(rows) == 0 {
+		return 0
+	}
+	return len(rows[0])
+}
+
+// Quantiles returns the values at the quantiles phis of a KLL sketch.
+func Quantiles(s *pb.KLLSketch, phis []float64) ([]float64, error) {
+	out := make([]float64, len(phis))
+	switch s.Type {
+	case "int":
+		sketch := convert.FromProtoKLL[int](s)
+		for i, phi := range phis {
+			out[i] = float64(sketch.QueryQuantile(phi))
+		}
+	case "float64":
+		sketch := convert.FromProtoKLL[float64](s)
+		for i, phi := range phis {
+			out[i] = sketch.QueryQuantile(phi)
+		}
+	default:
+		return nil, fmt.Errorf("%s is not supported, please submit a valid type", s.Type)
+	}
+	return out, nil
+}
+
+// Rank returns the number of items of a KLL sketch up to x.
+func Rank(s *pb.KLLSketch, x float64) (int, error) {
+	switch s.Type {
+	case "int":
+		return convert.FromProtoKLL[int](s).Query(int(x)), nil
+	case "float64":
+		return convert.FromProtoKLL[float64](s).Query(x), nil
+	}
+	return 0, fmt.Errorf("%s is not supported, please submit a valid type", s.Type)
+}
+
+// Frequency estimates how often x was added to a Count sketch or ASketch.
+func Frequency(s Sketch, x float64) (int, error) {
+	switch {
+	case s.Count != nil && s.Count.Type == "int":
+		return convert.FromProtoCount[int](s.Count).Query(int(x)), nil
+	case s.Count != nil && s.Count.Type == "float64":
+		return convert.FromProtoCount[float64](s.Count).Query(x), nil
+	case s.Asketch != nil && s.Asketch.Type == "int":
+		return convert.FromProtoASketch[int](s.Asketch).Query(int(x)), nil
+	case s.Asketch != nil && s.Asketch.Type == "float64":
+		return convert.FromProtoASketch[float64](s.Asketch).Query(x), nil
+	case s.Count != nil || s.Asketch != nil:
+		return 0, fmt.Errorf("%s is not supported, please submit a valid type", s.Type())
+	}
+	return 0, fmt.Errorf("%s sketches do not count items, use a count sketch or asketch", s.Kind())
+}
+
+// Cardinality estimates the distinct items of an HLL sketch.
+func Cardinality(s *pb.HLLSketch) (float64, error) {
+	switch s.Type {
+	case "int":
+		return convert.FromProtoHll[int](s).Query(), nil
+	case "float64":
+		return convert.FromProtoHll[float64](s).Query(), nil
+	}
+	return 0, fmt.Errorf("%s is not supported, please submit a valid type", s.Type)
+}
+
+// TopK returns the k most frequent items of the filter of an ASketch.
+func TopK(s *pb.ASketch, k int) []*pb.ASketchFilterEntry {
+	var out []*pb.ASketchFilterEntry
+	for _, slot := range s.Filter {
+		if slot.New >= 0 {
+			out = append(out, slot)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].New > out[j].New })
+	return out[:min(k, len(out))]
+}
+
+// Metric is one measure of how two sketches differ
+type Metric struct {
+	Name  string
+	Value float64
+}
+
+// Diff measures how far apart two sketches of the same kind and type are:
+//
+//   - kll: the Kolmogorov–Smirnov distance, the largest difference of their
+//     estimated CDFs at any item either sketch keeps
+//   - count: the L1 distance of their counters, the median over the rows
+//   - asketch: the L1 distance of the estimated counts of the items in either
+//     filter, and of the counters of their Count-Min sketches as for count
+//   - hll: both cardinalities, that of their union and their Jaccard index
+func Diff(a Sketch, b Sketch) ([]Metric, error) {
+	if a.Kind() != b.Kind() || a.Type() != b.Type() {
+		return nil, fmt.Errorf("can not compare a %s %s sketch to a %s %s sketch", a.Type(), a.Kind(), b.Type(), b.Kind())
+	}
+	switch a.Type() {
+	case "int":
+		return diff[int](a, b)
+	case "float64":
+		return diff[float64](a, b)
+	}
+	return nil, fmt.Errorf("%s is not supported, please submit a valid type", a.Type())
+}
+
+func diff[T shared.Number](a Sketch, b Sketch) ([]Metric, error) {
+	switch a.Kind() {
+	case "kll":
+		return []Metric{{"ks", ksDistance(convert.FromProtoKLL[T](a.Kll), convert.FromProtoKLL[T](b.Kll))}}, nil
+	case "count":
+		ca, cb := convert.FromProtoCount[T](a.Count), convert.FromProtoCount[T](b.Count)
+		if err := sameCounters(ca.Sketch, ca.Seeds, cb); err != nil {
+			return nil, err
+		}
+		return []Metric{{"l1", counterL1(ca.Sketch, cb.Sketch)}}, nil
+	case "hll":
+		ha, hb := convert.FromProtoHll[T](a.Hll), convert.FromProtoHll[T](b.Hll)
+		union := convert.FromProtoHll[T](a.Hll)
+		if err := union.Merge(*hb); err != nil {
+			return nil, err
+		}
+		ea, eb, eu := ha.Query(), hb.Query(), union.Query()
+		jaccard := 0.0
+		if eu > 0 {
+			jaccard = max(0, ea+eb-eu) / eu
+		}
+		return []Metric{{"cardinality_a", ea}, {"cardinality_b", eb}, {"union", eu}, {"jaccard", jaccard}}, nil
+	}
+	sa, sb := convert.FromProtoASketch[T](a.Asketch), convert.FromProtoASketch[T](b.Asketch)
+	items := map[T]bool{}
+	for _, slot := range append(sa.FilterSnapshot(), sb.FilterSnapshot()...) {
+		items[slot.Item] = true
+	}
+	filterL1 := 0.0
+	for x := range items {
+		filterL1 += math.Abs(float64(sa.Query(x) - sb.Query(x)))
+	}
+	_, rowsA, seedsA := sa.Snapshot()
+	_, rowsB, seedsB := sb.Snapshot()
+	if err := sameCounters(rowsA, seedsA, count.NewCountFromData[T](rowsB, seedsB)); err != nil {
+		return nil, err
+	}
+	return []Metric{{"filter_l1", filterL1}, {"countmin_l1", counterL1(rowsA, rowsB)}}, nil
+}
+
+// ksDistance is the Kolmogorov–Smirnov distance of two KLL sketches. Both
+// estimated CDFs are steps at the items the sketches keep, so the largest
+// difference is at one of them.
+func ksDistance[T shared.Number](a *kll.KLLSketch[T], b *kll.KLLSketch[T]) float64 {
+	if a.N == 0 || b.N == 0 {
+		if a.N == b.N {
+			return 0
+		}
+		return 1
+	}
+	d := 0.0
+	for _, sketch := range []*kll.KLLSketch[T]{a, b} {
+		for _, level := range sketch.Sketch {
+			for _, x := range level {
+				fa := float64(a.Query(x)) / float64(a.N)
+				fb := float64(b.Query(x)) / float64(b.N)
+				d = max(d, math.Abs(fa-fb))
+			}
+		}
+	}
+	return d
+}
+
+// counterL1 is the median over the rows of the L1 distance of two counter
+// arrays of the same size.
+func counterL1(a [][]int, b [][]int) float64 {
+	if len(a) == 0 {
+		return 0
+	}
+	rows := make([]float64, len(a))
+	for i := range a {
+		for j := range a[i] {
+			rows[i] += math.Abs(float64(a[i][j] - b[i][j]))
+		}
+	}
+	sort.Float64s(rows)
+	if len(rows)%2 == 1 {
+		return rows[len(rows)/2]
+	}
+	return (rows[len(rows)/2-1] + rows[len(rows)/2]) / 2
+}
@@ -0,0 +1,139 @@
+package sketchfile_test
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/shared"
+	"github.com/bruhng/distributed-sketching/sketches/asketch"
+	"github.com/bruhng/distributed-sketching/sketches/convert"
+	"github.com/bruhng/distributed-sketching/sketches/count"
+	"github.com/bruhng/distributed-sketching/sketches/hll"
+	"github.com/bruhng/distributed-sketching/sketches/kll"
+	"github.com/bruhng/distributed-sketching/sketchfile"
+	"google.golang.org/protobuf/proto"
+)
+
+func kllState(name string, from int, to int) *pb.ServerState {
+	sketch := kll.NewKLLSketch[int](200)
+	for i := from; i < to; i++ {
+		sketch.Add(i)
+	}
+	protoKll := convert.ToProtoKLL(sketch)
+	protoKll.Name = name
+	return &pb.ServerState{Kll: []*pb.KLLSketch{protoKll}}
+}
+
+func TestReadWrite(t *testing.T) {
+	state := kllState("speeds", 0, 100)
+	dir := t.TempDir()
+	for _, path := range []string{filepath.Join(dir, "s.pb"), filepath.Join(dir, "s.json")} {
+		if err := sketchfile.Write(path, state, ""); err != nil {
+			t.Fatal(err)
+		}
+		got, err := sketchfile.Read(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !proto.Equal(got, state) {
+			t.Errorf("%s read back as %v", path, got)
+		}
+	}
+	if err := sketchfile.Write(filepath.Join(dir, "s.xml"), state, "xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a, b := kllState("speeds", 0, 100), kllState("speeds", 100, 200)
+	other := kllState("other", 0, 10)
+	merged, err := sketchfile.Merge(a, b, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged.Kll) != 2 || merged.Kll[0].Name != "speeds" || merged.Kll[0].N != 200 || merged.Kll[1].N != 10 {
+		t.Fatalf("merged %v", sketchfile.Describe(merged))
+	}
+	q, err := sketchfile.Quantiles(merged.Kll[0], []float64{0, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// compaction keeps every other item, so the extremes may move a little
+	if q[0] > 5 || q[1] < 194 {
+		t.Errorf("min and max are %v, want about 0 and 199", q)
+	}
+
+	hlls := &pb.ServerState{}
+	for _, n := range []int{1, 2} {
+		sketch := hll.NewHLLSketch[int](shared.HLLRegisters, shared.HLLSeed)
+		for i := range 1000 * n {
+			sketch.Add(i)
+		}
+		hlls.Hll = append(hlls.Hll, convert.ToProtoHll(sketch, "ids"))
+	}
+	merged, err = sketchfile.Merge(hlls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	est, _ := sketchfile.Cardinality(merged.Hll[0])
+	if math.Abs(est-2000) > 200 {
+		t.Errorf("merged cardinality %v, want about 2000", est)
+	}
+
+	narrow := asketch.NewASketch[int](shared.ASketchSeed, shared.ASketchWidth/2, shared.ASketchDepth, shared.ASketchSlots)
+	reseeded := asketch.NewASketch[int](shared.ASketchSeed+1, shared.ASketchWidth, shared.ASketchDepth, shared.ASketchSlots)
+	for _, other := range []*asketch.ASketch[int]{narrow, reseeded} {
+		events := asketch.NewASketch[int](shared.ASketchSeed, shared.ASketchWidth, shared.ASketchDepth, shared.ASketchSlots)
+		state := &pb.ServerState{Asketch: []*pb.ASketch{convert.ToProtoASketch(events, "events"), convert.ToProtoASketch(other, "events")}}
+		if _, err := sketchfile.Merge(state); err == nil {
+			t.Error("expected an error merging ASketches of different counters")
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	state, _ := sketchfile.Merge(kllState("speeds", 0, 10), kllState("", 0, 10))
+	if s, err := sketchfile.Select(state, "", "", ""); err != nil || s.Kll.N != 10 {
+		t.Errorf("Select of the unnamed sketch gave %v, %v", s, err)
+	}
+	if _, err := sketchfile.Select(state, "hll", "speeds", ""); err == nil {
+		t.Error("expected an error for a missing sketch")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	same, _ := sketchfile.Select(kllState("a", 0, 1000), "kll", "a", "int")
+	shifted, _ := sketchfile.Select(kllState("b", 500, 1500), "kll", "b", "int")
+	m, err := sketchfile.Diff(same, same)
+	if err != nil || m[0].Name != "ks" || m[0].Value != 0 {
+		t.Errorf("distance of a sketch to itself is %v, %v", m, err)
+	}
+	m, err = sketchfile.Diff(same, shifted)
+	if err != nil || math.Abs(m[0].Value-0.5) > 0.05 {
+		t.Errorf("distance to a shifted sketch is %v, %v, want about 0.5", m, err)
+	}
+
+	ca, cb := count.NewCountSketch[int](157, 100, 10), count.NewCountSketch[int](157, 100, 10)
+	for range 7 {
+		ca.Add(3)
+	}
+	m, err = sketchfile.Diff(sketchfile.Sketch{Count: convert.ToProtoCount(ca)}, sketchfile.Sketch{Count: convert.ToProtoCount(cb)})
+	if err != nil || m[0].Name != "l1" || m[0].Value != 7 {
+		t.Errorf("count distance %v, %v, want 7", m, err)
+	}
+
+	aa := asketch.NewASketch[int](shared.ASketchSeed, shared.ASketchWidth, shared.ASketchDepth, shared.ASketchSlots)
+	ab := asketch.NewASketch[int](shared.ASketchSeed, shared.ASketchWidth, shared.ASketchDepth, shared.ASketchSlots)
+	aa.AddBy(1, 10)
+	ab.AddBy(1, 4)
+	m, err = sketchfile.Diff(sketchfile.Sketch{Asketch: convert.ToProtoASketch(aa, "")}, sketchfile.Sketch{Asketch: convert.ToProtoASketch(ab, "")})
+	if err != nil || m[0].Value != 6 || m[1].Value != 0 {
+		t.Errorf("asketch distance %v, %v, want 6 in the filter", m, err)
+	}
+
+	if _, err := sketchfile.Diff(same, sketchfile.Sketch{Count: convert.ToProtoCount(ca)}); err == nil {
+		t.Error("expected an error comparing different kinds")
+	}
+}
@@ -0,0 +1,225 @@
+package stream
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bruhng/distributed-sketching/shared"
+)
+
+// RowError reports a csv row that could not be turned into a stream element.
+type RowError struct {
+	Line   int
+	Column string
+	Value  string
+	Err    error
+}
+
+func (e *RowError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("line %d, column %s: %q %v", e.Line, e.Column, e.Value, e.Err)
+}
+
+var errNotANumber = errors.New("is not a number")
+
+// Cell is one raw value of a column together with the line it was read from.
+type Cell struct {
+	Line  int
+	Value string
+}
+
+// csvFile reads a csv row by row so only the current row is held in memory.
+// Files starting with the gzip magic bytes are decompressed transparently.
+type csvFile struct {
+	file    *os.File
+	gz      *gzip.Reader
+	reader  *csv.Reader
+	columns []int
+}
+
+func openCsv(path string, fields []string) (*csvFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &csvFile{file: file}
+
+	var r io.Reader = bufio.NewReader(file)
+	if magic, err := r.(*bufio.Reader).Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		f.gz, err = gzip.NewReader(r)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		r = f.gz
+	}
+	f.reader = csv.NewReader(r)
+	f.reader.ReuseRecord = true
+	f.reader.FieldsPerRecord = -1
+
+	header, err := f.reader.Read()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("could not read header: %w", err)
+	}
+	f.columns = make([]int, len(fields))
+	for c, field := range fields {
+		f.columns[c] = -1
+		for i, h := range header {
+			if h == field {
+				f.columns[c] = i
+				break
+			}
+		}
+		if f.columns[c] == -1 {
+			f.Close()
+			return nil, fmt.Errorf("invalid field name %s", field)
+		}
+	}
+	return f, nil
+}
+
+// next returns the cells of the selected columns of the next row. Rows that
+// are malformed or too short are returned as a *RowError and reading can
+// continue, io.EOF ends the file.
+func (f *csvFile) next() ([]string, int, error) {
+	record, err := f.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, parseErr.Line, &RowError{Line: parseErr.Line, Err: parseErr.Err}
+		}
+		return nil, 0, err
+	}
+	line, _ := f.reader.FieldPos(0)
+	cells := make([]string, len(f.columns))
+	for c, i := range f.columns {
+		if i >= len(record) {
+			return nil, line, &RowError{Line: line, Err: fmt.Errorf("has %d fields, column %d is missing", len(record), i+1)}
+		}
+		cells[c] = record[i]
+	}
+	return cells, line, nil
+}
+
+func (f *csvFile) Close() error {
+	if f.gz != nil {
+		f.gz.Close()
+	}
+	return f.file.Close()
+}
+
+// replayCsv reads path runAmount times (forever if negative) and calls emit
+// for every row, emit returning false skips the rest of the run. Row errors
+// go to report, other errors end the replay.
+func replayCsv(path string, fields []string, runAmount int, emit func(run int, cells []string, line int) bool, report func(error)) {
+	src, err := NewReplaySource(func() (Source, error) {
+		return openCsvSource(path, fields...)
+	}, runAmount)
+	if err != nil {
+		report(err)
+		return
+	}
+	defer src.Close()
+	cells := make([]string, 0, len(fields))
+	for {
+		cell, err := src.Next()
+		if err == io.EOF {
+			return
+		}
+		var rowErr *RowError
+		if errors.As(err, &rowErr) {
+			report(err)
+			continue
+		}
+		if err != nil {
+			report(err)
+			return
+		}
+		if cells = append(cells, cell.Value); len(cells) < len(fields) {
+			continue
+		}
+		if !emit(src.run, cells, cell.Line) {
+			if err := src.nextRun(); err != nil {
+				report(err)
+				return
+			}
+		}
+		cells = cells[:0]
+	}
+}
+
+// Columns are the raw cells of several fields of a csv, see NewColumnsFromCsv.
+type Columns struct {
+	// Cells has one channel per field, in the order the fields were given.
+	Cells []chan Cell
+	// Errors receives malformed rows and read failures like Stream.Errors, it
+	// is closed together with Cells.
+	Errors chan error
+	pace   *Limiter
+}
+
+func (c *Columns) report(err error) {
+	select {
+	case c.Errors <- err:
+	default:
+	}
+}
+
+// NewColumnsFromCsv reads the csv once per run and emits the raw cells of
+// every field on its own channel, in the same order as fields. A field may be
+// listed more than once to feed several sketches. Use ParseColumn to get a
+// typed stream.
+func NewColumnsFromCsv(csvPath string, fields []string, delayNano int, runAmount int, opts ...Option) (*Columns, error) {
+	f, err := openCsv(csvPath, fields)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+
+	pace := newOptions(opts).pacer(delayNano)
+	columns := &Columns{Cells: make([]chan Cell, len(fields)), Errors: make(chan error, 1000), pace: pace}
+	for c := range columns.Cells {
+		columns.Cells[c] = make(chan Cell, 1000)
+	}
+	go func() {
+		replayCsv(csvPath, fields, runAmount, func(_ int, cells []string, line int) bool {
+			for c, cell := range cells {
+				columns.Cells[c] <- Cell{Line: line, Value: cell}
+			}
+			pace.Wait()
+			return true
+		}, columns.report)
+		for _, ch := range columns.Cells {
+			close(ch)
+		}
+		close(columns.Errors)
+	}()
+	return columns, nil
+}
+
+// ParseColumn turns column c of columns into a stream of T, it lags with the
+// columns. Cells that are not numbers are reported on the stream's Errors
+// channel.
+func ParseColumn[T shared.Number](columns *Columns, c int, field string) *Stream[T] {
+	column := columns.Cells[c]
+	s := newStream[T](columns.pace)
+	go func() {
+		for cell := range column {
+			if parsed, ok := parseValue[T](cell.Value); ok {
+				s.Data <- parsed
+			} else {
+				s.report(&RowError{Line: cell.Line, Column: field, Value: cell.Value, Err: errNotANumber})
+			}
+		}
+		s.close()
+	}()
+	return s
+}
@@ -0,0 +1,21 @@
+package stream
+
+import "time"
+
+// FakeClock is a clock that only moves when a Limiter sleeps or the test
+// advances it.
+type FakeClock struct {
+	T time.Time
+}
+
+func (c *FakeClock) Now() time.Time { return c.T }
+
+func (c *FakeClock) Sleep(d time.Duration) { c.T = c.T.Add(d) }
+
+// NewFakeLimiter is NewLimiter with its time taken from c.
+func NewFakeLimiter(perSecond float64, mode Arrival, burstSize int, c *FakeClock) *Limiter {
+	l := NewLimiter(perSecond, mode)
+	l.burstSize = burstSize
+	l.clock = c
+	return l
+}
@@ -0,0 +1,180 @@
+package stream
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bruhng/distributed-sketching/shared"
+)
+
+// Generator returns the next value of a synthetic stream.
+type Generator func() float64
+
+// GeneratorSpec describes a synthetic stream, parsed from specs like
+//
+//	zipf?s=1.1&distinct=250000&n=1e6&seed=7
+//
+// Every distribution takes n (items per run, forever if negative or missing)
+// and seed (default 1). The distributions and their parameters are
+//
+//	zipf      s (skew > 1, default 1.5), v (default 1), distinct (default 250000)
+//	normal    mean (default 0), std (default 1)
+//	lognormal mu (default 0), sigma (default 1)
+//	uniform   min (default 0), max (default 1)
+//	drift     from, to (default 0, 100), std (default 1), over (default n),
+//	          shape linear or abrupt (default linear)
+//
+// drift is a normal stream whose mean moves from "from" to "to" over the first
+// "over" items, either gradually or in one jump halfway.
+type GeneratorSpec struct {
+	Dist   string
+	N      int
+	Seed   int64
+	params map[string]float64
+	shape  string
+}
+
+var generatorParams = map[string]map[string]float64{
+	"zipf":      {"s": 1.5, "v": 1, "distinct": 250000},
+	"normal":    {"mean": 0, "std": 1},
+	"lognormal": {"mu": 0, "sigma": 1},
+	"uniform":   {"min": 0, "max": 1},
+	"drift":     {"from": 0, "to": 100, "std": 1, "over": -1},
+}
+
+func ParseGeneratorSpec(spec string) (*GeneratorSpec, error) {
+	dist, rawQuery, _ := strings.Cut(spec, "?")
+	defaults, ok := generatorParams[dist]
+	if !ok {
+		return nil, fmt.Errorf("%s is not supported, please submit a valid distribution", dist)
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &GeneratorSpec{Dist: dist, N: -1, Seed: 1, params: map[string]float64{}, shape: "linear"}
+	for k, v := range defaults {
+		g.params[k] = v
+	}
+	for k, vs := range query {
+		v := vs[len(vs)-1]
+		if k == "shape" && dist == "drift" {
+			if v != "linear" && v != "abrupt" {
+				return nil, fmt.Errorf("%s is not supported, please submit a valid drift shape", v)
+			}
+			g.shape = v
+			continue
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s of %s: %s is not a valid number", k, dist, v)
+		}
+		switch _, known := defaults[k]; {
+		case k == "n":
+			g.N = int(f)
+		case k == "seed":
+			g.Seed = int64(f)
+		case known:
+			g.params[k] = f
+		default:
+			return nil, fmt.Errorf("%s has no parameter %s, valid are %s", dist, k, strings.Join(paramNames(dist), ", "))
+		}
+	}
+	if g.params["over"] < 0 {
+		g.params["over"] = float64(g.N)
+	}
+
+	switch {
+	case dist == "zipf" && (g.params["s"] <= 1 || g.params["v"] < 1 || g.params["distinct"] < 1):
+		return nil, fmt.Errorf("zipf needs s > 1, v >= 1 and distinct >= 1")
+	case dist == "uniform" && g.params["max"] < g.params["min"]:
+		return nil, fmt.Errorf("uniform needs min <= max")
+	case dist == "drift" && g.params["over"] <= 0:
+		return nil, fmt.Errorf("drift needs over > 0 or a finite n")
+	}
+	return g, nil
+}
+
+func paramNames(dist string) []string {
+	names := []string{"n", "seed"}
+	for k := range generatorParams[dist] {
+		names = append(names, k)
+	}
+	if dist == "drift" {
+		names = append(names, "shape")
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New returns a fresh generator, every generator of a spec yields the same
+// values.
+func (g *GeneratorSpec) New() Generator {
+	r := rand.New(rand.NewSource(g.Seed))
+	p := g.params
+	switch g.Dist {
+	case "zipf":
+		z := rand.NewZipf(r, p["s"], p["v"], uint64(p["distinct"])-1)
+		return func() float64 { return float64(z.Uint64()) }
+	case "normal":
+		return func() float64 { return p["mean"] + p["std"]*r.NormFloat64() }
+	case "lognormal":
+		return func() float64 { return math.Exp(p["mu"] + p["sigma"]*r.NormFloat64()) }
+	case "uniform":
+		return func() float64 { return p["min"] + (p["max"]-p["min"])*r.Float64() }
+	case "drift":
+		i := 0.0
+		return func() float64 {
+			progress := math.Min(i/p["over"], 1)
+			if g.shape == "abrupt" {
+				progress = math.Floor(math.Min(2*progress, 1))
+			}
+			i++
+			return p["from"] + (p["to"]-p["from"])*progress + p["std"]*r.NormFloat64()
+		}
+	}
+	panic("unknown distribution " + g.Dist)
+}
+
+// Generate returns the first n values of a spec, mostly for tests.
+func Generate[T shared.Number](g *GeneratorSpec, n int) []T {
+	next := g.New()
+	data := make([]T, n)
+	for i := range data {
+		data[i] = fromFloat[T](next())
+	}
+	return data
+}
+
+// NewStreamFromGenerator streams runAmount runs (forever if negative) of the
+// generator, restarting it from its seed every run.
+func NewStreamFromGenerator[T shared.Number](g *GeneratorSpec, delayNano int, runAmount int, opts ...Option) *Stream[T] {
+	pace := newOptions(opts).pacer(delayNano)
+	s := newStream[T](pace)
+	go func() {
+		for run := 0; runAmount < 0 || run < runAmount; run++ {
+			next := g.New()
+			for i := 0; g.N < 0 || i < g.N; i++ {
+				s.Data <- fromFloat[T](next())
+				pace.Wait()
+			}
+		}
+		s.close()
+	}()
+	return s
+}
+
+// fromFloat converts a generated value, rounding for int streams.
+func fromFloat[T shared.Number](v float64) T {
+	switch any(*new(T)).(type) {
+	case int:
+		return T(int64(math.Round(v)))
+	}
+	return T(v)
+}
@@ -0,0 +1,103 @@
+package stream_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/bruhng/distributed-sketching/stream"
+)
+
+func stats(data []float64) (mean, std float64) {
+	for _, v := range data {
+		mean += v
+	}
+	mean /= float64(len(data))
+	for _, v := range data {
+		std += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(std / float64(len(data)))
+}
+
+func TestGenerators(t *testing.T) {
+	spec := func(s string) *stream.GeneratorSpec {
+		g, err := stream.ParseGeneratorSpec(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return g
+	}
+
+	mean, std := stats(stream.Generate[float64](spec("normal?mean=10&std=2"), 20000))
+	if math.Abs(mean-10) > 0.1 || math.Abs(std-2) > 0.1 {
+		t.Errorf("normal: mean %v std %v", mean, std)
+	}
+
+	mean, _ = stats(stream.Generate[float64](spec("lognormal?mu=0&sigma=0.5"), 20000))
+	if want := math.Exp(0.125); math.Abs(mean-want) > 0.05 {
+		t.Errorf("lognormal: mean %v, want %v", mean, want)
+	}
+
+	for _, v := range stream.Generate[float64](spec("uniform?min=-3&max=5"), 10000) {
+		if v < -3 || v >= 5 {
+			t.Fatalf("uniform value %v out of range", v)
+		}
+	}
+
+	counts := map[int]int{}
+	for _, v := range stream.Generate[int](spec("zipf?s=1.1&distinct=1000"), 50000) {
+		if v < 0 || v >= 1000 {
+			t.Fatalf("zipf value %v out of range", v)
+		}
+		counts[v]++
+	}
+	if counts[0] <= counts[1] || counts[1] <= counts[10] {
+		t.Errorf("zipf counts not decreasing: %d %d %d", counts[0], counts[1], counts[10])
+	}
+
+	drift := stream.Generate[float64](spec("drift?from=0&to=100&std=1&n=10000"), 10000)
+	start, _ := stats(drift[:1000])
+	end, _ := stats(drift[9000:])
+	if math.Abs(start-5) > 1 || math.Abs(end-95) > 1 {
+		t.Errorf("linear drift: start %v end %v", start, end)
+	}
+	abrupt := stream.Generate[float64](spec("drift?to=50&over=1000&shape=abrupt"), 1000)
+	before, _ := stats(abrupt[:500])
+	after, _ := stats(abrupt[500:])
+	if math.Abs(before) > 0.5 || math.Abs(after-50) > 0.5 {
+		t.Errorf("abrupt drift: before %v after %v", before, after)
+	}
+}
+
+func TestGeneratorSeed(t *testing.T) {
+	a, _ := stream.ParseGeneratorSpec("normal?seed=3")
+	b, _ := stream.ParseGeneratorSpec("normal?seed=4")
+	if fmt.Sprint(stream.Generate[float64](a, 10)) != fmt.Sprint(stream.Generate[float64](a, 10)) {
+		t.Fatal("same seed gave different values")
+	}
+	if fmt.Sprint(stream.Generate[float64](a, 10)) == fmt.Sprint(stream.Generate[float64](b, 10)) {
+		t.Fatal("different seeds gave the same values")
+	}
+}
+
+func TestBadGeneratorSpecs(t *testing.T) {
+	for _, spec := range []string{"pareto", "zipf?s=1", "zipf?alpha=2", "normal?std=x", "uniform?min=2&max=1", "drift", "drift?n=10&shape=wavy"} {
+		if _, err := stream.ParseGeneratorSpec(spec); err == nil {
+			t.Errorf("%s was accepted", spec)
+		}
+	}
+}
+
+func TestOpenGenerator(t *testing.T) {
+	s, err := stream.Open[int]("gen:zipf?s=1.1&n=1e3", "item", 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := collect(s.Data)
+	if len(data) != 2000 {
+		t.Fatalf("got %d items, want 2000", len(data))
+	}
+	if fmt.Sprint(data[:1000]) != fmt.Sprint(data[1000:]) {
+		t.Fatal("runs of a seeded generator differ")
+	}
+}
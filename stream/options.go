@@ -0,0 +1,58 @@
+package stream
+
+import "time"
+
+// Option configures a stream built by Open or one of the NewStream*
+// constructors.
+type Option func(*options)
+
+type options struct {
+	arrival   Arrival
+	burstSize int
+	cutoff    int
+}
+
+func newOptions(opts []Option) options {
+	o := options{arrival: Uniform, burstSize: DefaultBurstSize, cutoff: -1}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithArrival paces the items with the given arrival pattern instead of
+// spacing them evenly.
+func WithArrival(mode Arrival) Option {
+	return func(o *options) { o.arrival = mode }
+}
+
+// WithBurstSize sets the number of items per burst of the Bursty arrival.
+func WithBurstSize(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.burstSize = n
+		}
+	}
+}
+
+// WithCutoff limits the number of items emitted per run of a csv file, n of
+// 0 or less keeps every item.
+func WithCutoff(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.cutoff = n
+		}
+	}
+}
+
+// pacer builds the limiter for a delay between items given in nanoseconds, as
+// taken by the stream constructors.
+func (o options) pacer(delayNano int) *Limiter {
+	perSecond := 0.0
+	if delayNano > 0 {
+		perSecond = float64(time.Second) / float64(delayNano)
+	}
+	l := NewLimiter(perSecond, o.arrival)
+	l.burstSize = o.burstSize
+	return l
+}
@@ -0,0 +1,151 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// ParquetSource emits the values of one column of a parquet file. Only flat
+// (non repeated) columns are supported, null values are skipped. Pages are
+// read one at a time so memory use is bounded by the size of a page, not the
+// file.
+type ParquetSource struct {
+	file   *os.File
+	column parquet.LeafColumn
+	groups []parquet.RowGroup
+	next   int // next row group to read
+
+	pages  parquet.Pages // of the current row group
+	values []parquet.Value
+	row    int
+}
+
+// NewParquetSource opens path and selects column, either by its dot separated
+// path in the schema or by its leaf name when that is unique.
+func NewParquetSource(path string, column string) (*ParquetSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	pf, err := parquet.OpenFile(file, info.Size())
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	col, err := findParquetColumn(pf.Schema(), column)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &ParquetSource{file: file, column: col, groups: pf.RowGroups()}, nil
+}
+
+// findParquetColumn returns the leaf of schema matching name.
+func findParquetColumn(schema *parquet.Schema, name string) (parquet.LeafColumn, error) {
+	var match []string
+	for _, path := range schema.Columns() {
+		if strings.Join(path, ".") == name {
+			match = path
+			break
+		}
+		if path[len(path)-1] == name {
+			if match != nil {
+				return parquet.LeafColumn{}, fmt.Errorf("column name %s is ambiguous, use its full path", name)
+			}
+			match = path
+		}
+	}
+	if match == nil {
+		return parquet.LeafColumn{}, fmt.Errorf("invalid field name %s", name)
+	}
+	leaf, _ := schema.Lookup(match...)
+	if leaf.MaxRepetitionLevel > 0 {
+		return parquet.LeafColumn{}, fmt.Errorf("repeated column %s is not supported", name)
+	}
+	return leaf, nil
+}
+
+func (p *ParquetSource) Next() (Cell, error) {
+	for {
+		for len(p.values) > 0 {
+			v := p.values[0]
+			p.values = p.values[1:]
+			p.row++
+			if !v.IsNull() {
+				return Cell{Line: p.row, Value: formatParquetValue(v)}, nil
+			}
+		}
+		if err := p.readPage(); err != nil {
+			return Cell{}, err
+		}
+	}
+}
+
+func (p *ParquetSource) Close() error {
+	if p.pages != nil {
+		p.pages.Close()
+	}
+	return p.file.Close()
+}
+
+// readPage reads the values of the next page of the column, moving on to the
+// next row group when the current one is used up.
+func (p *ParquetSource) readPage() error {
+	for {
+		if p.pages == nil {
+			if p.next >= len(p.groups) {
+				return io.EOF
+			}
+			p.pages = p.groups[p.next].ColumnChunks()[p.column.ColumnIndex].Pages()
+			p.next++
+		}
+		page, err := p.pages.ReadPage()
+		if err == io.EOF {
+			p.pages.Close()
+			p.pages = nil
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		p.values = make([]parquet.Value, page.NumValues())
+		n, err := page.Values().ReadValues(p.values)
+		parquet.Release(page)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		p.values = p.values[:n]
+		return nil
+	}
+}
+
+// formatParquetValue writes v the way it would appear in a csv, booleans as 0
+// or 1 so they can be sketched.
+func formatParquetValue(v parquet.Value) string {
+	switch v.Kind() {
+	case parquet.Boolean:
+		if v.Boolean() {
+			return "1"
+		}
+		return "0"
+	case parquet.Int32, parquet.Int64:
+		return strconv.FormatInt(v.Int64(), 10)
+	case parquet.Float:
+		return strconv.FormatFloat(float64(v.Float()), 'g', -1, 32)
+	case parquet.Double:
+		return strconv.FormatFloat(v.Double(), 'g', -1, 64)
+	default:
+		return string(v.ByteArray())
+	}
+}
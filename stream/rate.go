@@ -0,0 +1,138 @@
+package stream
+
+import (
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// Arrival is the shape of the inter-arrival times produced by a Limiter. All
+// modes emit the same average rate.
+type Arrival int
+
+const (
+	// Uniform spaces items evenly.
+	Uniform Arrival = iota
+	// Bursty emits a burst of items back to back and then idles.
+	Bursty
+	// Poisson draws exponentially distributed gaps, like independent events.
+	Poisson
+)
+
+// DefaultBurstSize is the number of items per burst in Bursty mode, see
+// WithBurstSize.
+const DefaultBurstSize = 100
+
+// minSleep is the shortest sleep a Limiter asks the scheduler for. Items due
+// within it are released together, which keeps the rate accurate even though
+// time.Sleep is much coarser than the gap between two items.
+const minSleep = time.Millisecond
+
+// maxLag bounds how far a Limiter catches up after the consumer stalled, so a
+// blocked channel does not turn into one huge burst.
+const maxLag = time.Second
+
+func ParseArrival(s string) (Arrival, error) {
+	switch s {
+	case "uniform", "":
+		return Uniform, nil
+	case "bursty":
+		return Bursty, nil
+	case "poisson":
+		return Poisson, nil
+	}
+	return Uniform, fmt.Errorf("%s is not supported, please submit a valid arrival mode", s)
+}
+
+func (a Arrival) String() string {
+	switch a {
+	case Bursty:
+		return "bursty"
+	case Poisson:
+		return "poisson"
+	}
+	return "uniform"
+}
+
+// Limiter paces a stream at a target rate without spinning. It keeps an
+// absolute schedule, so oversleeping on one item is paid back by the next ones
+// and the long run rate stays exact.
+type Limiter struct {
+	interval  time.Duration
+	mode      Arrival
+	next      time.Time
+	burstSize int
+	inBurst   int
+	rng       *rand.Rand
+	clock     clock
+	lag       atomic.Int64 // see Lag
+}
+
+// NewLimiter returns a limiter emitting perSecond items per second on average.
+// A rate of zero or less never waits.
+func NewLimiter(perSecond float64, mode Arrival) *Limiter {
+	l := &Limiter{mode: mode, burstSize: DefaultBurstSize, rng: rand.New(rand.NewSource(time.Now().UnixNano())), clock: realClock{}}
+	if perSecond > 0 {
+		l.interval = time.Duration(float64(time.Second) / perSecond)
+	}
+	return l
+}
+
+// Wait blocks until the next item may be emitted.
+func (l *Limiter) Wait() {
+	if l.interval <= 0 {
+		return
+	}
+	now := l.clock.Now()
+	var lag time.Duration
+	if !l.next.IsZero() && now.After(l.next) {
+		lag = now.Sub(l.next)
+	}
+	l.lag.Store(int64(lag))
+	if l.next.IsZero() || lag > maxLag {
+		l.next = now
+	}
+	if d := l.next.Sub(now); d > minSleep {
+		l.clock.Sleep(d)
+	}
+	l.next = l.next.Add(l.gap())
+}
+
+// Lag is how late the last item was released, the time between its place in
+// the schedule and the call to Wait. It grows while the consumer of the
+// stream falls behind the rate and is 0 without a rate. It may be read while
+// another goroutine waits.
+func (l *Limiter) Lag() time.Duration {
+	if l == nil {
+		return 0
+	}
+	return time.Duration(l.lag.Load())
+}
+
+func (l *Limiter) gap() time.Duration {
+	switch l.mode {
+	case Bursty:
+		l.inBurst++
+		if l.inBurst < l.burstSize {
+			return 0
+		}
+		l.inBurst = 0
+		return time.Duration(l.burstSize) * l.interval
+	case Poisson:
+		return time.Duration(l.rng.ExpFloat64() * float64(l.interval))
+	}
+	return l.interval
+}
+
+// clock is the time source of a Limiter, tests replace it with a fake one.
+type clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) Sleep(d time.Duration) { time.Sleep(d) }
@@ -0,0 +1,108 @@
+package stream_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bruhng/distributed-sketching/stream"
+)
+
+func TestLimiterRate(t *testing.T) {
+	const rate, items, burst = 20000.0, 4000, 100
+	interval := time.Duration(float64(time.Second) / rate)
+	want := time.Duration(items) * interval
+	for _, mode := range []stream.Arrival{stream.Uniform, stream.Bursty, stream.Poisson} {
+		clock := &stream.FakeClock{T: time.Unix(0, 0)}
+		l := stream.NewFakeLimiter(rate, mode, burst, clock)
+		for range items {
+			l.Wait()
+		}
+		// the last gap is never waited for, and items due within a
+		// millisecond are released together
+		got := clock.T.Sub(time.Unix(0, 0))
+		slack := time.Millisecond + burst*interval
+		if mode == stream.Poisson {
+			slack = want / 10
+		}
+		if got < want-slack || got > want+slack {
+			t.Errorf("%s: %d items took %v, want about %v", mode, items, got, want)
+		}
+	}
+}
+
+func TestLimiterBursts(t *testing.T) {
+	clock := &stream.FakeClock{T: time.Unix(0, 0)}
+	l := stream.NewFakeLimiter(1000, stream.Bursty, 10, clock)
+	var released []time.Time
+	for range 30 {
+		l.Wait()
+		released = append(released, clock.T)
+	}
+	// every burst of 10 is released at once, 10ms after the previous one
+	for i, at := range released {
+		if want := time.Unix(0, 0).Add(time.Duration(i/10) * 10 * time.Millisecond); !at.Equal(want) {
+			t.Fatalf("item %d released at %v, want %v", i, at.Sub(time.Unix(0, 0)), want.Sub(time.Unix(0, 0)))
+		}
+	}
+}
+
+func TestLimiterStall(t *testing.T) {
+	clock := &stream.FakeClock{T: time.Unix(0, 0)}
+	l := stream.NewFakeLimiter(100, stream.Uniform, 1, clock)
+	l.Wait()
+	// a consumer stalled for longer than a second is not caught up on
+	clock.T = clock.T.Add(5 * time.Second)
+	start := clock.T
+	for range 3 {
+		l.Wait()
+	}
+	if got := clock.T.Sub(start); got != 20*time.Millisecond {
+		t.Fatalf("3 items after a stall took %v, want 20ms", got)
+	}
+}
+
+func TestLimiterUnlimited(t *testing.T) {
+	l := stream.NewLimiter(0, stream.Uniform)
+	start := time.Now()
+	for range 100000 {
+		l.Wait()
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Fatal("an unlimited limiter waited")
+	}
+}
+
+func TestParseArrival(t *testing.T) {
+	for _, mode := range []stream.Arrival{stream.Uniform, stream.Bursty, stream.Poisson} {
+		if got, err := stream.ParseArrival(mode.String()); err != nil || got != mode {
+			t.Fatalf("ParseArrival(%q) = %v, %v", mode.String(), got, err)
+		}
+	}
+	if _, err := stream.ParseArrival("exponential"); err == nil {
+		t.Fatal("unknown arrival mode accepted")
+	}
+}
+
+func TestLimiterLag(t *testing.T) {
+	clock := &stream.FakeClock{T: time.Unix(0, 0)}
+	l := stream.NewFakeLimiter(100, stream.Uniform, 1, clock)
+	l.Wait()
+	l.Wait()
+	if lag := l.Lag(); lag != 0 {
+		t.Fatalf("lag on schedule = %v", lag)
+	}
+	// the consumer took 500ms for the item due at 20ms, the items after it
+	// are released back to back until the schedule is caught up
+	clock.T = clock.T.Add(500 * time.Millisecond)
+	l.Wait()
+	if lag := l.Lag(); lag != 490*time.Millisecond {
+		t.Fatalf("lag after a stall = %v, want 490ms", lag)
+	}
+	l.Wait()
+	if lag := l.Lag(); lag != 480*time.Millisecond {
+		t.Fatalf("lag while catching up = %v, want 480ms", lag)
+	}
+	if lag := stream.NewLimiter(0, stream.Uniform).Lag(); lag != 0 {
+		t.Fatalf("lag without a rate = %v", lag)
+	}
+}
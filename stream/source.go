@@ -0,0 +1,455 @@
+package stream
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bruhng/distributed-sketching/shared"
+)
+
+// Source produces the raw values of a data stream one by one. Next blocks
+// until a value is available and returns io.EOF once the source is
+// exhausted. A *RowError means only that value was bad and Next can be called
+// again, any other error ends the source.
+type Source interface {
+	Next() (Cell, error)
+	Close() error
+}
+
+// NewStreamFromSource parses the values of src into a stream of T, waiting
+// delayNano between elements. The source is closed when it is exhausted.
+func NewStreamFromSource[T shared.Number](src Source, field string, delayNano int, opts ...Option) *Stream[T] {
+	pace := newOptions(opts).pacer(delayNano)
+	s := newStream[T](pace)
+	go func() {
+		defer src.Close()
+		defer s.close()
+		for {
+			cell, err := src.Next()
+			if err == io.EOF {
+				return
+			}
+			var rowErr *RowError
+			if errors.As(err, &rowErr) {
+				s.report(err)
+				continue
+			}
+			if err != nil {
+				s.report(err)
+				return
+			}
+			parsed, ok := parseValue[T](cell.Value)
+			if !ok {
+				s.report(&RowError{Line: cell.Line, Column: field, Value: cell.Value, Err: errNotANumber})
+				continue
+			}
+			s.Data <- parsed
+			pace.Wait()
+		}
+	}()
+	return s
+}
+
+// Open builds a stream from a data set spec:
+//
+//	stdin or -           one value per line from standard input
+//	jsonl:<path>         newline delimited JSON, field is a dot separated path
+//	parquet:<path>       a column of a parquet file, field is the column path
+//	tcp:<addr>           lines sent to a TCP listener on addr, e.g. tcp::9000
+//	udp:<addr>           lines sent as UDP datagrams to addr
+//	csv:<path> or <path> a column of a csv file
+//	gen:<dist>?<params>  a synthetic stream, see GeneratorSpec
+//
+// Files ending in .jsonl, .ndjson or .parquet are recognised without the
+// prefix. Files are replayed runAmount times (forever if negative).
+func Open[T shared.Number](spec string, field string, delayNano int, runAmount int, opts ...Option) (*Stream[T], error) {
+	kind, path, ok := strings.Cut(spec, ":")
+	if !ok || len(kind) == 1 { // no scheme, or a windows drive letter
+		kind, path = "", spec
+	}
+	if kind == "" {
+		switch {
+		case spec == "stdin" || spec == "-":
+			kind = "stdin"
+		case strings.HasSuffix(spec, ".jsonl") || strings.HasSuffix(spec, ".ndjson"):
+			kind = "jsonl"
+		case strings.HasSuffix(spec, ".parquet"):
+			kind = "parquet"
+		default:
+			kind = "csv"
+		}
+	}
+
+	if kind == "gen" {
+		g, err := ParseGeneratorSpec(path)
+		if err != nil {
+			return nil, err
+		}
+		return NewStreamFromGenerator[T](g, delayNano, runAmount, opts...), nil
+	}
+
+	var src Source
+	var err error
+	switch kind {
+	case "stdin":
+		src = NewLineSource(os.Stdin)
+	case "jsonl":
+		src, err = NewReplaySource(func() (Source, error) {
+			f, err := os.Open(path)
+			if err != nil {
+				return nil, err
+			}
+			return NewJSONLinesSource(f, field), nil
+		}, runAmount)
+	case "parquet":
+		src, err = NewReplaySource(func() (Source, error) {
+			return NewParquetSource(path, field)
+		}, runAmount)
+	case "tcp", "udp":
+		src, err = ListenLines(kind, path)
+	case "csv":
+		src, err = NewCsvSource(path, field, runAmount)
+	default:
+		return nil, fmt.Errorf("%s is not a known data source", kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewStreamFromSource[T](src, field, delayNano, opts...), nil
+}
+
+// CsvSource reads the given fields of one pass over a csv file, the cells of
+// every row are emitted one after another in field order. Use NewCsvSource to
+// replay it, see NewStreamFromCsv.
+type CsvSource struct {
+	file *csvFile
+	row  []string
+	line int
+}
+
+func openCsvSource(path string, fields ...string) (*CsvSource, error) {
+	f, err := openCsv(path, fields)
+	if err != nil {
+		return nil, err
+	}
+	return &CsvSource{file: f}, nil
+}
+
+// NewCsvSource replays one column of a csv file runAmount times (forever if
+// negative).
+func NewCsvSource(path string, field string, runAmount int) (*ReplaySource, error) {
+	return NewReplaySource(func() (Source, error) {
+		return openCsvSource(path, field)
+	}, runAmount)
+}
+
+func (c *CsvSource) Next() (Cell, error) {
+	if len(c.row) == 0 {
+		cells, line, err := c.file.next()
+		if err != nil {
+			return Cell{Line: line}, err
+		}
+		c.row, c.line = cells, line
+	}
+	value := c.row[0]
+	c.row = c.row[1:]
+	return Cell{Line: c.line, Value: value}, nil
+}
+
+func (c *CsvSource) Close() error {
+	return c.file.Close()
+}
+
+// ReplaySource reads the sources returned by open one after another, runs
+// times in total (forever if negative). It ends early after a run without a
+// single cell, replaying it would not emit anything either.
+type ReplaySource struct {
+	open  func() (Source, error)
+	runs  int
+	run   int // of the current source, counting from 0
+	cur   Source
+	empty bool // no cell was read from cur yet
+}
+
+func NewReplaySource(open func() (Source, error), runs int) (*ReplaySource, error) {
+	cur, err := open()
+	if err != nil {
+		return nil, err
+	}
+	return &ReplaySource{open: open, runs: runs, cur: cur, empty: true}, nil
+}
+
+func (r *ReplaySource) Next() (Cell, error) {
+	for {
+		if r.cur == nil || r.runs == 0 {
+			return Cell{}, io.EOF
+		}
+		cell, err := r.cur.Next()
+		if err != io.EOF {
+			r.empty = false
+			return cell, err
+		}
+		if r.empty {
+			r.Close()
+			return Cell{}, io.EOF
+		}
+		if err = r.nextRun(); err != nil {
+			return Cell{}, err
+		}
+	}
+}
+
+// nextRun ends the current run early and opens the next one.
+func (r *ReplaySource) nextRun() error {
+	r.cur.Close()
+	r.cur = nil
+	r.runs--
+	if r.runs == 0 {
+		return nil
+	}
+	r.run++
+	cur, err := r.open()
+	if err != nil {
+		return err
+	}
+	r.cur, r.empty = cur, true
+	return nil
+}
+
+func (r *ReplaySource) Close() error {
+	if r.cur == nil {
+		return nil
+	}
+	err := r.cur.Close()
+	r.cur = nil
+	return err
+}
+
+// LineSource emits every non empty line of a reader, e.g. os.Stdin.
+type LineSource struct {
+	r       io.Reader
+	scanner *bufio.Scanner
+	line    int
+}
+
+func NewLineSource(r io.Reader) *LineSource {
+	return &LineSource{r: r, scanner: bufio.NewScanner(r)}
+}
+
+func (l *LineSource) Next() (Cell, error) {
+	for l.scanner.Scan() {
+		l.line++
+		text := strings.TrimSpace(l.scanner.Text())
+		if text != "" {
+			return Cell{Line: l.line, Value: text}, nil
+		}
+	}
+	if err := l.scanner.Err(); err != nil {
+		return Cell{}, err
+	}
+	return Cell{}, io.EOF
+}
+
+func (l *LineSource) Close() error {
+	if c, ok := l.r.(io.Closer); ok && l.r != os.Stdin {
+		return c.Close()
+	}
+	return nil
+}
+
+// JSONLinesSource emits one value per line of newline delimited JSON. The
+// value is selected with a dot separated path, array elements are selected by
+// index, e.g. "body.samples.0.speed".
+type JSONLinesSource struct {
+	lines *LineSource
+	path  []string
+}
+
+func NewJSONLinesSource(r io.Reader, path string) *JSONLinesSource {
+	var p []string
+	if path != "" {
+		p = strings.Split(path, ".")
+	}
+	return &JSONLinesSource{lines: NewLineSource(r), path: p}
+}
+
+func (j *JSONLinesSource) Next() (Cell, error) {
+	cell, err := j.lines.Next()
+	if err != nil {
+		return cell, err
+	}
+	dec := json.NewDecoder(strings.NewReader(cell.Value))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return cell, &RowError{Line: cell.Line, Err: err}
+	}
+	column := strings.Join(j.path, ".")
+	for _, key := range j.path {
+		switch node := v.(type) {
+		case map[string]any:
+			next, ok := node[key]
+			if !ok {
+				return cell, &RowError{Line: cell.Line, Column: column, Err: fmt.Errorf("has no %q", key)}
+			}
+			v = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return cell, &RowError{Line: cell.Line, Column: column, Err: fmt.Errorf("has no element %q", key)}
+			}
+			v = node[i]
+		default:
+			return cell, &RowError{Line: cell.Line, Column: column, Err: fmt.Errorf("cannot select %q of a %T", key, node)}
+		}
+	}
+	switch val := v.(type) {
+	case json.Number:
+		return Cell{Line: cell.Line, Value: val.String()}, nil
+	case string:
+		return Cell{Line: cell.Line, Value: val}, nil
+	default:
+		return cell, &RowError{Line: cell.Line, Column: column, Err: fmt.Errorf("is a %T, not a value", val)}
+	}
+}
+
+func (j *JSONLinesSource) Close() error {
+	return j.lines.Close()
+}
+
+// SocketSource listens on a TCP or UDP address and emits every line it
+// receives. TCP accepts any number of concurrent senders, a UDP datagram may
+// hold several lines. Next blocks until a line arrives or Close is called.
+type SocketSource struct {
+	listener net.Listener
+	packets  net.PacketConn
+	lines    chan Cell
+	done     chan struct{}
+	once     sync.Once
+	wg       sync.WaitGroup
+}
+
+// ListenLines starts a SocketSource, network is "tcp" or "udp".
+func ListenLines(network string, addr string) (*SocketSource, error) {
+	s := &SocketSource{lines: make(chan Cell, 1000), done: make(chan struct{})}
+	switch network {
+	case "tcp":
+		l, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		s.listener = l
+		s.wg.Add(1)
+		go s.acceptLoop()
+	case "udp":
+		pc, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			return nil, err
+		}
+		s.packets = pc
+		s.wg.Add(1)
+		go s.packetLoop()
+	default:
+		return nil, fmt.Errorf("%s is not a supported network, use tcp or udp", network)
+	}
+	return s, nil
+}
+
+// Addr is the address the source listens on.
+func (s *SocketSource) Addr() net.Addr {
+	if s.listener != nil {
+		return s.listener.Addr()
+	}
+	return s.packets.LocalAddr()
+}
+
+func (s *SocketSource) emit(line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return true
+	}
+	select {
+	case s.lines <- Cell{Value: line}:
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+func (s *SocketSource) acceptLoop() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			// unblock the scanner on Close, and stop watching once the peer is gone
+			ended := make(chan struct{})
+			defer close(ended)
+			go func() {
+				select {
+				case <-s.done:
+					conn.Close()
+				case <-ended:
+				}
+			}()
+			scanner := bufio.NewScanner(conn)
+			for scanner.Scan() {
+				if !s.emit(scanner.Text()) {
+					return
+				}
+			}
+		}()
+	}
+}
+
+func (s *SocketSource) packetLoop() {
+	defer s.wg.Done()
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := s.packets.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		for _, line := range strings.Split(string(buf[:n]), "\n") {
+			if !s.emit(line) {
+				return
+			}
+		}
+	}
+}
+
+func (s *SocketSource) Next() (Cell, error) {
+	select {
+	case cell := <-s.lines:
+		return cell, nil
+	case <-s.done:
+		return Cell{}, io.EOF
+	}
+}
+
+func (s *SocketSource) Close() error {
+	var err error
+	s.once.Do(func() {
+		close(s.done)
+		if s.listener != nil {
+			err = s.listener.Close()
+		} else {
+			err = s.packets.Close()
+		}
+		s.wg.Wait()
+	})
+	return err
+}
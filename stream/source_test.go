@@ -0,0 +1,230 @@
+package stream_test
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bruhng/distributed-sketching/stream"
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+)
+
+func TestJSONLinesSource(t *testing.T) {
+	input := `{"id": 1, "body": {"speed": 2.5, "tags": [7, 8]}}
+{"id": 2, "body": {"speed": "3"}}
+
+{"id": 3, "body": {}}
+not json
+{"id": 4, "body": {"speed": 4.75}}
+`
+	path := filepath.Join(t.TempDir(), "data.jsonl")
+	os.WriteFile(path, []byte(input), 0644)
+
+	s, err := stream.Open[float64](path, "body.speed", 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := collect(s.Data)
+	errs := collect(s.Errors)
+	want := []float64{2.5, 3, 4.75, 2.5, 3, 4.75}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if len(errs) != 4 {
+		t.Fatalf("got errors %v, want the missing field and bad line twice", errs)
+	}
+
+	tags := collect(stream.NewStreamFromSource[int](stream.NewJSONLinesSource(strings.NewReader(input), "body.tags.1"), "tags", 0).Data)
+	if len(tags) != 1 || tags[0] != 8 {
+		t.Fatalf("body.tags.1 = %v, want [8]", tags)
+	}
+}
+
+func TestLineSource(t *testing.T) {
+	got := collect(stream.NewStreamFromSource[int](stream.NewLineSource(strings.NewReader("1\n\n 2 \nx\n3")), "stdin", 0).Data)
+	if fmt.Sprint(got) != "[1 2 3]" {
+		t.Fatalf("got %v", got)
+	}
+}
+
+func TestSocketSource(t *testing.T) {
+	for _, network := range []string{"tcp", "udp"} {
+		src, err := stream.ListenLines(network, "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := stream.NewStreamFromSource[int](src, network, 0)
+
+		conn, err := net.Dial(network, src.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprint(conn, "1\n2\n3\n")
+		conn.Close()
+
+		for want := 1; want <= 3; want++ {
+			select {
+			case got := <-s.Data:
+				if got != want {
+					t.Fatalf("%s: got %d, want %d", network, got, want)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%s: timed out waiting for %d", network, want)
+			}
+		}
+		src.Close()
+		if _, ok := <-s.Data; ok {
+			t.Fatalf("%s: stream still open after Close", network)
+		}
+	}
+}
+
+func TestSocketSourceReleasesConnections(t *testing.T) {
+	src, err := stream.ListenLines("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	s := stream.NewStreamFromSource[int](src, "tcp", 0)
+	before := runtime.NumGoroutine()
+	for i := range 50 {
+		conn, err := net.Dial("tcp", src.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintln(conn, i)
+		conn.Close()
+		<-s.Data
+	}
+	// connection goroutines end shortly after their peer hangs up
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before+5 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d goroutines left after 50 closed connections, %d before", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+type parquetGPS struct {
+	Speed *float64 `parquet:"speed,optional,dict"`
+}
+
+type parquetRow struct {
+	ID  int64       `parquet:"id"`
+	GPS *parquetGPS `parquet:"gps,optional"`
+}
+
+// writeParquet writes two row groups of small pages with a required int64
+// column "id" and an optional double "gps.speed" in an optional group,
+// dictionary encoded, where every third speed is null.
+func writeParquet(t *testing.T, codec compress.Codec, rowsPerGroup int) (string, []int, []float64) {
+	path := filepath.Join(t.TempDir(), codec.String()+".parquet")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := parquet.NewGenericWriter[parquetRow](f, parquet.Compression(codec), parquet.PageBufferSize(256))
+	var ids []int
+	var speeds []float64
+	dict := []float64{0.5, 1.25, 2, 8.5}
+	for g := range 2 {
+		for r := range rowsPerGroup {
+			row := parquetRow{ID: int64(g*rowsPerGroup + r), GPS: &parquetGPS{}}
+			ids = append(ids, int(row.ID))
+			if r%3 != 2 {
+				row.GPS.Speed = &dict[r%4]
+				speeds = append(speeds, dict[r%4])
+			}
+			if _, err := w.Write([]parquetRow{row}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path, ids, speeds
+}
+
+func TestParquetSource(t *testing.T) {
+	for _, codec := range []compress.Codec{&parquet.Uncompressed, &parquet.Snappy, &parquet.Gzip, &parquet.Zstd} {
+		path, ids, speeds := writeParquet(t, codec, 1000)
+
+		gotIds := collect(stream.NewStreamFromSource[int](must(stream.NewParquetSource(path, "id")), "id", 0).Data)
+		if fmt.Sprint(gotIds) != fmt.Sprint(ids) {
+			t.Fatalf("%s: id = %v, want %v", codec, gotIds, ids)
+		}
+
+		s, err := stream.Open[float64]("parquet:"+path, "gps.speed", 0, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gotSpeeds := collect(s.Data)
+		if fmt.Sprint(gotSpeeds) != fmt.Sprint(speeds) {
+			t.Fatalf("%s: gps.speed = %v, want %v", codec, gotSpeeds, speeds)
+		}
+		if errs := collect(s.Errors); len(errs) != 0 {
+			t.Fatalf("%s: unexpected errors %v", codec, errs)
+		}
+	}
+
+	path, _, _ := writeParquet(t, &parquet.Uncompressed, 10)
+	if _, err := stream.NewParquetSource(path, "missing"); err == nil {
+		t.Fatal("opening a missing column did not fail")
+	}
+	if leaf := collect(stream.NewStreamFromSource[float64](must(stream.NewParquetSource(path, "speed")), "speed", 0).Data); len(leaf) == 0 {
+		t.Fatal("column could not be selected by its leaf name")
+	}
+}
+
+// testdata/datapage_v2.snappy.parquet is from apache/parquet-testing, written
+// by Spark with snappy compressed v2 data pages.
+func TestParquetSourceSparkFile(t *testing.T) {
+	path := filepath.Join("testdata", "datapage_v2.snappy.parquet")
+	for column, want := range map[string]string{
+		"b": "[1 2 3 4 5]", // delta binary packed int32
+		"c": "[2 3 4 5 2]", // dictionary encoded double
+		"d": "[1 1 1 0 1]", // rle encoded booleans
+		"a": "[]",          // strings are not numbers
+	} {
+		s, err := stream.Open[float64]("parquet:"+path, column, 0, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(collect(s.Data)); got != want {
+			t.Errorf("column %s = %v, want %v", column, got, want)
+		}
+	}
+	if _, err := stream.NewParquetSource(path, "e.list.element"); err == nil {
+		t.Error("a repeated column was accepted")
+	}
+
+	// a truncated file fails to open instead of being decoded
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	truncated := filepath.Join(t.TempDir(), "truncated.parquet")
+	os.WriteFile(truncated, data[:len(data)/2], 0644)
+	if _, err := stream.NewParquetSource(truncated, "b"); err == nil {
+		t.Error("a truncated file was opened")
+	}
+}
+
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
@@ -1,10 +1,8 @@
 package stream
 
 import (
-	"encoding/csv"
 	"fmt"
 	"math"
-	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -14,110 +12,119 @@ import (
 
 type Stream[T shared.Number] struct {
 	Data chan T
+	// Errors receives malformed rows and read failures. It is buffered and
+	// errors are dropped when nobody drains it, so reading it is optional.
+	// It is closed together with Data.
+	Errors chan error
+	pace   *Limiter
 }
 
-func preciseSleep(duration time.Duration) {
-	start := time.Now()
-	for time.Since(start) < duration {
-		// Busy-wait
+func newStream[T shared.Number](pace *Limiter) *Stream[T] {
+	return &Stream[T]{Data: make(chan T, 1000), Errors: make(chan error, 1000), pace: pace}
+}
+
+// Lag is how far the stream is behind the schedule of its rate, because its
+// consumer does not keep up, see Limiter.Lag. Unpaced streams never lag.
+func (s Stream[T]) Lag() time.Duration {
+	return s.pace.Lag()
+}
+
+func (s *Stream[T]) report(err error) {
+	select {
+	case s.Errors <- err:
+	default:
 	}
 }
 
-func NewStream[T shared.Number](data []T, delayNano int) *Stream[T] {
-	ch := make(chan T, 1000)
+func (s *Stream[T]) close() {
+	close(s.Data)
+	close(s.Errors)
+}
+
+func NewStream[T shared.Number](data []T, delayNano int, opts ...Option) *Stream[T] {
+	pace := newOptions(opts).pacer(delayNano)
+	s := newStream[T](pace)
 	go func() {
 		for _, item := range data {
-			ch <- item
-			preciseSleep(time.Duration(delayNano) * time.Nanosecond)
+			s.Data <- item
+			pace.Wait()
 		}
+		s.close()
 	}()
-	return &Stream[T]{Data: ch}
+	return s
 }
 
-func NewStreamFromCsv[T shared.Number](csvPath string, field string, delayNano int, runAmount int, optional_cutoff ...int) *Stream[T] {
-	cutoff := -1
-	if len(optional_cutoff) > 0 {
-		cutoff = optional_cutoff[0]
-	}
-	dataStream := NewStream(make([]T, 0), delayNano)
-	file, err := os.Open(csvPath)
+// NewStreamFromCsv streams one column of a (optionally gzip compressed) csv
+// file. Rows are parsed lazily while streaming, the file is reopened for every
+// one of runAmount runs (forever if negative). WithCutoff limits the number
+// of elements emitted per run.
+func NewStreamFromCsv[T shared.Number](csvPath string, field string, delayNano int, runAmount int, opts ...Option) *Stream[T] {
+	o := newOptions(opts)
+	// fail early on a missing file or field like the eager reader did
+	f, err := openCsv(csvPath, []string{field})
 	if err != nil {
 		fmt.Println(err)
 		panic("Could not read csv")
 	}
+	f.Close()
 
-	defer file.Close()
-
-	reader := csv.NewReader(file)
-
-	header, err := reader.Read()
-	if err != nil {
-		fmt.Println(err)
-		panic("could not reader header")
-	}
-
-	columnIndex := -1
-	for i, h := range header {
-		if h == field {
-			columnIndex = i
-			break
-		}
-	}
-	if columnIndex == -1 {
-		panic("Invalid field name")
-	}
-	streamArr := make([]T, 0)
-	for {
-		record, err := reader.Read()
-		if err != nil || cutoff == len(streamArr) {
-			break
-		}
-		data := strings.TrimSpace(record[columnIndex])
-
-		var parsed T
-		var ok bool
-
-		switch any(*new(T)).(type) {
-		case int:
-			// parse int first, or float second
-			if iv, err := strconv.ParseInt(data, 10, 64); err == nil {
-				parsed, ok = T(iv), true
-			} else if fv, err := strconv.ParseFloat(data, 64); err == nil {
-				// accept float number（e.g. 4.0、23.000）
-				if math.Abs(fv-math.Round(fv)) < 1e-9 {
-					parsed, ok = T(int64(math.Round(fv))), true
-				} else {
-					parsed, ok = T(int64(math.Round(fv))), true
-				}
+	pace := o.pacer(delayNano)
+	dataStream := newStream[T](pace)
+	go func() {
+		fields := []string{field}
+		emitted, currentRun := 0, 0
+		replayCsv(csvPath, fields, runAmount, func(run int, cells []string, line int) bool {
+			if run != currentRun {
+				emitted, currentRun = 0, run
 			}
-
-		case float64:
-			// float first, or int second
-			if fv, err := strconv.ParseFloat(data, 64); err == nil {
-				parsed, ok = T(fv), true
-			} else if iv, err := strconv.ParseInt(data, 10, 64); err == nil {
-				parsed, ok = T(float64(iv)), true
+			if o.cutoff == emitted {
+				return false
 			}
-		}
-
-		if !ok {
-			continue
-		}
-		streamArr = append(streamArr, parsed)
-	}
-	go func() {
-		for i := runAmount; i != 0; i-- {
-			for _, val := range streamArr {
-				dataStream.Data <- val
-				preciseSleep(time.Duration(delayNano) * time.Nanosecond)
+			parsed, ok := parseValue[T](cells[0])
+			if !ok {
+				dataStream.report(&RowError{Line: line, Column: field, Value: cells[0], Err: errNotANumber})
+				return true
 			}
-		}
-		close(dataStream.Data)
+			dataStream.Data <- parsed
+			emitted++
+			pace.Wait()
+			return true
+		}, dataStream.report)
+		dataStream.close()
 	}()
 
 	return dataStream
 }
 
+// parseValue converts one csv cell into T. Int streams round float cells and
+// float streams accept int cells.
+func parseValue[T shared.Number](cell string) (T, bool) {
+	data := strings.TrimSpace(cell)
+
+	var parsed T
+	var ok bool
+
+	switch any(*new(T)).(type) {
+	case int:
+		// parse int first, or float second
+		if iv, err := strconv.ParseInt(data, 10, 64); err == nil {
+			parsed, ok = T(iv), true
+		} else if fv, err := strconv.ParseFloat(data, 64); err == nil {
+			// accept float number（e.g. 4.0、23.000）
+			parsed, ok = T(int64(math.Round(fv))), true
+		}
+
+	case float64:
+		// float first, or int second
+		if fv, err := strconv.ParseFloat(data, 64); err == nil {
+			parsed, ok = T(fv), true
+		} else if iv, err := strconv.ParseInt(data, 10, 64); err == nil {
+			parsed, ok = T(float64(iv)), true
+		}
+	}
+	return parsed, ok
+}
+
 func parseNumber(s string) (any, error) {
 	if intValue, err := strconv.ParseInt(s, 10, 64); err == nil {
 		return intValue, nil
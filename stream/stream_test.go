@@ -0,0 +1,127 @@
+package stream_test
+
+import (
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bruhng/distributed-sketching/stream"
+)
+
+const testCsv = `id,speed,label
+1,2.5,a
+2,oops,b
+3,4,c
+4,5.5,d"d
+5
+6,7.25,f
+`
+
+func writeCsv(t *testing.T, name string, compress bool) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if !compress {
+		f.WriteString(testCsv)
+		return path
+	}
+	gz := gzip.NewWriter(f)
+	gz.Write([]byte(testCsv))
+	gz.Close()
+	return path
+}
+
+func collect[T any](data chan T) []T {
+	var out []T
+	for v := range data {
+		out = append(out, v)
+	}
+	return out
+}
+
+func TestStreamFromCsvReportsMalformedRows(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		path := writeCsv(t, "data.csv", compress)
+		s := stream.NewStreamFromCsv[float64](path, "speed", 0, 1)
+		got := collect(s.Data)
+		errs := collect(s.Errors)
+
+		want := []float64{2.5, 4, 7.25}
+		if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+			t.Fatalf("compress=%v: got %v, want %v", compress, got, want)
+		}
+		// oops, the bare quote and the short row
+		if len(errs) != 3 {
+			t.Fatalf("compress=%v: got errors %v, want 3", compress, errs)
+		}
+		var rowErr *stream.RowError
+		if !errors.As(errs[0], &rowErr) || rowErr.Line != 3 || rowErr.Value != "oops" {
+			t.Fatalf("compress=%v: first error = %v, want line 3 value oops", compress, errs[0])
+		}
+	}
+}
+
+func TestStreamFromCsvReplaysAndCutsOff(t *testing.T) {
+	path := writeCsv(t, "data.csv", false)
+	all := collect(stream.NewStreamFromCsv[int](path, "id", 0, 1).Data)
+
+	replayed := collect(stream.NewStreamFromCsv[int](path, "id", 0, 3).Data)
+	if len(replayed) != 3*len(all) {
+		t.Fatalf("3 runs emitted %d elements, want %d", len(replayed), 3*len(all))
+	}
+
+	// a cutoff of 0 keeps every item instead of ending every run at once
+	if uncut := collect(stream.NewStreamFromCsv[int](path, "id", 0, 2, stream.WithCutoff(0)).Data); len(uncut) != 2*len(all) {
+		t.Fatalf("cutoff 0 over 2 runs emitted %d elements, want %d", len(uncut), 2*len(all))
+	}
+	// a file without rows is not replayed forever
+	empty := filepath.Join(t.TempDir(), "empty.csv")
+	os.WriteFile(empty, []byte("id,speed,label\n"), 0o644)
+	if got := collect(stream.NewStreamFromCsv[int](empty, "id", 0, -1).Data); len(got) != 0 {
+		t.Fatalf("an empty file emitted %v", got)
+	}
+
+	cut := collect(stream.NewStreamFromCsv[int](path, "id", 0, 2, stream.WithCutoff(2)).Data)
+	if want := []int{1, 2, 1, 2}; len(cut) != len(want) || cut[0] != 1 || cut[1] != 2 || cut[2] != 1 || cut[3] != 2 {
+		t.Fatalf("cutoff 2 over 2 runs = %v, want %v", cut, want)
+	}
+}
+
+func TestColumnsFromCsv(t *testing.T) {
+	path := writeCsv(t, "data.csv.gz", true)
+	columns, err := stream.NewColumnsFromCsv(path, []string{"id", "speed", "id"}, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := stream.ParseColumn[int](columns, 0, "id")
+	speeds := stream.ParseColumn[float64](columns, 1, "speed")
+	idsAgain := stream.ParseColumn[int](columns, 2, "id")
+
+	done := make(chan []int)
+	go func() { done <- collect(idsAgain.Data) }()
+	go collect(speeds.Data)
+	got := collect(ids.Data)
+	if again := <-done; len(again) != len(got) {
+		t.Fatalf("column listed twice emitted %d and %d elements", len(got), len(again))
+	}
+	if len(got) == 0 || got[0] != 1 {
+		t.Fatalf("ids = %v", got)
+	}
+	// the bare quote and the short row, bad numbers are left to ParseColumn
+	if errs := collect(columns.Errors); len(errs) != 2 {
+		t.Fatalf("got errors %v, want 2", errs)
+	}
+
+	if _, err := stream.NewColumnsFromCsv(path, []string{"id", "colour"}, 0, 1); err == nil {
+		t.Fatal("a missing field was accepted")
+	}
+	if _, err := stream.NewColumnsFromCsv(filepath.Join(t.TempDir(), "missing.csv"), []string{"id"}, 0, 1); err == nil {
+		t.Fatal("a missing file was accepted")
+	}
+}
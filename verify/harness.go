@@ -0,0 +1,438 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/bruhng/distributed-sketching/output"
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/shared"
+	"github.com/bruhng/distributed-sketching/sketches/asketch"
+	"github.com/bruhng/distributed-sketching/sketches/convert"
+	"github.com/bruhng/distributed-sketching/sketches/count"
+	countmin "github.com/bruhng/distributed-sketching/sketches/count-min"
+	"github.com/bruhng/distributed-sketching/sketches/hll"
+	"github.com/bruhng/distributed-sketching/sketches/kll"
+	"github.com/bruhng/distributed-sketching/stream"
+)
+
+// harnessConfig selects the sketch a data set is replayed through and the
+// questions it is asked.
+type harnessConfig struct {
+	// sketch is kll, count, countmin, hll or asketch
+	sketch string
+	// parts is the number of sketches the data is split across round robin,
+	// like separate clients, before they are merged
+	parts int
+	// kllK is the accuracy parameter of KLL sketches
+	kllK int
+	phis []float64
+	// topK is the number of heavy hitters asked of an ASketch
+	topK int
+	// queries is the number of frequent and of random items point queried
+	queries int
+	seed    int64
+	// server merges the parts and answers the queries when set, name is the
+	// sketch it keeps them under
+	server pb.SketcherClient
+	name   string
+}
+
+type metric struct {
+	name  string
+	value float64
+}
+
+// report is the accuracy of one replay: a few summary metrics and a row per
+// question in detail.
+type report struct {
+	summary []metric
+	detail  *output.Table
+}
+
+func (r *report) summaryTable(cfg harnessConfig) *output.Table {
+	t := &output.Table{Columns: []string{"sketch", "metric", "value"}}
+	for _, m := range r.summary {
+		t.Rows = append(t.Rows, []any{cfg.sketch, m.name, m.value})
+	}
+	return t
+}
+
+// truth holds the exact answers for a data set
+type truth[T shared.Number] struct {
+	sorted []T
+	counts map[T]int
+}
+
+func newTruth[T shared.Number](data []T) *truth[T] {
+	t := &truth[T]{sorted: append([]T(nil), data...), counts: map[T]int{}}
+	sort.Slice(t.sorted, func(i, j int) bool { return t.sorted[i] < t.sorted[j] })
+	for _, x := range data {
+		t.counts[x]++
+	}
+	return t
+}
+
+// rankError is how far phi is from the quantiles x has in the data. Items
+// equal to x span an interval of quantiles, any phi inside it is exact.
+func (t *truth[T]) rankError(x T, phi float64) float64 {
+	n := float64(len(t.sorted))
+	lo := float64(sort.Search(len(t.sorted), func(i int) bool { return t.sorted[i] >= x })) / n
+	hi := float64(sort.Search(len(t.sorted), func(i int) bool { return t.sorted[i] > x })) / n
+	return max(0, lo-phi, phi-hi)
+}
+
+// quantile is the exact value at phi, the same way KLL picks it
+func (t *truth[T]) quantile(phi float64) T {
+	i := max(1, int(phi*float64(len(t.sorted))))
+	return t.sorted[i-1]
+}
+
+// top returns the k most frequent items, ties broken by the smaller item
+func (t *truth[T]) top(k int) []T {
+	items := make([]T, 0, len(t.counts))
+	for x := range t.counts {
+		items = append(items, x)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if t.counts[items[i]] != t.counts[items[j]] {
+			return t.counts[items[i]] > t.counts[items[j]]
+		}
+		return items[i] < items[j]
+	})
+	return items[:min(k, len(items))]
+}
+
+// pointQueries are the n most frequent items and n other distinct items
+// picked at random.
+func (t *truth[T]) pointQueries(n int, seed int64) []T {
+	top := t.top(len(t.counts))
+	out := append([]T(nil), top[:min(n, len(top))]...)
+	rest := top[len(out):]
+	r := rand.New(rand.NewSource(seed))
+	r.Shuffle(len(rest), func(i, j int) { rest[i], rest[j] = rest[j], rest[i] })
+	return append(out, rest[:min(n, len(rest))]...)
+}
+
+// split deals data round robin into parts slices
+func split[T shared.Number](data []T, parts int) [][]T {
+	out := make([][]T, max(1, parts))
+	for i, x := range data {
+		out[i%len(out)] = append(out[i%len(out)], x)
+	}
+	return out
+}
+
+func numeric[T shared.Number](x T, name string) *pb.NumericValue {
+	v := &pb.NumericValue{Type: fmt.Sprintf("%T", x), Name: name}
+	if v.Type == "int" {
+		v.Value = &pb.NumericValue_IntVal{IntVal: int64(x)}
+	} else {
+		v.Value = &pb.NumericValue_FloatVal{FloatVal: float64(x)}
+	}
+	return v
+}
+
+func fromNumeric[T shared.Number](v *pb.NumericValue) T {
+	if i, ok := v.GetValue().(*pb.NumericValue_IntVal); ok {
+		return T(i.IntVal)
+	}
+	return T(v.GetFloatVal())
+}
+
+// item is x as a table value, ints stay ints
+func item[T shared.Number](x T) any {
+	if fmt.Sprintf("%T", x) == "int" {
+		return int64(x)
+	}
+	return float64(x)
+}
+
+func relError(estimate float64, exact float64) float64 {
+	if exact == 0 {
+		return math.Abs(estimate)
+	}
+	return math.Abs(estimate-exact) / exact
+}
+
+// evaluate replays data through the sketch of cfg and compares its answers
+// with the exact ones.
+func evaluate[T shared.Number](ctx context.Context, data []T, cfg harnessConfig) (*report, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("the data set is empty")
+	}
+	if cfg.server != nil && cfg.sketch == "countmin" {
+		return nil, fmt.Errorf("the server keeps no countmin sketches, evaluate them locally")
+	}
+	if cfg.server != nil {
+		if err := checkNewName[T](ctx, cfg); err != nil {
+			return nil, err
+		}
+	}
+	t := newTruth(data)
+	parts := split(data, cfg.parts)
+	switch cfg.sketch {
+	case "kll":
+		return evaluateKll(ctx, t, parts, cfg)
+	case "count", "countmin":
+		return evaluateCount(ctx, t, parts, cfg)
+	case "hll":
+		return evaluateHll(ctx, t, parts, cfg)
+	case "asketch":
+		return evaluateTopK(ctx, t, parts, cfg)
+	}
+	return nil, fmt.Errorf("%s is not supported, please submit a valid sketch", cfg.sketch)
+}
+
+// checkNewName fails when the server already keeps a sketch under the name of
+// cfg, the replay would be merged into it and not measured alone.
+func checkNewName[T shared.Number](ctx context.Context, cfg harnessConfig) error {
+	res, err := cfg.server.DescribeSketch(ctx, &pb.DescribeRequest{Name: cfg.name, Kind: cfg.sketch, Type: fmt.Sprintf("%T", *new(T))})
+	if err != nil {
+		return err
+	}
+	if len(res.Sketches) > 0 {
+		return fmt.Errorf("the server already keeps a %s sketch %q, pick another name", cfg.sketch, cfg.name)
+	}
+	return nil
+}
+
+func evaluateKll[T shared.Number](ctx context.Context, t *truth[T], parts [][]T, cfg harnessConfig) (*report, error) {
+	var quantile func(phi float64) (T, error)
+	sketches := make([]*kll.KLLSketch[T], len(parts))
+	for i, part := range parts {
+		sketches[i] = kll.NewKLLSketch[T](cfg.kllK)
+		for _, x := range part {
+			sketches[i].Add(x)
+		}
+	}
+	if cfg.server != nil {
+		for _, sketch := range sketches {
+			protoSketch := convert.ToProtoKLL(sketch)
+			protoSketch.Name = cfg.name
+			if _, err := cfg.server.MergeKll(ctx, protoSketch); err != nil {
+				return nil, err
+			}
+		}
+		quantile = func(phi float64) (T, error) {
+			res, err := cfg.server.ReverseQueryKll(ctx, &pb.ReverseQuery{Phi: phi, Type: fmt.Sprintf("%T", *new(T)), Name: cfg.name})
+			if err != nil {
+				return 0, err
+			}
+			return fromNumeric[T](res), nil
+		}
+	} else {
+		for _, sketch := range sketches[1:] {
+			sketches[0].Merge(*sketch)
+		}
+		quantile = func(phi float64) (T, error) { return sketches[0].QueryQuantile(phi), nil }
+	}
+
+	r := &report{detail: &output.Table{Columns: []string{"phi", "estimate", "exact", "rank_error"}}}
+	var worst, sum float64
+	for _, phi := range cfg.phis {
+		estimate, err := quantile(phi)
+		if err != nil {
+			return nil, err
+		}
+		e := t.rankError(estimate, phi)
+		worst, sum = max(worst, e), sum+e
+		r.detail.Rows = append(r.detail.Rows, []any{phi, item(estimate), item(t.quantile(phi)), e})
+	}
+	r.summary = []metric{
+		{"n", float64(len(t.sorted))},
+		{"max_rank_error", worst},
+		{"mean_rank_error", sum / float64(len(cfg.phis))},
+	}
+	return r, nil
+}
+
+func evaluateCount[T shared.Number](ctx context.Context, t *truth[T], parts [][]T, cfg harnessConfig) (*report, error) {
+	var estimate func(x T) (int, error)
+	switch {
+	case cfg.sketch == "countmin":
+		merged := countmin.NewCountMin[T](shared.ASketchSeed, shared.ASketchWidth, shared.ASketchDepth)
+		for _, part := range parts {
+			sketch := countmin.NewCountMin[T](shared.ASketchSeed, shared.ASketchWidth, shared.ASketchDepth)
+			for _, x := range part {
+				sketch.Add(x)
+			}
+			merged.Merge(*sketch)
+		}
+		estimate = func(x T) (int, error) { return merged.Query(x), nil }
+	case cfg.server != nil:
+		for _, part := range parts {
+			sketch := count.NewCountSketch[T](157, 100, 10)
+			for _, x := range part {
+				sketch.Add(x)
+			}
+			protoSketch := convert.ToProtoCount(sketch)
+			protoSketch.Name = cfg.name
+			if _, err := cfg.server.MergeCount(ctx, protoSketch); err != nil {
+				return nil, err
+			}
+		}
+		estimate = func(x T) (int, error) {
+			res, err := cfg.server.QueryCount(ctx, numeric(x, cfg.name))
+			if err != nil {
+				return 0, err
+			}
+			return int(res.Res), nil
+		}
+	default:
+		merged := count.NewCountSketch[T](157, 100, 10)
+		for _, part := range parts {
+			sketch := count.NewCountSketch[T](157, 100, 10)
+			for _, x := range part {
+				sketch.Add(x)
+			}
+			merged.Merge(*sketch)
+		}
+		estimate = func(x T) (int, error) { return merged.Query(x), nil }
+	}
+
+	r := &report{detail: &output.Table{Columns: []string{"item", "exact", "estimate", "rel_error"}}}
+	queries := t.pointQueries(cfg.queries, cfg.seed)
+	var worst, sum float64
+	for _, x := range queries {
+		est, err := estimate(x)
+		if err != nil {
+			return nil, err
+		}
+		e := relError(float64(est), float64(t.counts[x]))
+		worst, sum = max(worst, e), sum+e
+		r.detail.Rows = append(r.detail.Rows, []any{item(x), int64(t.counts[x]), int64(est), e})
+	}
+	r.summary = []metric{
+		{"queries", float64(len(queries))},
+		{"are", sum / float64(len(queries))},
+		{"max_rel_error", worst},
+	}
+	return r, nil
+}
+
+func evaluateHll[T shared.Number](ctx context.Context, t *truth[T], parts [][]T, cfg harnessConfig) (*report, error) {
+	merged := hll.NewHLLSketch[T](shared.HLLRegisters, shared.HLLSeed)
+	var estimate float64
+	for _, part := range parts {
+		sketch := hll.NewHLLSketch[T](shared.HLLRegisters, shared.HLLSeed)
+		for _, x := range part {
+			sketch.Add(x)
+		}
+		if cfg.server != nil {
+			if _, err := cfg.server.MergeHll(ctx, convert.ToProtoHll(sketch, cfg.name)); err != nil {
+				return nil, err
+			}
+		} else if err := merged.Merge(*sketch); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.server != nil {
+		res, err := cfg.server.QueryHll(ctx, &pb.CardinalityRequest{Name: cfg.name, Type: fmt.Sprintf("%T", *new(T))})
+		if err != nil {
+			return nil, err
+		}
+		estimate = res.Estimate
+	} else {
+		estimate = merged.Query()
+	}
+
+	exact := float64(len(t.counts))
+	e := relError(estimate, exact)
+	return &report{
+		summary: []metric{{"exact", exact}, {"estimate", estimate}, {"rel_error", e}},
+		detail: &output.Table{
+			Columns: []string{"exact", "estimate", "rel_error"},
+			Rows:    [][]any{{int64(exact), estimate, e}},
+		},
+	}, nil
+}
+
+func evaluateTopK[T shared.Number](ctx context.Context, t *truth[T], parts [][]T, cfg harnessConfig) (*report, error) {
+	var slots []asketch.FilterSlot[T]
+	merged := asketch.NewASketch[T](shared.ASketchSeed, shared.ASketchWidth, shared.ASketchDepth, shared.ASketchSlots)
+	for _, part := range parts {
+		sketch := asketch.NewASketch[T](shared.ASketchSeed, shared.ASketchWidth, shared.ASketchDepth, shared.ASketchSlots)
+		for _, x := range part {
+			sketch.Add(x)
+		}
+		if cfg.server != nil {
+			if _, err := cfg.server.MergeASketch(ctx, convert.ToProtoASketch(sketch, cfg.name)); err != nil {
+				return nil, err
+			}
+		} else {
+			merged.MergeSketch(sketch)
+		}
+	}
+	if cfg.server != nil {
+		res, err := cfg.server.TopKASketch(ctx, &pb.TopKRequest{K: uint32(cfg.topK), Type: fmt.Sprintf("%T", *new(T)), Name: cfg.name})
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range res.Entries {
+			slots = append(slots, asketch.FilterSlot[T]{Item: fromNumeric[T](e.Key), New: int(e.EstFreq)})
+		}
+	} else {
+		slots = merged.TopK(cfg.topK)
+	}
+
+	exactTop := map[T]bool{}
+	for _, x := range t.top(cfg.topK) {
+		exactTop[x] = true
+	}
+	r := &report{detail: &output.Table{Columns: []string{"rank", "item", "estimate", "exact", "in_exact_top"}}}
+	var hits int
+	var sum float64
+	for i, slot := range slots {
+		if exactTop[slot.Item] {
+			hits++
+		}
+		sum += relError(float64(slot.New), float64(t.counts[slot.Item]))
+		r.detail.Rows = append(r.detail.Rows, []any{int64(i + 1), item(slot.Item), int64(slot.New), int64(t.counts[slot.Item]), exactTop[slot.Item]})
+	}
+	precision, are := 0.0, 0.0
+	if len(slots) > 0 {
+		precision, are = float64(hits)/float64(len(slots)), sum/float64(len(slots))
+	}
+	r.summary = []metric{
+		{"k", float64(cfg.topK)},
+		{"precision", precision},
+		{"recall", float64(hits) / float64(len(exactTop))},
+		{"are", are},
+	}
+	return r, nil
+}
+
+// replay reads the data set spec and evaluates the sketch of cfg on it
+func replay[T shared.Number](spec string, field string, cfg harnessConfig) (*report, error) {
+	data, err := readAll[T](spec, field)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	return evaluate(ctx, data, cfg)
+}
+
+// readAll collects the values of a data set spec, see stream.Open
+func readAll[T shared.Number](spec string, field string) ([]T, error) {
+	s, err := stream.Open[T](spec, field, 0, 1)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for err := range s.Errors {
+			log.Println("skipping row:", err)
+		}
+	}()
+	var data []T
+	for x := range s.Data {
+		data = append(data, x)
+	}
+	return data, nil
+}
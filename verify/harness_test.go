@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bruhng/distributed-sketching/server/servertest"
+)
+
+func TestRankError(t *testing.T) {
+	tr := newTruth([]int{1, 2, 2, 2, 3})
+	for _, tc := range []struct {
+		x    int
+		phi  float64
+		want float64
+	}{
+		{2, 0.5, 0},
+		{2, 0.2, 0},
+		{2, 0.8, 0},
+		{1, 0.5, 0.3},
+		{3, 0.5, 0.3},
+	} {
+		if got := tr.rankError(tc.x, tc.phi); got < tc.want-1e-9 || got > tc.want+1e-9 {
+			t.Errorf("rankError(%d, %v) = %v, want %v", tc.x, tc.phi, got, tc.want)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	var data []int
+	for i := range 2000 {
+		data = append(data, i%100, i%7)
+	}
+	for _, tc := range []struct {
+		sketch string
+		metric string
+		ok     func(float64) bool
+	}{
+		{"kll", "max_rank_error", func(v float64) bool { return v < 0.05 }},
+		{"countmin", "are", func(v float64) bool { return v < 0.5 }},
+		{"count", "queries", func(v float64) bool { return v == 20 }},
+		{"hll", "rel_error", func(v float64) bool { return v < 0.1 }},
+		{"asketch", "precision", func(v float64) bool { return v == 1 }},
+	} {
+		cfg := harnessConfig{sketch: tc.sketch, parts: 3, kllK: 200, phis: []float64{0.1, 0.5, 0.9}, topK: 5, queries: 10, seed: 1}
+		r, err := evaluate(context.Background(), data, cfg)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.sketch, err)
+		}
+		var found bool
+		for _, m := range r.summary {
+			if m.name == tc.metric {
+				found = true
+				if !tc.ok(m.value) {
+					t.Errorf("%s: %s = %v", tc.sketch, m.name, m.value)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("%s: no %s in %v", tc.sketch, tc.metric, r.summary)
+		}
+		if len(r.detail.Rows) == 0 {
+			t.Errorf("%s: no detail rows", tc.sketch)
+		}
+	}
+
+	if _, err := evaluate(context.Background(), data, harnessConfig{sketch: "tdigest"}); err == nil {
+		t.Error("expected an error for an unknown sketch")
+	}
+}
+
+func TestEvaluateOnServer(t *testing.T) {
+	var data []int
+	for i := range 2000 {
+		data = append(data, i%100, i%7)
+	}
+	c := servertest.NewServer(t).Client(t)
+	for _, tc := range []struct {
+		sketch string
+		metric string
+		ok     func(float64) bool
+	}{
+		{"kll", "max_rank_error", func(v float64) bool { return v < 0.05 }},
+		{"count", "are", func(v float64) bool { return v < 0.5 }},
+		{"hll", "rel_error", func(v float64) bool { return v < 0.1 }},
+		{"asketch", "precision", func(v float64) bool { return v == 1 }},
+	} {
+		cfg := harnessConfig{sketch: tc.sketch, parts: 3, kllK: 200, phis: []float64{0.1, 0.5, 0.9}, topK: 5, queries: 10, seed: 1, server: c, name: "verify_" + tc.sketch}
+		r, err := evaluate(context.Background(), data, cfg)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.sketch, err)
+		}
+		for _, m := range r.summary {
+			if m.name == tc.metric && !tc.ok(m.value) {
+				t.Errorf("%s: %s = %v", tc.sketch, m.name, m.value)
+			}
+		}
+		if _, err := evaluate(context.Background(), data, cfg); err == nil {
+			t.Errorf("%s: expected an error replaying into a sketch the server already keeps", tc.sketch)
+		}
+	}
+}
@@ -9,7 +9,12 @@ import (
 	"os"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
+
+	pb "github.com/bruhng/distributed-sketching/proto"
+	"github.com/bruhng/distributed-sketching/security"
+	"google.golang.org/grpc"
 )
 
 func quantize(v float64, round int) float64 {
@@ -88,7 +93,8 @@ type est struct {
 	est  int
 }
 
-func readASketchBlock(path string, round int) ([]est, error) {
+// readASketchBlock returns the last top-k block auto_query wrote for field.
+func readASketchBlock(path string, field string, round int) ([]est, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -107,7 +113,7 @@ func readASketchBlock(path string, round int) ([]est, error) {
 	blocks := map[string][]est{}
 	order := []string{}
 	for i := 1; i < len(rows); i++ {
-		if len(rows[i]) < 5 {
+		if len(rows[i]) < 5 || rows[i][2] != field {
 			continue
 		}
 		ts := rows[i][0]
@@ -141,10 +147,79 @@ func main() {
 	round := flag.Int("round", -1, "quantize float by N decimals; -1 to disable")
 	topk := flag.Int("k", 10, "K")
 	asketch := flag.String("asketch", "topk_result.csv", "ASketch CSV exported by auto_query")
+	field := flag.String("field", "", "field column to compare in the ASketch CSV (defaults to --header)")
+	sketch := flag.String("sketch", "", "replay --truth through a kll, count, countmin, hll or asketch sketch instead of reading --asketch")
+	typ := flag.String("type", "float", "element type of the replayed sketch, float or int")
+	parts := flag.Int("parts", 1, "number of sketches the data is split across before they are merged")
+	kllK := flag.Int("kllk", 200, "k of replayed KLL sketches")
+	phis := flag.String("phi", "0.01,0.05,0.1,0.25,0.5,0.75,0.9,0.95,0.99", "quantiles asked of replayed KLL sketches")
+	queries := flag.Int("queries", 20, "number of frequent and of random items point queried in count sketches")
+	seed := flag.Int64("seed", 1, "seed picking the random point queries")
+	server := flag.String("server", "", "address of a server that merges the replayed sketches and answers, e.g. 127.0.0.1:8080")
+	name := flag.String("name", "", "new name the server keeps the replayed sketches under, verify:<sketch>:<time> by default")
+	format := flag.String("o", "table", "output format of the replay: table, json or csv")
+	out := flag.String("out", "", "file to write the replay report to instead of standard output")
+	detail := flag.Bool("detail", false, "report every query of the replay instead of the summary")
+	security.RegisterFlags(flag.CommandLine)
 	flag.Parse()
 
 	if *truthCSV == "" || *header == "" {
-		log.Fatal("usage: verify_topk --truth data.csv --header <name> --k 10 --asketch vdop_topk.csv")
+		log.Fatal("usage: verify_topk --truth data.csv --header <name> --k 10 --asketch vdop_topk.csv\n" +
+			"       verify_topk --truth <data set> --header <name> --sketch kll|count|countmin|hll|asketch [--server addr] [--o csv]")
+	}
+
+	if *sketch != "" {
+		if *name == "" {
+			*name = fmt.Sprintf("verify:%s:%d", *sketch, time.Now().UnixNano())
+		}
+		cfg := harnessConfig{sketch: *sketch, parts: *parts, kllK: *kllK, topK: *topk, queries: *queries, seed: *seed, name: *name}
+		for _, s := range strings.Split(*phis, ",") {
+			phi, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil || phi < 0 || phi > 1 {
+				log.Fatalf("%s is not a quantile between 0 and 1", s)
+			}
+			cfg.phis = append(cfg.phis, phi)
+		}
+		if *server != "" {
+			opts, err := security.DialOptions()
+			if err != nil {
+				log.Fatal(err)
+			}
+			conn, err := grpc.NewClient(*server, opts...)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer conn.Close()
+			cfg.server = pb.NewSketcherClient(conn)
+		}
+		var r *report
+		var err error
+		switch *typ {
+		case "int":
+			r, err = replay[int](*truthCSV, *header, cfg)
+		case "float", "float64":
+			r, err = replay[float64](*truthCSV, *header, cfg)
+		default:
+			log.Fatalf("%s is not a valid type, use float or int", *typ)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		table := r.summaryTable(cfg)
+		if *detail {
+			table = r.detail
+		}
+		w := os.Stdout
+		if *out != "" {
+			if w, err = os.Create(*out); err != nil {
+				log.Fatal(err)
+			}
+			defer w.Close()
+		}
+		if err := table.Write(w, *format); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
 
 	gt, err := readTruth(*truthCSV, *header, *round)
@@ -153,7 +228,10 @@ func main() {
 	}
 	gtTop := topKFromCounts(gt, *topk)
 
-	asBlk, err := readASketchBlock(*asketch, *round)
+	if *field == "" {
+		*field = *header
+	}
+	asBlk, err := readASketchBlock(*asketch, *field, *round)
 	if err != nil {
 		log.Fatal(err)
 	}